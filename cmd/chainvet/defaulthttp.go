@@ -32,9 +32,10 @@ var prohibitedMethods = map[string]bool{
 }
 
 var checkCmds = map[string]bool{
-	"cored":   true,
-	"corectl": true,
-	"signerd": true,
+	"cored":       true,
+	"corectl":     true,
+	"lightclient": true,
+	"signerd":     true,
 }
 
 // checkHttpCall checks that the chain packages do not use the
@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -33,6 +35,12 @@ var (
 	home    = config.HomeDirFromEnvironment()
 	coreURL = env.String("CORE_URL", "http://localhost:1999")
 
+	// Passphrases for export-keys and import-keys. They are read
+	// from the environment, not flags, to keep them out of the
+	// process list and shell history.
+	mockHSMPassphrase = env.String("MOCKHSM_PASSPHRASE", "")
+	backupPassphrase  = env.String("BACKUP_PASSPHRASE", "")
+
 	// build vars; initialized by the linker
 	buildTag    = "?"
 	buildCommit = "?"
@@ -70,6 +78,8 @@ var commands = map[string]*command{
 	"rm":                   {rm},
 	"set":                  {set},
 	"wait":                 {wait},
+	"export-keys":          {exportKeys},
+	"import-keys":          {importKeys},
 }
 
 func main() {
@@ -498,6 +508,75 @@ func wait(client *rpc.Client, args []string) {
 	}
 }
 
+// exportKeys writes an encrypted backup of the MockHSM's keys
+// to stdout. The MockHSM's passphrase is read from MOCKHSM_PASSPHRASE
+// and the backup is encrypted with BACKUP_PASSPHRASE.
+func exportKeys(client *rpc.Client, args []string) {
+	const usage = "usage: MOCKHSM_PASSPHRASE=... BACKUP_PASSPHRASE=... corectl export-keys"
+	if len(args) != 0 || *mockHSMPassphrase == "" || *backupPassphrase == "" {
+		fatalln(usage)
+	}
+
+	req := struct {
+		HSMPassphrase string `json:"hsm_passphrase"`
+		Passphrase    string `json:"passphrase"`
+	}{*mockHSMPassphrase, *backupPassphrase}
+	var backup json.RawMessage
+	err := client.Call(context.Background(), "/mockhsm/export-keys", req, &backup)
+	dieOnRPCError(err)
+	fmt.Println(string(backup))
+}
+
+// importKeys imports the keys in a backup made by exportKeys,
+// read from the named file or from stdin. The backup's passphrase
+// is read from BACKUP_PASSPHRASE.
+func importKeys(client *rpc.Client, args []string) {
+	const usage = "usage: BACKUP_PASSPHRASE=... corectl import-keys [file]"
+	if len(args) > 1 || *backupPassphrase == "" {
+		fatalln(usage)
+	}
+
+	var (
+		backup []byte
+		err    error
+	)
+	if len(args) == 1 {
+		backup, err = ioutil.ReadFile(args[0])
+	} else {
+		backup, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fatalln("error: reading backup:", err)
+	}
+	if json.Unmarshal(backup, new(json.RawMessage)) != nil {
+		fatalln("error: backup is not valid JSON")
+	}
+
+	req := struct {
+		Backup     json.RawMessage `json:"backup"`
+		Passphrase string          `json:"passphrase"`
+	}{backup, *backupPassphrase}
+	var keys []struct {
+		Type          string
+		Pub           string
+		Alias         *string
+		OriginalAlias *string `json:"original_alias"`
+		Status        string
+	}
+	err = client.Call(context.Background(), "/mockhsm/import-keys", req, &keys)
+	dieOnRPCError(err)
+	for _, k := range keys {
+		line := []string{k.Status, k.Type, k.Pub}
+		if k.Alias != nil {
+			line = append(line, *k.Alias)
+		}
+		if k.Status == "renamed" && k.OriginalAlias != nil {
+			line = append(line, "(was "+*k.OriginalAlias+")")
+		}
+		fmt.Println(strings.Join(line, " "))
+	}
+}
+
 func mustRPCClient() *rpc.Client {
 	// TODO(kr): refactor some of this cert-loading logic into chain/core
 	// and use it from cored as well.
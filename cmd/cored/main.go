@@ -29,6 +29,7 @@ import (
 	"chain/core/generator"
 	"chain/core/migrate"
 	"chain/core/rpc"
+	"chain/core/txbuilder"
 	"chain/core/txdb"
 	"chain/crypto/ed25519"
 	"chain/database/pg"
@@ -67,6 +68,8 @@ var (
 	rpsToken      = env.Int("RATELIMIT_TOKEN", 0)       // reqs/sec
 	rpsRemoteAddr = env.Int("RATELIMIT_REMOTE_ADDR", 0) // reqs/sec
 	indexTxs      = env.Bool("INDEX_TRANSACTIONS", true)
+	signerdURL    = env.String("SIGNERD_URL", "")
+	signerdToken  = env.String("SIGNERD_ACCESS_TOKEN", "")
 	home          = config.HomeDirFromEnvironment()
 
 	version string // initialized in init()
@@ -343,6 +346,18 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 
 	opts = append(opts, core.IndexTransactions(*indexTxs))
 	opts = append(opts, enableMockHSM(db)...)
+	// Sign the Core's own transactions with signerd, if configured,
+	// instead of the MockHSM.
+	if *signerdURL != "" {
+		signerd := &txbuilder.RemoteSigner{Peer: &rpc.Client{
+			BaseURL:     *signerdURL,
+			AccessToken: *signerdToken,
+			ProcessID:   processID,
+			Version:     version,
+			Client:      httpClient,
+		}}
+		opts = append(opts, core.TransactionSigner(signerd.Sign))
+	}
 	// Add any configured API request rate limits.
 	if *rpsToken > 0 {
 		opts = append(opts, core.RateLimit(limit.AuthUserID, 2*(*rpsToken), *rpsToken))
@@ -3,21 +3,54 @@
 package main
 
 import (
+	"context"
+	"sync"
+
 	"chain/core"
 	"chain/core/blocksigner"
 	"chain/core/config"
 	"chain/core/mockhsm"
 	"chain/database/pg"
+	"chain/env"
+	chainlog "chain/log"
+)
+
+// The MockHSM stays locked until it's unlocked with this passphrase,
+// either here at startup or later through /mockhsm/unlock.
+var mockHSMPassphrase = env.String("MOCKHSM_PASSPHRASE", "")
+
+var (
+	mockHSMOnce   sync.Once
+	sharedMockHSM *mockhsm.HSM
 )
 
 func init() {
 	config.BuildConfig.MockHSM = true
 }
 
+// openMockHSM returns the process's MockHSM, shared by the API
+// and the block signer so that unlocking it unlocks both.
+func openMockHSM(db pg.DB) *mockhsm.HSM {
+	mockHSMOnce.Do(func() {
+		ctx := context.Background()
+		sharedMockHSM = mockhsm.New(db)
+		config.MockHSM = sharedMockHSM
+		if *mockHSMPassphrase == "" {
+			chainlog.Printf(ctx, "MockHSM is locked; unlock it with /mockhsm/unlock")
+			return
+		}
+		err := sharedMockHSM.Unlock(ctx, *mockHSMPassphrase)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	})
+	return sharedMockHSM
+}
+
 func enableMockHSM(db pg.DB) []core.RunOption {
-	return []core.RunOption{core.MockHSM(mockhsm.New(db))}
+	return []core.RunOption{core.MockHSM(openMockHSM(db))}
 }
 
 func mockHSM(db pg.DB) blocksigner.Signer {
-	return mockhsm.New(db)
+	return openMockHSM(db)
 }
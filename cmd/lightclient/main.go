@@ -0,0 +1,199 @@
+// Command lightclient follows a blockchain by its block headers and
+// checks proofs against them, without Postgres or a copy of the
+// blockchain state.
+//
+// It syncs block headers from a Chain Core (TARGET) with the crosscore
+// get-block RPC, so TARGET_AUTH must be an access token with the
+// crosscore policy. It trusts the initial block whose hash is
+// BLOCKCHAIN_ID and verifies every header after that. If TARGET serves
+// an invalid header, lightclient exits.
+//
+// It serves these requests, with JSON bodies:
+//
+//	/block-height               the height of the last verified header
+//	/list-signer-sets           the consensus programs in effect so far
+//	/verify-transaction-proof   check a Core's /get-transaction-proof response
+//	/verify-output-proof        check a Core's /get-output-proof response
+//
+// A proof at a height lightclient hasn't verified yet fails with CH130,
+// and can be retried later.
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"chain/core/lightclient"
+	"chain/core/rpc"
+	chainjson "chain/encoding/json"
+	"chain/env"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httperror"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/patricia"
+)
+
+var (
+	listenAddr   = env.String("LISTEN", "localhost:1996")
+	target       = env.String("TARGET", "http://localhost:1999")
+	targetAuth   = env.String("TARGET_AUTH", "")
+	blockchainID = env.String("BLOCKCHAIN_ID", "")
+	tlsCert      = env.String("TLS_CERT", "") // file path
+	tlsKey       = env.String("TLS_KEY", "")  // file path
+)
+
+var errorFormatter = httperror.Formatter{
+	Default:     httperror.Info{HTTPStatus: 500, ChainCode: "CH000", Message: "Light Client Error"},
+	IsTemporary: func(info httperror.Info, _ error) bool { return info.ChainCode == "CH000" || info.ChainCode == "CH130" },
+	Errors: map[error]httperror.Info{
+		httpjson.ErrBadRequest:       {HTTPStatus: 400, ChainCode: "CH003", Message: "Invalid request body"},
+		lightclient.ErrUnknownHeight: {HTTPStatus: 400, ChainCode: "CH130", Message: "Block header not yet verified"},
+		lightclient.ErrBadProof:      {HTTPStatus: 400, ChainCode: "CH131", Message: "Invalid proof"},
+	},
+}
+
+func main() {
+	env.Parse()
+	ctx := context.Background()
+
+	var id bc.Hash
+	err := id.UnmarshalText([]byte(*blockchainID))
+	if err != nil || *blockchainID == "" {
+		log.Fatalkv(ctx, log.KeyError, errors.New("BLOCKCHAIN_ID must be set to the hash of the initial block"))
+	}
+	peer := &rpc.Client{
+		BaseURL:      *target,
+		AccessToken:  *targetAuth,
+		BlockchainID: id.String(),
+	}
+
+	lc := lightclient.New(id)
+	go func() {
+		err := lc.Sync(ctx, peer)
+		log.Fatalkv(ctx, log.KeyError, err)
+	}()
+
+	a := &api{lc}
+	mux := http.NewServeMux()
+	mux.Handle("/block-height", jsonHandler(a.blockHeight))
+	mux.Handle("/list-signer-sets", jsonHandler(a.listSignerSets))
+	mux.Handle("/verify-transaction-proof", jsonHandler(a.verifyTxProof))
+	mux.Handle("/verify-output-proof", jsonHandler(a.verifyOutputProof))
+
+	log.Printf(ctx, "lightclient listening at %s", *listenAddr)
+	server := &http.Server{Addr: *listenAddr, Handler: mux}
+	if *tlsCert != "" || *tlsKey != "" {
+		err = server.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	log.Fatalkv(ctx, log.KeyError, err)
+}
+
+type api struct {
+	lc *lightclient.Client
+}
+
+func (a *api) blockHeight() map[string]uint64 {
+	return map[string]uint64{"block_height": a.lc.Height()}
+}
+
+type signerSet struct {
+	BlockHeight      uint64               `json:"block_height"`
+	ConsensusProgram chainjson.HexBytes   `json:"consensus_program"`
+	Pubkeys          []chainjson.HexBytes `json:"pubkeys"`
+	Quorum           int                  `json:"quorum"`
+}
+
+func (a *api) listSignerSets() []signerSet {
+	var resp []signerSet
+	for _, s := range a.lc.SignerSets() {
+		set := signerSet{
+			BlockHeight:      s.Height,
+			ConsensusProgram: s.Program,
+			Pubkeys:          []chainjson.HexBytes{},
+			Quorum:           s.Quorum,
+		}
+		for _, pubkey := range s.Pubkeys {
+			set.Pubkeys = append(set.Pubkeys, chainjson.HexBytes(pubkey))
+		}
+		resp = append(resp, set)
+	}
+	return resp
+}
+
+type verified struct {
+	BlockHeight uint64  `json:"block_height"`
+	BlockID     bc.Hash `json:"block_id"`
+}
+
+// verifyTxProof takes the response of a Core's
+// /get-transaction-proof and checks it against the
+// verified header at its height.
+func (a *api) verifyTxProof(in struct {
+	ID          bc.Hash        `json:"id"`
+	BlockHeight uint64         `json:"block_height"`
+	Proof       bc.MerkleProof `json:"proof"`
+}) (*verified, error) {
+	err := a.lc.VerifyTx(in.BlockHeight, in.ID, &in.Proof)
+	if err != nil {
+		return nil, err
+	}
+	return a.verified(in.BlockHeight)
+}
+
+type patriciaPath struct {
+	Item     chainjson.HexBytes `json:"item"`
+	Right    []bool             `json:"right"`
+	Siblings []bc.Hash          `json:"siblings"`
+}
+
+func (p *patriciaPath) path() *patricia.Path {
+	if p == nil {
+		return nil
+	}
+	return &patricia.Path{Item: p.Item, Right: p.Right, Siblings: p.Siblings}
+}
+
+// verifyOutputProof takes the response of a Core's
+// /get-output-proof and checks it against the
+// verified header at its height.
+func (a *api) verifyOutputProof(in struct {
+	OutputID    bc.Hash `json:"output_id"`
+	Unspent     bool    `json:"unspent"`
+	BlockHeight uint64  `json:"block_height"`
+	Proof       struct {
+		Leaf *patriciaPath `json:"leaf"`
+		Prev *patriciaPath `json:"prev"`
+		Next *patriciaPath `json:"next"`
+	} `json:"proof"`
+}) (*verified, error) {
+	p := &patricia.Proof{
+		Leaf: in.Proof.Leaf.path(),
+		Prev: in.Proof.Prev.path(),
+		Next: in.Proof.Next.path(),
+	}
+	err := a.lc.VerifyOutput(in.BlockHeight, in.OutputID, in.Unspent, p)
+	if err != nil {
+		return nil, err
+	}
+	return a.verified(in.BlockHeight)
+}
+
+func (a *api) verified(height uint64) (*verified, error) {
+	id, err := a.lc.BlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return &verified{BlockHeight: height, BlockID: id}, nil
+}
+
+func jsonHandler(f interface{}) http.Handler {
+	h, err := httpjson.Handler(f, errorFormatter.Write)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"database/sql"
+	"sync"
+
+	"chain/crypto/ed25519/chainkd"
+	"chain/crypto/kek"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// schema is the whole of signerd's database: its keys, the
+// parameters of the key-encryption key that protects them, and the
+// access tokens accepted by the server. The access_tokens table has
+// the columns used by accesstoken.CredentialStore. signerd shares no
+// tables with cored, so it must not be pointed at a Core's database.
+const schema = `
+	CREATE TABLE IF NOT EXISTS signerd_kek (
+		singleton boolean DEFAULT true NOT NULL PRIMARY KEY,
+		salt bytea NOT NULL,
+		verifier bytea NOT NULL,
+		CONSTRAINT signerd_kek_singleton CHECK (singleton)
+	);
+	CREATE TABLE IF NOT EXISTS signerd_keys (
+		xpub bytea NOT NULL PRIMARY KEY,
+		xprv bytea NOT NULL,
+		alias text UNIQUE,
+		created_at timestamp with time zone DEFAULT now() NOT NULL
+	);
+	CREATE SEQUENCE IF NOT EXISTS access_tokens_sort_id_seq;
+	CREATE TABLE IF NOT EXISTS access_tokens (
+		id text NOT NULL PRIMARY KEY,
+		sort_id text DEFAULT lpad(nextval('access_tokens_sort_id_seq')::text, 20, '0') NOT NULL,
+		type text,
+		hashed_secret bytea NOT NULL,
+		created timestamp with time zone DEFAULT now() NOT NULL
+	);
+`
+
+var (
+	errBadPassphrase  = errors.New("incorrect signerd passphrase")
+	errDuplicateAlias = errors.New("duplicate key alias")
+	errNoKey          = errors.New("key not found")
+)
+
+// verifierPlaintext is sealed with the key-encryption key when the
+// database is first set up, so that a wrong passphrase is detected
+// before any key is stored or read.
+var verifierPlaintext = []byte("chain signerd key-encryption key")
+
+// keyStore holds chain_kd private keys, encrypted at rest with
+// a key-encryption key derived from the operator's passphrase.
+type keyStore struct {
+	db   pg.DB
+	aead cipher.AEAD
+
+	cacheMu sync.Mutex
+	cache   map[chainkd.XPub]chainkd.XPrv
+}
+
+// openKeyStore creates signerd's tables if they don't exist and
+// derives the key-encryption key from passphrase. The first call on
+// a database sets the passphrase; later calls must supply the same
+// one or fail with errBadPassphrase.
+func openKeyStore(ctx context.Context, db pg.DB, passphrase string) (*keyStore, error) {
+	if passphrase == "" {
+		return nil, errors.WithDetail(errBadPassphrase, "the passphrase must not be empty")
+	}
+	_, err := db.ExecContext(ctx, schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating schema")
+	}
+
+	const selectQ = `SELECT salt, verifier FROM signerd_kek`
+	var salt, verifier []byte
+	err = db.QueryRowContext(ctx, selectQ).Scan(&salt, &verifier)
+	if err == sql.ErrNoRows {
+		salt, err = kek.NewSalt()
+		if err != nil {
+			return nil, err
+		}
+		aead, err := kek.New(passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+		verifier, err = kek.Seal(aead, verifierPlaintext, nil)
+		if err != nil {
+			return nil, err
+		}
+		const insertQ = `
+			INSERT INTO signerd_kek (salt, verifier) VALUES ($1, $2)
+			ON CONFLICT (singleton) DO NOTHING
+		`
+		_, err = db.ExecContext(ctx, insertQ, salt, verifier)
+		if err != nil {
+			return nil, errors.Wrap(err, "storing key-encryption key parameters")
+		}
+
+		// If another process set the passphrase first, use its salt.
+		var stored []byte
+		err = db.QueryRowContext(ctx, selectQ).Scan(&stored, &verifier)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading key-encryption key parameters")
+		}
+		if bytes.Equal(stored, salt) {
+			return newKeyStore(db, aead), nil
+		}
+		salt = stored
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading key-encryption key parameters")
+	}
+
+	aead, err := kek.New(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := kek.Open(aead, verifier, nil); err != nil {
+		return nil, errBadPassphrase
+	}
+	return newKeyStore(db, aead), nil
+}
+
+func newKeyStore(db pg.DB, aead cipher.AEAD) *keyStore {
+	return &keyStore{
+		db:    db,
+		aead:  aead,
+		cache: make(map[chainkd.XPub]chainkd.XPrv),
+	}
+}
+
+// create stores a new random chain_kd key and returns its xpub.
+func (ks *keyStore) create(ctx context.Context, alias string) (chainkd.XPub, error) {
+	xprv, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		return xpub, err
+	}
+	sealed, err := kek.Seal(ks.aead, xprv.Bytes(), xpub.Bytes())
+	if err != nil {
+		return xpub, err
+	}
+	sqlAlias := sql.NullString{String: alias, Valid: alias != ""}
+	const q = `INSERT INTO signerd_keys (xpub, xprv, alias) VALUES ($1, $2, $3)`
+	_, err = ks.db.ExecContext(ctx, q, xpub.Bytes(), sealed, sqlAlias)
+	if pg.IsUniqueViolation(err) {
+		return xpub, errors.WithDetailf(errDuplicateAlias, "value: %q", alias)
+	} else if err != nil {
+		return xpub, errors.Wrap(err, "storing new xpub")
+	}
+	return xpub, nil
+}
+
+func (ks *keyStore) load(ctx context.Context, xpub chainkd.XPub) (xprv chainkd.XPrv, err error) {
+	ks.cacheMu.Lock()
+	defer ks.cacheMu.Unlock()
+
+	if xprv, ok := ks.cache[xpub]; ok {
+		return xprv, nil
+	}
+	var sealed []byte
+	err = ks.db.QueryRowContext(ctx, `SELECT xprv FROM signerd_keys WHERE xpub = $1`, xpub.Bytes()).Scan(&sealed)
+	if err == sql.ErrNoRows {
+		return xprv, errNoKey
+	} else if err != nil {
+		return xprv, errors.Wrap(err)
+	}
+	b, err := kek.Open(ks.aead, sealed, xpub.Bytes())
+	if err != nil {
+		return xprv, errors.Wrapf(err, "decrypting key %x", xpub.Bytes())
+	}
+	copy(xprv[:], b)
+	ks.cache[xpub] = xprv
+	return xprv, nil
+}
+
+// sign signs msg with the key for xpub, derived along path.
+// It returns errNoKey if the store has no key for xpub.
+func (ks *keyStore) sign(ctx context.Context, xpub chainkd.XPub, path [][]byte, msg []byte) ([]byte, error) {
+	xprv, err := ks.load(ctx, xpub)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 {
+		xprv = xprv.Derive(path)
+	}
+	return xprv.Sign(msg), nil
+}
@@ -0,0 +1,265 @@
+// Command signerd is a transaction signer that runs in its own process,
+// separate from cored.
+//
+// It keeps chain_kd keys, encrypted with a passphrase, in its own
+// Postgres database, which holds nothing but those keys and signerd's
+// access tokens. It serves /sign-transaction with the same request
+// and response as a Core's /mockhsm/sign-transaction. Callers must
+// authenticate with an access token. A Core can use signerd through
+// txbuilder.RemoteSigner, and the SDKs' HSM signers can use it as the
+// URL of a remote HSM.
+//
+// signerd refuses to sign templates that break the signing policy
+// set by SIGNERD_MAX_AMOUNT (comma-separated assetid:amount pairs),
+// SIGNERD_ALLOWED_PROGRAM (comma-separated hex control programs)
+// and SIGNERD_SIGNING_WINDOW (comma-separated HH:MM-HH:MM ranges in
+// UTC), with the same rules as a Core's signing_* configuration
+// options. signerd doesn't know the Core's accounts, so it takes
+// every spent control program to be local and every output to
+// another program, including change, to leave the local accounts.
+//
+// Usage:
+//
+//	signerd                    serve signing requests
+//	signerd create-key [alias] create a key and print its xpub
+//	signerd create-token [id]  create an access token and print it
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"chain/core/accesstoken"
+	"chain/core/signpolicy"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	"chain/env"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/authn"
+	"chain/net/http/httperror"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+)
+
+var (
+	listenAddr = env.String("LISTEN", ":1998")
+	dbURL      = env.String("DATABASE_URL", "postgres:///signerd?sslmode=disable")
+	passphrase = env.String("SIGNERD_PASSPHRASE", "")
+	tlsCert    = env.String("TLS_CERT", "") // file path
+	tlsKey     = env.String("TLS_KEY", "")  // file path
+	maxDBConns = env.Int("MAXDBCONNS", 10)
+
+	maxAmounts      = env.StringSlice("SIGNERD_MAX_AMOUNT")
+	allowedPrograms = env.StringSlice("SIGNERD_ALLOWED_PROGRAM")
+	signingWindows  = env.StringSlice("SIGNERD_SIGNING_WINDOW")
+)
+
+var errNotAuthenticated = errors.New("not authenticated")
+
+var errorFormatter = httperror.Formatter{
+	Default:     httperror.Info{HTTPStatus: 500, ChainCode: "CH000", Message: "Signer Error"},
+	IsTemporary: func(info httperror.Info, _ error) bool { return info.ChainCode == "CH000" },
+	Errors: map[error]httperror.Info{
+		errNotAuthenticated:     {HTTPStatus: 401, ChainCode: "CH009", Message: "Request could not be authenticated"},
+		httpjson.ErrBadRequest:  {HTTPStatus: 400, ChainCode: "CH003", Message: "Invalid request body"},
+		signpolicy.ErrViolation: {HTTPStatus: 400, ChainCode: "CH806", Message: "Signing policy violation: see attached data"},
+	},
+}
+
+func main() {
+	env.Parse()
+	ctx := context.Background()
+	if *passphrase == "" {
+		fatalln("error: SIGNERD_PASSPHRASE must be set")
+	}
+	policy, err := parsePolicy()
+	if err != nil {
+		fatalln("error:", err, errors.Detail(err))
+	}
+
+	sql.Register("signerdpg", pg.NewDriver())
+	db, err := sql.Open("signerdpg", *dbURL)
+	if err != nil {
+		log.Fatalkv(ctx, log.KeyError, err)
+	}
+	db.SetMaxOpenConns(*maxDBConns)
+	db.SetMaxIdleConns(*maxDBConns)
+	keys, err := openKeyStore(ctx, db, *passphrase)
+	if err != nil {
+		log.Fatalkv(ctx, log.KeyError, err)
+	}
+	tokens := &accesstoken.CredentialStore{DB: db}
+
+	args := os.Args[1:]
+	if len(args) > 0 {
+		runCommand(ctx, keys, tokens, args)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/sign-transaction", jsonHandler((&signer{keys, policy}).signTemplates))
+	handler := authenticate(authn.NewAPI(tokens, "", nil), mux)
+
+	log.Printf(ctx, "signerd listening at %s", *listenAddr)
+	server := &http.Server{Addr: *listenAddr, Handler: handler}
+	if *tlsCert != "" || *tlsKey != "" {
+		err = server.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	log.Fatalkv(ctx, log.KeyError, err)
+}
+
+func runCommand(ctx context.Context, keys *keyStore, tokens *accesstoken.CredentialStore, args []string) {
+	switch {
+	case args[0] == "create-key" && len(args) <= 2:
+		var alias string
+		if len(args) == 2 {
+			alias = args[1]
+		}
+		xpub, err := keys.create(ctx, alias)
+		if err != nil {
+			fatalln("error:", err, errors.Detail(err))
+		}
+		fmt.Println(xpub.String())
+	case args[0] == "create-token" && len(args) == 2:
+		tok, err := tokens.Create(ctx, args[1], "")
+		if err != nil {
+			fatalln("error:", err)
+		}
+		fmt.Println(tok.Token)
+	default:
+		fatalln("usage: signerd [create-key [alias] | create-token [id]]")
+	}
+}
+
+// authenticate requires every request to carry a valid access token.
+// Unlike cored, signerd doesn't accept client certificates or
+// unauthenticated requests from localhost.
+func authenticate(a *authn.API, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req, err := a.Authenticate(req)
+		if err == nil && authn.Token(req.Context()) == "" {
+			err = errors.WithDetail(errors.New("unauthenticated"), "An access token is required.")
+		}
+		if err != nil {
+			errorFormatter.Write(req.Context(), w, errors.Sub(errNotAuthenticated, err))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// parsePolicy returns the signing policy set in the environment.
+func parsePolicy() (*signpolicy.Policy, error) {
+	p := new(signpolicy.Policy)
+	for _, s := range *maxAmounts {
+		parts := strings.Split(s, ":")
+		if len(parts) != 2 {
+			return nil, errors.WithDetailf(signpolicy.ErrBadRule, "SIGNERD_MAX_AMOUNT: %q is not assetid:amount.", s)
+		}
+		assetID, amount, err := signpolicy.ParseMaxAmount(parts[0], parts[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "SIGNERD_MAX_AMOUNT")
+		}
+		if p.MaxAmounts == nil {
+			p.MaxAmounts = make(map[bc.AssetID]uint64)
+		}
+		p.MaxAmounts[assetID] = amount
+	}
+	for _, s := range *allowedPrograms {
+		prog, err := hex.DecodeString(s)
+		if err != nil || len(prog) == 0 {
+			return nil, errors.WithDetailf(signpolicy.ErrBadRule, "SIGNERD_ALLOWED_PROGRAM: %q is not a non-empty hex control program.", s)
+		}
+		p.AllowedPrograms = append(p.AllowedPrograms, prog)
+	}
+	for _, s := range *signingWindows {
+		parts := strings.Split(s, "-")
+		if len(parts) != 2 {
+			return nil, errors.WithDetailf(signpolicy.ErrBadRule, "SIGNERD_SIGNING_WINDOW: %q is not HH:MM-HH:MM.", s)
+		}
+		w, err := signpolicy.ParseWindow(parts[0], parts[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "SIGNERD_SIGNING_WINDOW")
+		}
+		p.Windows = append(p.Windows, w)
+	}
+	return p, nil
+}
+
+// spentPrograms is a signpolicy.AccountLookup that puts every
+// control program spent by tpl in a single local account.
+func spentPrograms(tpl *txbuilder.Template) signpolicy.AccountLookup {
+	return func(ctx context.Context, progs [][]byte) (map[string]string, error) {
+		spent := make(map[string]bool)
+		for _, in := range tpl.Transaction.Inputs {
+			if prog := in.ControlProgram(); prog != nil {
+				spent[string(prog)] = true
+			}
+		}
+		accounts := make(map[string]string)
+		for _, prog := range progs {
+			if spent[string(prog)] {
+				accounts[string(prog)] = "local"
+			}
+		}
+		return accounts, nil
+	}
+}
+
+type signer struct {
+	keys   *keyStore
+	policy *signpolicy.Policy
+}
+
+// signTemplates has the same request and response as
+// a Core's /mockhsm/sign-transaction.
+func (s *signer) signTemplates(ctx context.Context, x struct {
+	Txs   []*txbuilder.Template `json:"transactions"`
+	XPubs []chainkd.XPub        `json:"xpubs"`
+}) []interface{} {
+	resp := make([]interface{}, 0, len(x.Txs))
+	for _, tx := range x.Txs {
+		err := s.policy.Check(ctx, tx, spentPrograms(tx), time.Now())
+		if err == nil {
+			err = txbuilder.Sign(ctx, tx, x.XPubs, s.signTemplate)
+		}
+		if err != nil {
+			info := errorFormatter.Format(err)
+			resp = append(resp, info)
+		} else {
+			resp = append(resp, tx)
+		}
+	}
+	return resp
+}
+
+func (s *signer) signTemplate(ctx context.Context, xpub chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+	sigBytes, err := s.keys.sign(ctx, xpub, path, data[:])
+	if err == errNoKey {
+		return nil, nil
+	}
+	return sigBytes, err
+}
+
+func jsonHandler(f interface{}) http.Handler {
+	h, err := httpjson.Handler(f, errorFormatter.Write)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func fatalln(v ...interface{}) {
+	fmt.Fprintln(os.Stderr, v...)
+	os.Exit(2)
+}
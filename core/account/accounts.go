@@ -19,6 +19,7 @@ import (
 	"chain/errors"
 	"chain/log"
 	"chain/protocol"
+	"chain/protocol/bc/legacy"
 	"chain/protocol/vm/vmutil"
 )
 
@@ -27,6 +28,15 @@ const maxAccountCache = 1000
 var (
 	ErrDuplicateAlias = errors.New("duplicate account alias")
 	ErrBadIdentifier  = errors.New("either ID or alias must be specified, and not both")
+
+	// ErrWatchOnly is returned when building a transaction that
+	// spends from a watch-only account, or when deriving a control
+	// program for a watch-only account that has no keys.
+	ErrWatchOnly = errors.New("account is watch-only")
+
+	// ErrProgramInUse is returned by CreateWatchOnly when one of
+	// the control programs already belongs to an account.
+	ErrProgramInUse = errors.New("control program already belongs to an account")
 )
 
 func NewManager(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Manager {
@@ -83,19 +93,188 @@ func (m *Manager) ExpireReservations(ctx context.Context, period time.Duration)
 	}
 }
 
+// RestoreReservations loads the unexpired UTXO reservations made by
+// any process, such as a previous leader, so that their UTXOs stay
+// reserved and their client tokens stay idempotent.
+func (m *Manager) RestoreReservations(ctx context.Context) error {
+	return m.utxoDB.Restore(ctx)
+}
+
+// ListReservations returns up to limit unexpired reservations
+// matching f, ordered by ID and starting after the reservation with
+// ID after.
+func (m *Manager) ListReservations(ctx context.Context, f ReservationFilter, after uint64, limit int) ([]*Reservation, error) {
+	reservations, err := m.utxoDB.List(ctx, f, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Reservation
+	for _, res := range reservations {
+		r := &Reservation{
+			ID:          res.ID,
+			AccountID:   res.Source.AccountID,
+			AssetID:     res.Source.AssetID,
+			Change:      res.Change,
+			ExpiresAt:   res.Expiry,
+			ClientToken: res.ClientToken,
+		}
+		for _, u := range res.UTXOs {
+			r.Amount += u.Amount
+			r.OutputIDs = append(r.OutputIDs, u.OutputID)
+		}
+		r.Amount -= res.Change
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// CancelReservation cancels the reservation with the given ID,
+// making its UTXOs available to other reservations. Transactions
+// built on the reservation fail CheckCanceledReservations.
+func (m *Manager) CancelReservation(ctx context.Context, id uint64) error {
+	return m.utxoDB.Revoke(ctx, id)
+}
+
+// CheckCanceledReservations returns ErrCanceled if tx spends UTXOs
+// of a reservation canceled with CancelReservation.
+func (m *Manager) CheckCanceledReservations(ctx context.Context, tx *legacy.Tx) error {
+	return m.utxoDB.CheckCanceled(ctx, tx.SpentOutputIDs)
+}
+
 type Account struct {
 	*signers.Signer
 	Alias string
 	Tags  map[string]interface{}
+
+	// WatchOnly accounts have their UTXOs tracked,
+	// but this Core never builds spends from them.
+	WatchOnly bool
 }
 
-// Create creates a new Account.
-func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int, alias string, tags map[string]interface{}, clientToken string) (*Account, error) {
+// Create creates a new Account. Spends from the account that don't
+// choose a coin selection strategy use sel, or SmallestSufficient if
+// sel is empty.
+func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int, alias string, tags map[string]interface{}, sel Selection, clientToken string) (*Account, error) {
+	err := sel.Validate()
+	if err != nil {
+		return nil, err
+	}
 	signer, err := signers.Create(ctx, m.db, "account", xpubs, quorum, clientToken)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
+	return m.insertAccount(ctx, signer, alias, tags, sel, false)
+}
 
+// CreateWatchOnly creates a new watch-only Account, whose UTXOs are
+// tracked and annotated like those of other accounts, but which
+// can't be spent from in transactions built by this Core.
+//
+// If programs is empty, the account's control programs are derived
+// from xpubs as for other accounts, though the keys are never used
+// for signing. Otherwise xpubs must be empty, and the account has
+// no keys and owns exactly the given control programs. The account's
+// coin selection strategy is set to sel, as in Create.
+func (m *Manager) CreateWatchOnly(ctx context.Context, xpubs []chainkd.XPub, quorum int, programs [][]byte, alias string, tags map[string]interface{}, sel Selection, clientToken string) (*Account, error) {
+	err := sel.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if len(programs) == 0 {
+		signer, err := signers.Create(ctx, m.db, "account", xpubs, quorum, clientToken)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		return m.insertAccount(ctx, signer, alias, tags, sel, true)
+	}
+	if len(xpubs) > 0 || quorum != 0 {
+		return nil, errors.WithDetail(ErrWatchOnly, "provide either root xpubs or control programs, not both")
+	}
+	seen := make(map[string]bool, len(programs))
+	for i, prog := range programs {
+		if len(prog) == 0 {
+			return nil, errors.WithDetailf(ErrWatchOnly, "control program %d is empty", i)
+		}
+		if seen[string(prog)] {
+			return nil, errors.WithDetailf(ErrWatchOnly, "control program %d is repeated", i)
+		}
+		seen[string(prog)] = true
+	}
+
+	// Check for programs of other accounts before creating this one.
+	// A retried request finds its own account's programs, which
+	// are fine.
+	const q = `
+		SELECT control_program FROM account_control_programs
+		WHERE control_program = ANY($1::bytea[]) AND signer_id NOT IN (
+			SELECT id FROM signers WHERE client_token = $2
+		)
+		LIMIT 1
+	`
+	var used []byte
+	err = m.db.QueryRowContext(ctx, q, pq.ByteaArray(programs), clientToken).Scan(&used)
+	if err == nil {
+		return nil, errors.WithDetailf(ErrProgramInUse, "control program %x", used)
+	} else if err != stdsql.ErrNoRows {
+		return nil, errors.Wrap(err, "checking control programs")
+	}
+
+	signer, err := signers.CreateKeyless(ctx, m.db, "account", clientToken)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	tagsParam, err := tagsToNullString(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the programs and the account in one statement, so that
+	// an account never exists without all of its programs. A retried
+	// request has stored them already. If another account takes one
+	// of the programs first, the statement fails with a unique
+	// violation and stores nothing.
+	const insertQ = `
+		WITH progs AS (
+			INSERT INTO account_control_programs (signer_id, key_index, control_program, change)
+			SELECT $1, 0, prog, false FROM unnest($2::bytea[]) AS prog
+			WHERE NOT EXISTS (SELECT 1 FROM account_control_programs WHERE signer_id = $1)
+			RETURNING 1
+		), acct AS (
+			INSERT INTO accounts (account_id, alias, tags, coin_selection, watch_only)
+			VALUES ($1, $3, $4, $5, true)
+			ON CONFLICT (account_id) DO UPDATE SET alias = $3, tags = $4, coin_selection = $5
+		)
+		SELECT count(*) FROM progs
+	`
+	aliasSQL := stdsql.NullString{String: alias, Valid: alias != ""}
+	selSQL := stdsql.NullString{String: string(sel), Valid: sel != ""}
+	var stored int
+	err = m.db.QueryRowContext(ctx, insertQ, signer.ID, pq.ByteaArray(programs), aliasSQL, tagsParam, selSQL).Scan(&stored)
+	if pqErr, ok := err.(*pq.Error); ok && pg.IsUniqueViolation(err) && pqErr.Constraint == "accounts_alias_key" {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
+	} else if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrProgramInUse, "a control program was given to another account concurrently")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "storing account and control programs")
+	}
+	if stored != 0 && stored != len(programs) {
+		return nil, errors.Wrapf(errors.New("incomplete control program insert"), "stored %d of %d", stored, len(programs))
+	}
+
+	account := &Account{
+		Signer:    signer,
+		Alias:     alias,
+		Tags:      tags,
+		WatchOnly: true,
+	}
+	err = m.indexAnnotatedAccount(ctx, account)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing annotated account")
+	}
+	return account, nil
+}
+
+func (m *Manager) insertAccount(ctx context.Context, signer *signers.Signer, alias string, tags map[string]interface{}, sel Selection, watchOnly bool) (*Account, error) {
 	tagsParam, err := tagsToNullString(tags)
 	if err != nil {
 		return nil, err
@@ -106,11 +285,13 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 		Valid:  alias != "",
 	}
 
+	selSQL := stdsql.NullString{String: string(sel), Valid: sel != ""}
+
 	const q = `
-		INSERT INTO accounts (account_id, alias, tags) VALUES ($1, $2, $3)
-		ON CONFLICT (account_id) DO UPDATE SET alias = $2, tags = $3
+		INSERT INTO accounts (account_id, alias, tags, coin_selection, watch_only) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id) DO UPDATE SET alias = $2, tags = $3, coin_selection = $4
 	`
-	_, err = m.db.ExecContext(ctx, q, signer.ID, aliasSQL, tagsParam)
+	_, err = m.db.ExecContext(ctx, q, signer.ID, aliasSQL, tagsParam, selSQL, watchOnly)
 	if pg.IsUniqueViolation(err) {
 		return nil, errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
 	} else if err != nil {
@@ -118,9 +299,10 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	}
 
 	account := &Account{
-		Signer: signer,
-		Alias:  alias,
-		Tags:   tags,
+		Signer:    signer,
+		Alias:     alias,
+		Tags:      tags,
+		WatchOnly: watchOnly,
 	}
 
 	err = m.indexAnnotatedAccount(ctx, account)
@@ -191,6 +373,132 @@ func (m *Manager) UpdateTags(ctx context.Context, id, alias *string, tags map[st
 	}), "update account index")
 }
 
+// UpdateCoinSelection sets the coin selection strategy used by spends
+// from the specified account that don't choose their own. The account
+// may be identified either by ID or Alias, but not both. An empty
+// strategy restores the default, SmallestSufficient.
+func (m *Manager) UpdateCoinSelection(ctx context.Context, id, alias *string, sel Selection) error {
+	if (id == nil) == (alias == nil) {
+		return errors.Wrap(ErrBadIdentifier)
+	}
+	err := sel.Validate()
+	if err != nil {
+		return err
+	}
+
+	var signer *signers.Signer
+	if id != nil {
+		signer, err = m.findByID(ctx, *id)
+		if err != nil {
+			return errors.Wrap(err, "get account by ID")
+		}
+	} else {
+		signer, err = m.FindByAlias(ctx, *alias)
+		if err != nil {
+			return errors.Wrap(err, "get account by alias")
+		}
+	}
+
+	const q = `UPDATE accounts SET coin_selection = $1 WHERE account_id = $2`
+	_, err = m.db.ExecContext(ctx, q, stdsql.NullString{String: string(sel), Valid: sel != ""}, signer.ID)
+	return errors.Wrap(err, "update entry in accounts table")
+}
+
+// UpdateKeys replaces the keys and quorum of the specified account,
+// keeping its ID, alias and tags. The account may be identified
+// either by ID or Alias, but not both.
+//
+// Control programs created afterward are derived from the new keys.
+// UTXOs locked with earlier keys stay spendable, and are signed for
+// with the keys that locked them.
+func (m *Manager) UpdateKeys(ctx context.Context, id, alias *string, xpubs []chainkd.XPub, quorum int) (*Account, error) {
+	if (id == nil) == (alias == nil) {
+		return nil, errors.Wrap(ErrBadIdentifier)
+	}
+
+	var (
+		signer *signers.Signer
+		err    error
+	)
+	if id != nil {
+		signer, err = m.findByID(ctx, *id)
+		if err != nil {
+			return nil, errors.Wrap(err, "get account by ID")
+		}
+	} else {
+		signer, err = m.FindByAlias(ctx, *alias)
+		if err != nil {
+			return nil, errors.Wrap(err, "get account by alias")
+		}
+	}
+
+	if len(signer.XPubs) == 0 {
+		return nil, errors.WithDetail(ErrWatchOnly, "account is defined by its control programs and has no keys")
+	}
+
+	signer, err = signers.UpdateKeys(ctx, m.db, "account", signer.ID, xpubs, quorum)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating keys")
+	}
+	m.cacheMu.Lock()
+	m.cache.Remove(signer.ID)
+	m.cacheMu.Unlock()
+
+	const q = `SELECT alias, tags, watch_only FROM accounts WHERE account_id = $1`
+	var (
+		aliasStr  stdsql.NullString
+		tagsJSON  []byte
+		watchOnly bool
+	)
+	err = m.db.QueryRowContext(ctx, q, signer.ID).Scan(&aliasStr, &tagsJSON, &watchOnly)
+	if err != nil {
+		return nil, errors.Wrap(err, "alias and tags lookup")
+	}
+	account := &Account{Signer: signer, Alias: aliasStr.String, WatchOnly: watchOnly}
+	if len(tagsJSON) > 0 {
+		err = json.Unmarshal(tagsJSON, &account.Tags)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding tags")
+		}
+	}
+
+	err = m.indexAnnotatedAccount(ctx, account)
+	if err != nil {
+		return nil, errors.Wrap(err, "update account index")
+	}
+	return account, nil
+}
+
+// checkSpendable returns ErrWatchOnly if the account is watch-only.
+func (m *Manager) checkSpendable(ctx context.Context, accountID string) error {
+	const q = `SELECT watch_only FROM accounts WHERE account_id = $1`
+	var watchOnly bool
+	err := m.db.QueryRowContext(ctx, q, accountID).Scan(&watchOnly)
+	if err != nil && err != stdsql.ErrNoRows {
+		return errors.Wrap(err)
+	}
+	if watchOnly {
+		return errors.WithDetailf(ErrWatchOnly, "account %s can't be spent from", accountID)
+	}
+	return nil
+}
+
+// coinSelection returns the account's coin selection strategy.
+func (m *Manager) coinSelection(ctx context.Context, accountID string) (Selection, error) {
+	const q = `SELECT coin_selection FROM accounts WHERE account_id = $1`
+	var sel stdsql.NullString
+	err := m.db.QueryRowContext(ctx, q, accountID).Scan(&sel)
+	if err == stdsql.ErrNoRows {
+		return SmallestSufficient, nil
+	} else if err != nil {
+		return "", errors.Wrap(err)
+	}
+	if !sel.Valid {
+		return SmallestSufficient, nil
+	}
+	return Selection(sel.String), nil
+}
+
 // FindByAlias retrieves an account's Signer record by its alias
 func (m *Manager) FindByAlias(ctx context.Context, alias string) (*signers.Signer, error) {
 	var accountID string
@@ -217,6 +525,9 @@ func (m *Manager) FindByAlias(ctx context.Context, alias string) (*signers.Signe
 }
 
 // findByID returns an account's Signer record by its ID.
+// The cached record may predate a key update made by another
+// process; callers that derive keys or control programs from
+// the account's current keys must use findCurrent instead.
 func (m *Manager) findByID(ctx context.Context, id string) (*signers.Signer, error) {
 	m.cacheMu.Lock()
 	cached, ok := m.cache.Get(id)
@@ -224,6 +535,12 @@ func (m *Manager) findByID(ctx context.Context, id string) (*signers.Signer, err
 	if ok {
 		return cached.(*signers.Signer), nil
 	}
+	return m.findCurrent(ctx, id)
+}
+
+// findCurrent reads an account's Signer record from the
+// database, bypassing the cache, and caches the result.
+func (m *Manager) findCurrent(ctx context.Context, id string) (*signers.Signer, error) {
 	account, err := signers.Find(ctx, m.db, "account", id)
 	if err != nil {
 		return nil, err
@@ -237,16 +554,26 @@ func (m *Manager) findByID(ctx context.Context, id string) (*signers.Signer, err
 type controlProgram struct {
 	accountID      string
 	keyIndex       uint64
+	keyVersion     int
 	controlProgram []byte
 	change         bool
 	expiresAt      time.Time
 }
 
 func (m *Manager) createControlProgram(ctx context.Context, accountID string, change bool, expiresAt time.Time) (*controlProgram, error) {
-	account, err := m.findByID(ctx, accountID)
+	account, err := m.findCurrent(ctx, accountID)
 	if err != nil {
 		return nil, err
 	}
+	return m.deriveControlProgram(ctx, account, change, expiresAt)
+}
+
+// deriveControlProgram derives a new control program for account
+// with the account's current keys.
+func (m *Manager) deriveControlProgram(ctx context.Context, account *signers.Signer, change bool, expiresAt time.Time) (*controlProgram, error) {
+	if len(account.XPubs) == 0 {
+		return nil, errors.WithDetail(ErrWatchOnly, "account has no keys to derive control programs from; use one of its existing control programs")
+	}
 
 	idx, err := m.nextIndex(ctx)
 	if err != nil {
@@ -263,6 +590,7 @@ func (m *Manager) createControlProgram(ctx context.Context, accountID string, ch
 	return &controlProgram{
 		accountID:      account.ID,
 		keyIndex:       idx,
+		keyVersion:     account.KeyVersion,
 		controlProgram: control,
 		change:         change,
 		expiresAt:      expiresAt,
@@ -283,11 +611,29 @@ func (m *Manager) CreateControlProgram(ctx context.Context, accountID string, ch
 	return cp.controlProgram, nil
 }
 
+// ProgramAccounts returns the IDs of the accounts that own
+// the given control programs, keyed by string(program).
+// Programs that belong to no account are absent.
+func (m *Manager) ProgramAccounts(ctx context.Context, progs [][]byte) (map[string]string, error) {
+	const q = `
+		SELECT signer_id, control_program FROM account_control_programs
+		WHERE control_program IN (SELECT unnest($1::bytea[]))
+	`
+	accounts := make(map[string]string, len(progs))
+	err := pg.ForQueryRows(ctx, m.db, q, pq.ByteaArray(progs), func(accountID string, program []byte) {
+		accounts[string(program)] = accountID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "loading control programs")
+	}
+	return accounts, nil
+}
+
 func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*controlProgram) error {
 	const q = `
-		INSERT INTO account_control_programs (signer_id, key_index, control_program, change, expires_at)
+		INSERT INTO account_control_programs (signer_id, key_index, control_program, change, expires_at, key_version)
 		SELECT unnest($1::text[]), unnest($2::bigint[]), unnest($3::bytea[]), unnest($4::boolean[]),
-			unnest($5::timestamp with time zone[])
+			unnest($5::timestamp with time zone[]), unnest($6::integer[])
 	`
 	var (
 		accountIDs   pq.StringArray
@@ -295,10 +641,12 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*con
 		controlProgs pq.ByteaArray
 		change       pq.BoolArray
 		expirations  []stdsql.NullString
+		keyVersions  pq.Int64Array
 	)
 	for _, p := range progs {
 		accountIDs = append(accountIDs, p.accountID)
 		keyIndexes = append(keyIndexes, int64(p.keyIndex))
+		keyVersions = append(keyVersions, int64(p.keyVersion))
 		controlProgs = append(controlProgs, p.controlProgram)
 		change = append(change, p.change)
 		expirations = append(expirations, stdsql.NullString{
@@ -307,7 +655,7 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*con
 		})
 	}
 
-	_, err := m.db.ExecContext(ctx, q, accountIDs, keyIndexes, controlProgs, change, pq.Array(expirations))
+	_, err := m.db.ExecContext(ctx, q, accountIDs, keyIndexes, controlProgs, change, pq.Array(expirations), keyVersions)
 	return errors.Wrap(err)
 }
 
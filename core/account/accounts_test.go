@@ -21,7 +21,7 @@ func TestCreateAccount(t *testing.T) {
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -38,17 +38,40 @@ func TestCreateAccount(t *testing.T) {
 	}
 }
 
+func TestCreateAccountCoinSelection(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, LargestFirst, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := m.coinSelection(ctx, account.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got != LargestFirst {
+		t.Errorf("coinSelection = %q want %q", got, LargestFirst)
+	}
+
+	_, err = m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "biggest", "")
+	if errors.Root(err) != ErrBadSelection {
+		t.Errorf("Create with unknown strategy: got %v want %v", err, ErrBadSelection)
+	}
+}
+
 func TestCreateAccountIdempotency(t *testing.T) {
 	db := pgtest.NewTx(t)
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 	var clientToken = "a-unique-client-token"
 
-	account1, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, clientToken)
+	account1, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, "", clientToken)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
-	account2, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, clientToken)
+	account2, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "satoshi", nil, "", clientToken)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -63,7 +86,7 @@ func TestCreateAccountReusedAlias(t *testing.T) {
 	ctx := context.Background()
 	m.createTestAccount(ctx, t, "some-account", nil)
 
-	_, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "some-account", nil, "")
+	_, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "some-account", nil, "", "")
 	if errors.Root(err) != ErrDuplicateAlias {
 		t.Errorf("Expected %s when reusing an alias, got %v", ErrDuplicateAlias, err)
 	}
@@ -75,7 +98,7 @@ func TestCreateControlProgram(t *testing.T) {
 	m := NewManager(db, prottest.NewChain(t), nil)
 	ctx := context.Background()
 
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -95,8 +118,146 @@ func TestCreateControlProgram(t *testing.T) {
 	}
 }
 
+func TestUpdateKeys(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	acc := m.createTestAccount(ctx, t, "alice", map[string]interface{}{"team": "ops"})
+	oldCP := m.createTestControlProgram(ctx, t, acc.ID)
+
+	newXPub := testutil.TestXPrv.Child([]byte{1}, true).XPub()
+	updated, err := m.UpdateKeys(ctx, nil, &acc.Alias, []chainkd.XPub{newXPub}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if updated.ID != acc.ID || updated.Alias != "alice" || updated.Tags["team"] != "ops" {
+		t.Errorf("updated account = %+v, want ID, alias and tags of %+v", updated, acc)
+	}
+	if updated.KeyVersion != 2 || updated.XPubs[0] != newXPub {
+		t.Errorf("updated account has key version %d and keys %v, want 2 and %v", updated.KeyVersion, updated.XPubs, newXPub)
+	}
+
+	newCP, err := m.createControlProgram(ctx, acc.ID, false, time.Time{})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if newCP.keyVersion != 2 {
+		t.Errorf("new control program key version = %d, want 2", newCP.keyVersion)
+	}
+	if bytes.Equal(newCP.controlProgram, oldCP.controlProgram) {
+		t.Error("expected new control program to use the new keys")
+	}
+
+	_, err = m.UpdateKeys(ctx, &acc.ID, &acc.Alias, []chainkd.XPub{newXPub}, 1)
+	if errors.Root(err) != ErrBadIdentifier {
+		t.Errorf("UpdateKeys with ID and alias = %v, want %v", err, ErrBadIdentifier)
+	}
+
+	// Another process that cached the account sees the new keys.
+	other := NewManager(db, prottest.NewChain(t), nil)
+	_, err = other.findByID(ctx, acc.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = m.UpdateKeys(ctx, &acc.ID, nil, []chainkd.XPub{testutil.TestXPub}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	otherCP, err := other.createControlProgram(ctx, acc.ID, false, time.Time{})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if otherCP.keyVersion != 3 {
+		t.Errorf("other process's control program key version = %d, want 3", otherCP.keyVersion)
+	}
+}
+
+func TestCreateWatchOnly(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	byKeys, err := m.CreateWatchOnly(ctx, []chainkd.XPub{testutil.TestXPub}, 1, nil, "by-keys", nil, "", "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !byKeys.WatchOnly {
+		t.Error("expected account to be watch-only")
+	}
+	_, err = m.CreateControlProgram(ctx, byKeys.ID, false, time.Time{})
+	if err != nil {
+		t.Errorf("creating control program for watch-only account with keys: %v", err)
+	}
+
+	progs := [][]byte{[]byte("external1"), []byte("external2")}
+	byProgs, err := m.CreateWatchOnly(ctx, nil, 0, progs, "by-programs", nil, "", "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := m.ProgramAccounts(ctx, progs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for _, p := range progs {
+		if got[string(p)] != byProgs.ID {
+			t.Errorf("program %q belongs to %q, want %s", p, got[string(p)], byProgs.ID)
+		}
+	}
+	_, err = m.CreateControlProgram(ctx, byProgs.ID, false, time.Time{})
+	if errors.Root(err) != ErrWatchOnly {
+		t.Errorf("creating control program for keyless account = %v, want %v", err, ErrWatchOnly)
+	}
+
+	_, err = m.CreateWatchOnly(ctx, nil, 0, progs[1:], "", nil, "", "")
+	if errors.Root(err) != ErrProgramInUse {
+		t.Errorf("reusing a control program = %v, want %v", err, ErrProgramInUse)
+	}
+	_, err = m.CreateWatchOnly(ctx, []chainkd.XPub{testutil.TestXPub}, 1, progs, "", nil, "", "")
+	if errors.Root(err) != ErrWatchOnly {
+		t.Errorf("creating with keys and programs = %v, want %v", err, ErrWatchOnly)
+	}
+
+	// A failed create stores none of its programs.
+	extra := [][]byte{[]byte("external3")}
+	_, err = m.CreateWatchOnly(ctx, nil, 0, extra, "by-programs", nil, "", "")
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("creating with a duplicate alias = %v, want %v", err, ErrDuplicateAlias)
+	}
+	got, err = m.ProgramAccounts(ctx, extra)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(got) != 0 {
+		t.Errorf("after failed create, programs belong to %v, want none", got)
+	}
+
+	// A retried create returns the same account.
+	first, err := m.CreateWatchOnly(ctx, nil, 0, extra, "", nil, "", "retry-token")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	second, err := m.CreateWatchOnly(ctx, nil, 0, extra, "", nil, "", "retry-token")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("retried create got account %s, want %s", second.ID, first.ID)
+	}
+
+	for _, id := range []string{byKeys.ID, byProgs.ID} {
+		if err := m.checkSpendable(ctx, id); errors.Root(err) != ErrWatchOnly {
+			t.Errorf("checkSpendable(%s) = %v, want %v", id, err, ErrWatchOnly)
+		}
+	}
+	spendable := m.createTestAccount(ctx, t, "", nil)
+	if err := m.checkSpendable(ctx, spendable.ID); err != nil {
+		t.Errorf("checkSpendable(%s) = %v, want nil", spendable.ID, err)
+	}
+}
+
 func (m *Manager) createTestAccount(ctx context.Context, t testing.TB, alias string, tags map[string]interface{}) *Account {
-	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, alias, tags, "")
+	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, alias, tags, "", "")
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -3,9 +3,11 @@ package account
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"chain/core/signers"
 	"chain/core/txbuilder"
+	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/log"
@@ -35,6 +37,10 @@ type spendAction struct {
 	AccountID     string        `json:"account_id"`
 	ReferenceData chainjson.Map `json:"reference_data"`
 	ClientToken   *string       `json:"client_token"`
+
+	// CoinSelection overrides the account's default coin selection
+	// strategy.
+	CoinSelection Selection `json:"coin_selection"`
 }
 
 func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
@@ -48,17 +54,33 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	if len(missing) > 0 {
 		return txbuilder.MissingFieldsError(missing...)
 	}
+	err := a.CoinSelection.Validate()
+	if err != nil {
+		return err
+	}
 
-	acct, err := a.accounts.findByID(ctx, a.AccountID)
+	acct, err := a.accounts.findCurrent(ctx, a.AccountID)
 	if err != nil {
 		return errors.Wrap(err, "get account info")
 	}
+	err = a.accounts.checkSpendable(ctx, a.AccountID)
+	if err != nil {
+		return err
+	}
+
+	sel := a.CoinSelection
+	if sel == "" {
+		sel, err = a.accounts.coinSelection(ctx, a.AccountID)
+		if err != nil {
+			return errors.Wrap(err, "get account coin selection")
+		}
+	}
 
 	src := source{
 		AssetID:   *a.AssetId,
 		AccountID: a.AccountID,
 	}
-	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, a.ClientToken, b.MaxTime())
+	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, sel, a.ClientToken, b.MaxTime())
 	if err != nil {
 		return errors.Wrap(err, "reserving utxos")
 	}
@@ -67,7 +89,7 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	b.OnRollback(canceler(ctx, a.accounts, res.ID))
 
 	for _, r := range res.UTXOs {
-		txInput, sigInst, err := utxoToInputs(ctx, acct, r, a.ReferenceData)
+		txInput, sigInst, err := utxoToInputs(ctx, a.accounts.db, acct, r, a.ReferenceData)
 		if err != nil {
 			return errors.Wrap(err, "creating inputs")
 		}
@@ -126,11 +148,15 @@ func (a *spendUTXOAction) Build(ctx context.Context, b *txbuilder.TemplateBuilde
 	}
 	b.OnRollback(canceler(ctx, a.accounts, res.ID))
 
-	acct, err := a.accounts.findByID(ctx, res.Source.AccountID)
+	err = a.accounts.checkSpendable(ctx, res.Source.AccountID)
+	if err != nil {
+		return err
+	}
+	acct, err := a.accounts.findCurrent(ctx, res.Source.AccountID)
 	if err != nil {
 		return err
 	}
-	txInput, sigInst, err := utxoToInputs(ctx, acct, res.UTXOs[0], a.ReferenceData)
+	txInput, sigInst, err := utxoToInputs(ctx, a.accounts.db, acct, res.UTXOs[0], a.ReferenceData)
 	if err != nil {
 		return err
 	}
@@ -147,11 +173,18 @@ func canceler(ctx context.Context, m *Manager, rid uint64) func() {
 	}
 }
 
-func utxoToInputs(ctx context.Context, account *signers.Signer, u *utxo, refData []byte) (
+// utxoToInputs returns an input spending u and the instruction
+// for signing it with the version of the account's keys that
+// locked u.
+func utxoToInputs(ctx context.Context, db pg.DB, account *signers.Signer, u *utxo, refData []byte) (
 	*legacy.TxInput,
 	*txbuilder.SigningInstruction,
 	error,
 ) {
+	account, err := signers.FindVersion(ctx, db, account, u.KeyVersion)
+	if err != nil {
+		return nil, nil, err
+	}
 	txInput := legacy.NewSpendInput(nil, u.SourceID, u.AssetID, u.Amount, u.SourcePos, u.ControlProgram, u.RefDataHash, refData)
 
 	sigInst := &txbuilder.SigningInstruction{}
@@ -206,14 +239,118 @@ func (a *controlAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder)
 	return b.AddOutput(legacy.NewTxOutput(*a.AssetId, a.Amount, acp.controlProgram, a.ReferenceData))
 }
 
-// insertControlProgramDelayed takes a template builder and an account
-// control program that hasn't been inserted to the database yet. It
+// MaxBatchSize limits the size in bytes of the outputs a single
+// control_accounts_batch action may produce. The template built from
+// the action is sent back to Core, hex-encoded, to be signed and
+// submitted, and Core accepts request bodies of at most 10MB (see
+// maxReqSize in package core). Hex doubles the size, so 4MB of
+// outputs leaves 2MB of the request for the transaction's inputs
+// and the rest of the template.
+const MaxBatchSize = 4e6
+
+// batchOutputOverhead is an upper bound on the bytes an output needs
+// besides its control program and reference data: its asset ID,
+// amount, version numbers and length prefixes.
+const batchOutputOverhead = 64
+
+// ErrBatchTooLarge is returned when the outputs of a
+// control_accounts_batch action would take more than
+// MaxBatchSize bytes.
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// BatchRecipient is one payment in a control_accounts_batch action.
+type BatchRecipient struct {
+	AccountID     string        `json:"account_id"`
+	Amount        uint64        `json:"amount"`
+	ReferenceData chainjson.Map `json:"reference_data"`
+}
+
+func (m *Manager) NewControlBatchAction(assetID bc.AssetID, recipients []BatchRecipient) txbuilder.Action {
+	return &controlBatchAction{
+		accounts:   m,
+		AssetID:    &assetID,
+		Recipients: recipients,
+	}
+}
+
+func (m *Manager) DecodeControlBatchAction(data []byte) (txbuilder.Action, error) {
+	a := &controlBatchAction{accounts: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// controlBatchAction pays a single asset to many accounts at once.
+// It allocates all of its control programs together, so a payout to
+// thousands of accounts costs one database insert rather than one
+// per recipient.
+type controlBatchAction struct {
+	accounts   *Manager
+	AssetID    *bc.AssetID      `json:"asset_id"`
+	Recipients []BatchRecipient `json:"recipients"`
+}
+
+func (a *controlBatchAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.AssetID == nil || a.AssetID.IsZero() {
+		missing = append(missing, "asset_id")
+	}
+	if len(a.Recipients) == 0 {
+		missing = append(missing, "recipients")
+	}
+	for i, r := range a.Recipients {
+		if r.AccountID == "" {
+			missing = append(missing, fmt.Sprintf("recipients[%d].account_id", i))
+		}
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	// Produce all the control programs, but don't insert them into the
+	// database until the whole template is built. Each account is
+	// looked up once, however many recipients it has.
+	accounts := make(map[string]*signers.Signer)
+	acps := make([]*controlProgram, 0, len(a.Recipients))
+	var size int
+	for i, r := range a.Recipients {
+		account, ok := accounts[r.AccountID]
+		if !ok {
+			var err error
+			account, err = a.accounts.findCurrent(ctx, r.AccountID)
+			if err != nil {
+				return errors.Wrapf(err, "recipient %d", i)
+			}
+			accounts[r.AccountID] = account
+		}
+		acp, err := a.accounts.deriveControlProgram(ctx, account, false, b.MaxTime())
+		if err != nil {
+			return errors.Wrapf(err, "recipient %d", i)
+		}
+		size += len(acp.controlProgram) + len(r.ReferenceData) + batchOutputOverhead
+		if size > MaxBatchSize {
+			return errors.WithDetailf(ErrBatchTooLarge, "outputs for the first %d of %d recipients exceed %d bytes", i+1, len(a.Recipients), int(MaxBatchSize))
+		}
+		acps = append(acps, acp)
+	}
+	a.accounts.insertControlProgramDelayed(ctx, b, acps...)
+
+	for i, r := range a.Recipients {
+		err := b.AddOutput(legacy.NewTxOutput(*a.AssetID, r.Amount, acps[i].controlProgram, r.ReferenceData))
+		if err != nil {
+			return errors.Wrapf(err, "recipient %d", i)
+		}
+	}
+	return nil
+}
+
+// insertControlProgramDelayed takes a template builder and account
+// control programs that haven't been inserted to the database yet. It
 // registers callbacks on the TemplateBuilder so that all of the template's
 // account control programs are batch inserted if building the rest of
 // the template is successful.
-func (m *Manager) insertControlProgramDelayed(ctx context.Context, b *txbuilder.TemplateBuilder, acp *controlProgram) {
+func (m *Manager) insertControlProgramDelayed(ctx context.Context, b *txbuilder.TemplateBuilder, acps ...*controlProgram) {
 	m.delayedACPsMu.Lock()
-	m.delayedACPs[b] = append(m.delayedACPs[b], acp)
+	m.delayedACPs[b] = append(m.delayedACPs[b], acps...)
 	m.delayedACPsMu.Unlock()
 
 	b.OnRollback(func() {
@@ -3,6 +3,7 @@ package account_test
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"chain/core/txbuilder"
 	"chain/database/pg"
 	"chain/database/pg/pgtest"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -119,6 +122,76 @@ func TestAccountSourceUTXOReserve(t *testing.T) {
 	}
 }
 
+func TestAccountSourceReserveFailover(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset, 2, accID)
+	coretest.CreatePins(ctx, t, pinStore)
+	// Make a block so that account UTXOs are available to spend.
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	assetAmount1 := bc.AssetAmount{AssetId: &asset, Amount: 1}
+	clientToken := "failover-idempotency-key"
+	tmpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{
+		accounts.NewSpendAction(assetAmount1, accID, nil, &clientToken),
+		accounts.NewControlAction(assetAmount1, accID, nil),
+	}, time.Now().Add(5*time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Simulate a new leader taking over with no in-memory state.
+	newAccounts := account.NewManager(db, c, pinStore)
+	err = newAccounts.RestoreReservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The only utxo is still reserved by the template.
+	builder := txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err = newAccounts.NewSpendAction(assetAmount1, accID, nil, nil).Build(ctx, builder)
+	if errors.Root(err) != account.ErrReserved {
+		t.Errorf("got error %v, want %v", err, account.ErrReserved)
+	}
+
+	// Retrying with the same client token returns the same utxo.
+	builder = txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err = newAccounts.NewSpendAction(assetAmount1, accID, nil, &clientToken).Build(ctx, builder)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, tx, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.DeepEqual(tx.Inputs, tmpl.Transaction.Inputs) {
+		t.Errorf("build txins\ngot:\n\t%+v\nwant:\n\t%+v", tx.Inputs, tmpl.Transaction.Inputs)
+	}
+
+	// The template built before failover can still be submitted.
+	coretest.SignTxTemplate(t, ctx, tmpl, nil)
+	err = txbuilder.FinalizeTx(ctx, c, g, tmpl.Transaction)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+}
+
 func TestAccountSourceReserveIdempotency(t *testing.T) {
 	var (
 		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
@@ -191,6 +264,60 @@ func TestAccountSourceReserveIdempotency(t *testing.T) {
 	}
 }
 
+func TestControlBatchAction(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+
+		accID1 = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		accID2 = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset  = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	batch := accounts.NewControlBatchAction(asset, []account.BatchRecipient{
+		{AccountID: accID1, Amount: 1},
+		{AccountID: accID2, Amount: 2},
+		{AccountID: accID1, Amount: 3},
+	})
+	builder := txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err := batch.Build(ctx, builder)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, tx, err := builder.Build()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	wantAccounts := []string{accID1, accID2, accID1}
+	if len(tx.Outputs) != len(wantAccounts) {
+		t.Fatalf("got %d outputs, want %d", len(tx.Outputs), len(wantAccounts))
+	}
+	for i, out := range tx.Outputs {
+		if out.Amount != uint64(i+1) {
+			t.Errorf("output %d has amount %d, want %d", i, out.Amount, i+1)
+		}
+		if !programInAccount(ctx, t, db, out.ControlProgram, wantAccounts[i]) {
+			t.Errorf("expected output %d control program to belong to account %s", i, wantAccounts[i])
+		}
+	}
+
+	refData := chainjson.Map(`"` + strings.Repeat("x", 1e6) + `"`)
+	tooLarge := make([]account.BatchRecipient, account.MaxBatchSize/1e6)
+	for i := range tooLarge {
+		tooLarge[i] = account.BatchRecipient{AccountID: accID1, Amount: 1, ReferenceData: refData}
+	}
+	batch = accounts.NewControlBatchAction(asset, tooLarge)
+	err = batch.Build(ctx, txbuilder.NewBuilder(time.Now().Add(5*time.Minute)))
+	if errors.Root(err) != account.ErrBatchTooLarge {
+		t.Errorf("got error %v, want ErrBatchTooLarge", err)
+	}
+}
+
 func programInAccount(ctx context.Context, t testing.TB, db pg.DB, program []byte, account string) bool {
 	const q = `SELECT signer_id=$1 FROM account_control_programs WHERE control_program=$2`
 	var in bool
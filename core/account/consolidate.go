@@ -0,0 +1,396 @@
+package account
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/core/signers"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+const (
+	// maxConsolidationInputs is the most UTXOs a single
+	// consolidation transaction spends.
+	maxConsolidationInputs = 100
+
+	// consolidationTTL is how long a consolidation transaction's
+	// UTXOs stay reserved.
+	consolidationTTL = 5 * time.Minute
+)
+
+// errNoQuorum is returned by merge when the Consolidator's signer
+// can't produce enough signatures to spend the UTXOs.
+var errNoQuorum = errors.New("signer can't produce a quorum of signatures")
+
+// Consolidator periodically merges the UTXOs of every account and
+// asset whose number of UTXOs exceeds a threshold. It builds, signs
+// and submits transactions that spend up to maxConsolidationInputs of
+// the smallest available UTXOs to a single output in the same account.
+// Accounts whose keys the signer can't produce a quorum of signatures
+// for are skipped until their keys are updated.
+type Consolidator struct {
+	accounts  *Manager
+	threshold func() int
+	sign      txbuilder.TemplateSigner
+	submitter txbuilder.Submitter
+
+	mu         sync.Mutex
+	status     ConsolidationStatus
+	unsignable map[string]int // account ID -> key version
+}
+
+// ConsolidationStatus reports the progress of a Consolidator.
+type ConsolidationStatus struct {
+	// Threshold is the UTXO count above which an account's UTXOs of
+	// an asset are consolidated. Zero means consolidation is disabled.
+	Threshold int `json:"threshold"`
+
+	// Pending is the number of (account, asset) pairs that were over
+	// the threshold at the last run.
+	Pending int `json:"pending"`
+
+	LastRunAt             time.Time `json:"last_run_at"`
+	TransactionsSubmitted uint64    `json:"transactions_submitted"`
+	OutputsConsolidated   uint64    `json:"outputs_consolidated"`
+	LastError             string    `json:"last_error,omitempty"`
+}
+
+// NewConsolidator returns a Consolidator for the accounts in m.
+// The threshold function returns the current UTXO count threshold;
+// a value less than 2 disables consolidation. Transactions are signed
+// with sign and submitted with s.
+func (m *Manager) NewConsolidator(threshold func() int, sign txbuilder.TemplateSigner, s txbuilder.Submitter) *Consolidator {
+	return &Consolidator{
+		accounts:   m,
+		threshold:  threshold,
+		sign:       sign,
+		submitter:  s,
+		unsignable: make(map[string]int),
+	}
+}
+
+// Status returns the Consolidator's progress so far.
+func (c *Consolidator) Status() ConsolidationStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Run consolidates UTXOs periodically.
+// It blocks until the context is canceled.
+func (c *Consolidator) Run(ctx context.Context, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf(ctx, "Deposed, Consolidator exiting")
+			return
+		case <-ticks:
+			err := c.consolidate(ctx)
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		}
+	}
+}
+
+// consolidate submits one consolidation transaction for each account
+// and asset with more than the threshold number of UTXOs.
+func (c *Consolidator) consolidate(ctx context.Context) error {
+	threshold := c.threshold()
+	if threshold < 2 {
+		c.mu.Lock()
+		c.status.Threshold = 0
+		c.status.Pending = 0
+		c.mu.Unlock()
+		return nil
+	}
+	if c.sign == nil {
+		return c.finish(threshold, 0, errors.New("no transaction signer configured"))
+	}
+
+	const q = `
+		SELECT account_id, asset_id FROM account_utxos u
+		WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.account_id = u.account_id AND a.watch_only)
+		GROUP BY account_id, asset_id
+		HAVING count(*) > $1
+	`
+	var srcs []source
+	err := pg.ForQueryRows(ctx, c.accounts.db, q, threshold, func(accountID string, assetID bc.AssetID) {
+		srcs = append(srcs, source{AccountID: accountID, AssetID: assetID})
+	})
+	if err != nil {
+		return c.finish(threshold, len(srcs), errors.Wrap(err, "counting account utxos"))
+	}
+
+	var firstErr error
+	for _, src := range srcs {
+		skip, err := c.skip(ctx, src.AccountID)
+		if err != nil {
+			return c.finish(threshold, len(srcs), err)
+		}
+		if skip {
+			continue
+		}
+		n, err := c.consolidateSource(ctx, src)
+		if errors.Root(err) == errNoQuorum {
+			log.Printkv(ctx, log.KeyError, err, "at", "skipping consolidation", "account_id", src.AccountID)
+			continue
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "consolidating asset %x in account %s", src.AssetID.Bytes(), src.AccountID)
+			log.Error(ctx, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		c.mu.Lock()
+		c.status.TransactionsSubmitted++
+		c.status.OutputsConsolidated += uint64(n)
+		c.mu.Unlock()
+	}
+	return c.finish(threshold, len(srcs), firstErr)
+}
+
+// skip reports whether the signer couldn't sign for the account's
+// current keys the last time it was consolidated.
+func (c *Consolidator) skip(ctx context.Context, accountID string) (bool, error) {
+	c.mu.Lock()
+	version, ok := c.unsignable[accountID]
+	c.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	acct, err := c.accounts.findCurrent(ctx, accountID)
+	if err != nil {
+		return false, errors.Wrap(err, "get account info")
+	}
+	if acct.KeyVersion == version {
+		return true, nil
+	}
+	c.mu.Lock()
+	delete(c.unsignable, accountID)
+	c.mu.Unlock()
+	return false, nil
+}
+
+func (c *Consolidator) finish(threshold, pending int, err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Threshold = threshold
+	c.status.Pending = pending
+	c.status.LastRunAt = time.Now()
+	c.status.LastError = ""
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	return err
+}
+
+// consolidateSource submits a transaction spending the smallest
+// unreserved UTXOs of src to a single output in the same account.
+// It returns the number of UTXOs spent, which is zero if fewer than
+// two are available.
+func (c *Consolidator) consolidateSource(ctx context.Context, src source) (int, error) {
+	const q = `
+		SELECT output_id, amount, key_version FROM account_utxos u
+		WHERE account_id = $1 AND asset_id = $2
+			AND NOT EXISTS (SELECT 1 FROM reserved_utxos r WHERE r.output_id = u.output_id)
+		ORDER BY amount, output_id
+		LIMIT $3
+	`
+	utxos, err := c.findUTXOs(ctx, q, src.AccountID, src.AssetID, maxConsolidationInputs)
+	if err != nil {
+		return 0, err
+	}
+	if len(utxos) < 2 {
+		// Everything else is reserved; try again later.
+		return 0, nil
+	}
+	_, err = c.merge(ctx, src, utxos)
+	if err != nil {
+		return 0, err
+	}
+	return len(utxos), nil
+}
+
+// SweepOldKeys submits transactions moving the account's unreserved
+// UTXOs that are locked with keys replaced by UpdateKeys to control
+// programs derived from the account's current keys. It returns the
+// IDs of the submitted transactions.
+func (c *Consolidator) SweepOldKeys(ctx context.Context, accountID string) ([]bc.Hash, error) {
+	if c.sign == nil {
+		return nil, errors.New("no transaction signer configured")
+	}
+	acct, err := c.accounts.findCurrent(ctx, accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get account info")
+	}
+
+	var assetIDs []bc.AssetID
+	const assetsQ = `
+		SELECT DISTINCT asset_id FROM account_utxos
+		WHERE account_id = $1 AND key_version <> $2
+	`
+	err = pg.ForQueryRows(ctx, c.accounts.db, assetsQ, accountID, acct.KeyVersion, func(assetID bc.AssetID) {
+		assetIDs = append(assetIDs, assetID)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "finding assets to sweep")
+	}
+
+	const q = `
+		SELECT output_id, amount, key_version FROM account_utxos u
+		WHERE account_id = $1 AND asset_id = $2 AND key_version <> $3
+			AND NOT EXISTS (SELECT 1 FROM reserved_utxos r WHERE r.output_id = u.output_id)
+		ORDER BY output_id
+		LIMIT $4
+	`
+	var txIDs []bc.Hash
+	for _, assetID := range assetIDs {
+		src := source{AccountID: accountID, AssetID: assetID}
+		for {
+			utxos, err := c.findUTXOs(ctx, q, accountID, assetID, acct.KeyVersion, maxConsolidationInputs)
+			if err != nil {
+				return txIDs, err
+			}
+			if len(utxos) == 0 {
+				break
+			}
+			txID, err := c.merge(ctx, src, utxos)
+			if err != nil {
+				return txIDs, errors.Wrapf(err, "sweeping asset %x", assetID.Bytes())
+			}
+			txIDs = append(txIDs, txID)
+			if len(utxos) < maxConsolidationInputs {
+				break
+			}
+		}
+	}
+	return txIDs, nil
+}
+
+// mergeUTXO is a UTXO to be spent by merge.
+type mergeUTXO struct {
+	outputID   bc.Hash
+	amount     uint64
+	keyVersion int
+}
+
+// findUTXOs runs q, which selects the output ID, amount and key
+// version of account UTXOs, with args.
+func (c *Consolidator) findUTXOs(ctx context.Context, q string, args ...interface{}) ([]mergeUTXO, error) {
+	var utxos []mergeUTXO
+	args = append(args, func(outputID bc.Hash, amount uint64, keyVersion int) {
+		utxos = append(utxos, mergeUTXO{outputID, amount, keyVersion})
+	})
+	err := pg.ForQueryRows(ctx, c.accounts.db, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding utxos")
+	}
+	return utxos, nil
+}
+
+// merge builds, signs and submits a transaction spending utxos
+// to a single output in src's account, derived from the account's
+// current keys. It returns the transaction's ID. If the transaction
+// isn't submitted, merge cancels the reservations of utxos made
+// while building it.
+func (c *Consolidator) merge(ctx context.Context, src source, utxos []mergeUTXO) (bc.Hash, error) {
+	var (
+		actions  []txbuilder.Action
+		total    uint64
+		versions = make(map[int]bool)
+	)
+	for _, u := range utxos {
+		actions = append(actions, c.accounts.NewSpendUTXOAction(u.outputID))
+		total += u.amount
+		versions[u.keyVersion] = true
+	}
+	assetID := src.AssetID
+	actions = append(actions, c.accounts.NewControlAction(bc.AssetAmount{AssetId: &assetID, Amount: total}, src.AccountID, nil))
+
+	tpl, err := txbuilder.Build(ctx, nil, actions, time.Now().Add(consolidationTTL))
+	if err != nil {
+		return bc.Hash{}, errors.Wrap(err, "building transaction")
+	}
+	err = c.signAndSubmit(ctx, src, tpl, versions)
+	if err != nil {
+		outputIDs := make([]bc.Hash, 0, len(utxos))
+		for _, u := range utxos {
+			outputIDs = append(outputIDs, u.outputID)
+		}
+		cancelErr := c.accounts.utxoDB.CancelUTXOs(ctx, src, outputIDs)
+		if cancelErr != nil {
+			log.Error(ctx, cancelErr)
+		}
+		return bc.Hash{}, err
+	}
+	return tpl.Transaction.ID, nil
+}
+
+// signAndSubmit signs tpl with every version of src's account keys
+// in versions and submits it. If the signer can't produce a quorum of
+// signatures for every input, it returns errNoQuorum and remembers the
+// account's current key version so that consolidate skips the account.
+func (c *Consolidator) signAndSubmit(ctx context.Context, src source, tpl *txbuilder.Template, versions map[int]bool) error {
+	acct, err := c.accounts.findByID(ctx, src.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "get account info")
+	}
+	// Sign with every version of the account's keys
+	// that locked one of the UTXOs.
+	var xpubs []chainkd.XPub
+	for v := range versions {
+		keys, err := signers.FindVersion(ctx, c.accounts.db, acct, v)
+		if err != nil {
+			return errors.Wrap(err, "get account keys")
+		}
+		xpubs = append(xpubs, keys.XPubs...)
+	}
+	err = c.sign(ctx, tpl, xpubs)
+	if err != nil {
+		return errors.Wrap(err, "signing transaction")
+	}
+	if !hasQuorum(tpl) {
+		c.mu.Lock()
+		c.unsignable[src.AccountID] = acct.KeyVersion
+		c.mu.Unlock()
+		return errors.WithDetailf(errNoQuorum, "account %s", src.AccountID)
+	}
+	err = txbuilder.FinalizeTx(ctx, c.accounts.chain, c.submitter, tpl.Transaction)
+	return errors.Wrap(err, "submitting transaction")
+}
+
+// hasQuorum reports whether every signature witness
+// in tpl has a quorum of signatures.
+func hasQuorum(tpl *txbuilder.Template) bool {
+	for _, sigInst := range tpl.SigningInstructions {
+		for _, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(*txbuilder.SignatureWitness)
+			if !ok {
+				continue
+			}
+			var n int
+			for _, sig := range sw.Sigs {
+				if len(sig) > 0 {
+					n++
+				}
+			}
+			if n < sw.Quorum {
+				return false
+			}
+		}
+	}
+	return true
+}
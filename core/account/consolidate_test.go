@@ -0,0 +1,200 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/coretest"
+	"chain/core/generator"
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestConsolidate(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	for i := 0; i < 4; i++ {
+		coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset, 1, accID)
+	}
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	sign := func(_ context.Context, _ chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+		return testutil.TestXPrv.Derive(path).Sign(data[:]), nil
+	}
+	threshold := 5
+	consolidator := accounts.NewConsolidator(func() int { return threshold }, txbuilder.SignWith(sign), g)
+
+	// Four utxos are under the threshold.
+	err := account.Consolidate(consolidator, ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got := consolidator.Status(); got.Pending != 0 || got.TransactionsSubmitted != 0 {
+		t.Fatalf("status = %+v, want nothing pending or submitted", got)
+	}
+
+	threshold = 3
+	err = account.Consolidate(consolidator, ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got := consolidator.Status()
+	if got.Threshold != 3 || got.Pending != 1 || got.TransactionsSubmitted != 1 || got.OutputsConsolidated != 4 {
+		t.Errorf("status = %+v, want 1 pending, 1 tx submitted and 4 outputs consolidated", got)
+	}
+
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+	<-pinStore.PinWaiter(account.DeleteSpentsPinName, c.Height())
+
+	var n, total int
+	err = db.QueryRowContext(ctx, `SELECT count(*), sum(amount) FROM account_utxos WHERE account_id = $1`, accID).Scan(&n, &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || total != 4 {
+		t.Errorf("got %d utxos totaling %d, want 1 totaling 4", n, total)
+	}
+}
+
+func TestConsolidateNoQuorum(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	for i := 0; i < 3; i++ {
+		coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset, 1, accID)
+	}
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	// The signer doesn't hold the account's key.
+	var calls int
+	sign := func(context.Context, chainkd.XPub, [][]byte, [32]byte) ([]byte, error) {
+		calls++
+		return nil, nil
+	}
+	consolidator := accounts.NewConsolidator(func() int { return 2 }, txbuilder.SignWith(sign), g)
+
+	for i := 0; i < 2; i++ {
+		err := account.Consolidate(consolidator, ctx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	if got := consolidator.Status(); got.TransactionsSubmitted != 0 {
+		t.Errorf("status = %+v, want nothing submitted", got)
+	}
+	if calls != 3 {
+		t.Errorf("signer called %d times, want 3 (only on the first run)", calls)
+	}
+
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT count(*) FROM reservations`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("got %d reservations, want 0", n)
+	}
+}
+
+func TestSweepOldKeys(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	for i := 0; i < 2; i++ {
+		coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset, 1, accID)
+	}
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	newXPrv := testutil.TestXPrv.Child([]byte{1}, true)
+	_, err := accounts.UpdateKeys(ctx, &accID, nil, []chainkd.XPub{newXPrv.XPub()}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	sign := func(_ context.Context, xpub chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+		for _, xprv := range []chainkd.XPrv{testutil.TestXPrv, newXPrv} {
+			if xprv.XPub() == xpub {
+				return xprv.Derive(path).Sign(data[:]), nil
+			}
+		}
+		return nil, nil
+	}
+	consolidator := accounts.NewConsolidator(func() int { return 0 }, txbuilder.SignWith(sign), g)
+	txIDs, err := consolidator.SweepOldKeys(ctx, accID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(txIDs) != 1 {
+		t.Fatalf("swept with %d transactions, want 1", len(txIDs))
+	}
+
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+	<-pinStore.PinWaiter(account.DeleteSpentsPinName, c.Height())
+
+	var n, total, version int
+	err = db.QueryRowContext(ctx, `SELECT count(*), sum(amount), max(key_version) FROM account_utxos WHERE account_id = $1`, accID).Scan(&n, &total, &version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || total != 2 || version != 2 {
+		t.Errorf("got %d utxos totaling %d at key version %d, want 1 totaling 2 at version 2", n, total, version)
+	}
+}
@@ -0,0 +1,3 @@
+package account
+
+var Consolidate = (*Consolidator).consolidate
@@ -39,10 +39,12 @@ type Saver interface {
 
 func Annotated(a *Account) (*query.AnnotatedAccount, error) {
 	aa := &query.AnnotatedAccount{
-		ID:     a.ID,
-		Alias:  a.Alias,
-		Quorum: a.Quorum,
-		Tags:   &emptyJSONObject,
+		ID:          a.ID,
+		Alias:       a.Alias,
+		Keys:        []*query.AccountKey{},
+		Quorum:      a.Quorum,
+		Tags:        &emptyJSONObject,
+		IsWatchOnly: query.Bool(a.WatchOnly),
 	}
 
 	tags, err := json.Marshal(a.Tags)
@@ -93,9 +95,10 @@ type rawOutput struct {
 
 type accountOutput struct {
 	rawOutput
-	AccountID string
-	keyIndex  uint64
-	change    bool
+	AccountID  string
+	keyIndex   uint64
+	keyVersion int
+	change     bool
 }
 
 func (m *Manager) ProcessBlocks(ctx context.Context) {
@@ -195,17 +198,18 @@ func (m *Manager) loadAccountInfo(ctx context.Context, outs []*rawOutput) ([]*ac
 	result := make([]*accountOutput, 0, len(outs))
 
 	const q = `
-		SELECT signer_id, key_index, control_program, change
+		SELECT signer_id, key_index, key_version, control_program, change
 		FROM account_control_programs
 		WHERE control_program IN (SELECT unnest($1::bytea[]))
 	`
-	err := pg.ForQueryRows(ctx, m.db, q, scripts, func(accountID string, keyIndex uint64, program []byte, change bool) {
+	err := pg.ForQueryRows(ctx, m.db, q, scripts, func(accountID string, keyIndex uint64, keyVersion int, program []byte, change bool) {
 		for _, out := range outsByScript[string(program)] {
 			newOut := &accountOutput{
-				rawOutput: *out,
-				AccountID: accountID,
-				keyIndex:  keyIndex,
-				change:    change,
+				rawOutput:  *out,
+				AccountID:  accountID,
+				keyIndex:   keyIndex,
+				keyVersion: keyVersion,
+				change:     change,
 			}
 			result = append(result, newOut)
 		}
@@ -232,6 +236,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos pq.Int64Array
 		refData   pq.ByteaArray
 		change    pq.BoolArray
+		version   pq.Int64Array
 	)
 	for _, out := range outs {
 		outputID = append(outputID, out.OutputID.Bytes())
@@ -244,14 +249,16 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos = append(sourcePos, int64(out.sourcePos))
 		refData = append(refData, out.refData.Bytes())
 		change = append(change, out.change)
+		version = append(version, int64(out.keyVersion))
 	}
 
 	const q = `
 		INSERT INTO account_utxos (output_id, asset_id, amount, account_id, control_program_index,
-			control_program, confirmed_in, source_id, source_pos, ref_data_hash, change)
+			control_program, confirmed_in, source_id, source_pos, ref_data_hash, change, key_version)
 		SELECT unnest($1::bytea[]), unnest($2::bytea[]),  unnest($3::bigint[]),
 			   unnest($4::text[]), unnest($5::bigint[]), unnest($6::bytea[]), $7,
-			   unnest($8::bytea[]), unnest($9::bigint[]), unnest($10::bytea[]), unnest($11::boolean[])
+			   unnest($8::bytea[]), unnest($9::bigint[]), unnest($10::bytea[]), unnest($11::boolean[]),
+			   unnest($12::integer[])
 		ON CONFLICT (output_id) DO NOTHING
 	`
 	_, err := m.db.ExecContext(ctx, q,
@@ -266,6 +273,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos,
 		refData,
 		change,
+		version,
 	)
 	return errors.Wrap(err)
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	"github.com/lib/pq"
+
 	"chain/core/pin"
 	"chain/database/pg"
 	"chain/errors"
@@ -30,6 +33,10 @@ var (
 	// new change outputs will be created
 	// in sufficient amounts to satisfy the request.
 	ErrReserved = errors.New("reservation found outputs already reserved")
+
+	// ErrCanceled indicates that a transaction spends outputs whose
+	// reservation was canceled by a client.
+	ErrCanceled = errors.New("reservation canceled")
 )
 
 // utxo describes an individual account utxo.
@@ -48,6 +55,11 @@ type utxo struct {
 
 	AccountID           string
 	ControlProgramIndex uint64
+	ConfirmedIn         uint64
+
+	// KeyVersion is the version of the account's keys
+	// that ControlProgram was derived from.
+	KeyVersion int
 }
 
 func (u *utxo) source() source {
@@ -71,6 +83,27 @@ type reservation struct {
 	ClientToken *string
 }
 
+// Reservation describes an outstanding reservation of account UTXOs,
+// such as one held by a transaction template.
+type Reservation struct {
+	ID          uint64     `json:"id"`
+	AccountID   string     `json:"account_id"`
+	AssetID     bc.AssetID `json:"asset_id"`
+	Amount      uint64     `json:"amount"`
+	Change      uint64     `json:"change"`
+	OutputIDs   []bc.Hash  `json:"output_ids"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ClientToken *string    `json:"client_token,omitempty"`
+}
+
+// ReservationFilter selects the reservations returned by
+// ListReservations. Zero-valued fields match every reservation.
+type ReservationFilter struct {
+	AccountID     string
+	AssetID       *bc.AssetID
+	ExpiresBefore time.Time
+}
+
 func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
 	return &reserver{
 		c:            c,
@@ -81,10 +114,12 @@ func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
 	}
 }
 
-// reserver implements a utxo reserver that stores reservations
-// in-memory. It relies on the account_utxos table for the source of
-// truth of valid UTXOs but tracks which of those UTXOs are reserved
-// in-memory.
+// reserver implements a utxo reserver. It relies on the account_utxos
+// table for the source of truth of valid UTXOs and tracks which of
+// those UTXOs are reserved in-memory. Every reservation is also
+// written to the reservations and reserved_utxos tables, so that
+// a process that becomes leader can rebuild the in-memory state
+// with Restore, and so that no two processes reserve the same UTXO.
 //
 // To reduce latency and prevent deadlock, no two mutexes (either on
 // reserver or sourceReserver) should be held at the same time
@@ -92,11 +127,10 @@ func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
 // reserver ensures idempotency of reservations until the reservation
 // expiration.
 type reserver struct {
-	c                 *protocol.Chain
-	db                pg.DB
-	pinStore          *pin.Store
-	nextReservationID uint64
-	idempotency       idempotency.Group
+	c           *protocol.Chain
+	db          pg.DB
+	pinStore    *pin.Store
+	idempotency idempotency.Group
 
 	reservationsMu sync.Mutex
 	reservations   map[uint64]*reservation
@@ -106,24 +140,37 @@ type reserver struct {
 }
 
 // Reserve selects and reserves UTXOs according to the criteria provided
-// in source. The resulting reservation expires at exp.
-func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (*reservation, error) {
+// in source, choosing among them with strategy sel. The resulting
+// reservation expires at exp.
+func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, sel Selection, clientToken *string, exp time.Time) (*reservation, error) {
 	if clientToken == nil {
-		return re.reserve(ctx, src, amount, clientToken, exp)
+		return re.reserve(ctx, src, amount, sel, clientToken, exp)
 	}
 
 	untypedRes, err := re.idempotency.Once(*clientToken, func() (interface{}, error) {
-		return re.reserve(ctx, src, amount, clientToken, exp)
+		return re.reserve(ctx, src, amount, sel, clientToken, exp)
 	})
 	return untypedRes.(*reservation), err
 }
 
-func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (res *reservation, err error) {
+func (re *reserver) reserve(ctx context.Context, src source, amount uint64, sel Selection, clientToken *string, exp time.Time) (res *reservation, err error) {
+	if clientToken != nil {
+		// The reservation may have been made before this process
+		// became leader.
+		res, err = re.findByClientToken(ctx, *clientToken)
+		if err != nil || res != nil {
+			return res, err
+		}
+	}
+
+	rid, err := re.nextID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	sourceReserver := re.source(src)
 
 	// Try to reserve the right amount.
-	rid := atomic.AddUint64(&re.nextReservationID, 1)
-	reserved, total, err := sourceReserver.reserve(ctx, rid, amount)
+	reserved, total, err := sourceReserver.reserve(ctx, rid, amount, sel)
 	if err != nil {
 		return nil, err
 	}
@@ -136,16 +183,11 @@ func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clie
 		ClientToken: clientToken,
 	}
 
-	// Save the successful reservation.
-	re.reservationsMu.Lock()
-	defer re.reservationsMu.Unlock()
-	re.reservations[rid] = res
-
 	// Make change if necessary
 	if total > amount {
 		res.Change = total - amount
 	}
-	return res, nil
+	return re.save(ctx, res)
 }
 
 // ReserveUTXO reserves a specific utxo for spending. The resulting
@@ -170,7 +212,17 @@ func (re *reserver) reserveUTXO(ctx context.Context, out bc.Hash, exp time.Time,
 		return nil, pg.ErrUserInputNotFound
 	}
 
-	rid := atomic.AddUint64(&re.nextReservationID, 1)
+	if clientToken != nil {
+		res, err := re.findByClientToken(ctx, *clientToken)
+		if err != nil || res != nil {
+			return res, err
+		}
+	}
+
+	rid, err := re.nextID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	err = re.source(u.source()).reserveUTXO(rid, u)
 	if err != nil {
 		return nil, err
@@ -183,15 +235,96 @@ func (re *reserver) reserveUTXO(ctx context.Context, out bc.Hash, exp time.Time,
 		Expiry:      exp,
 		ClientToken: clientToken,
 	}
-	re.reservationsMu.Lock()
-	re.reservations[rid] = res
-	re.reservationsMu.Unlock()
-	return res, nil
+	return re.save(ctx, res)
+}
+
+func (re *reserver) nextID(ctx context.Context) (uint64, error) {
+	var rid uint64
+	err := re.db.QueryRowContext(ctx, `SELECT nextval('reservations_id_seq')`).Scan(&rid)
+	return rid, errors.Wrap(err, "getting reservation id")
+}
+
+// save persists res, whose UTXOs must already be reserved in-memory,
+// and records it in re.reservations. If another process holds a
+// reservation on one of the UTXOs, save cancels res and returns
+// ErrReserved. If another process already made a reservation with the
+// same client token, save cancels res and returns that reservation
+// instead.
+func (re *reserver) save(ctx context.Context, res *reservation) (*reservation, error) {
+	// The reservation is only inserted if none of its UTXOs are
+	// already reserved, and conflicts are skipped rather than
+	// raised, so that a failed reservation doesn't abort the
+	// enclosing transaction when re.db is one.
+	const q = `
+		WITH res AS (
+			INSERT INTO reservations (id, account_id, asset_id, change, expiry, client_token)
+			SELECT $1::bigint, $2::text, $3::bytea, $4::bigint, $5::timestamptz, $6::text
+			WHERE NOT EXISTS (SELECT 1 FROM reserved_utxos WHERE output_id = ANY($7::bytea[]))
+			ON CONFLICT DO NOTHING
+			RETURNING id
+		), ins AS (
+			INSERT INTO reserved_utxos (output_id, reservation_id)
+			SELECT unnest($7::bytea[]), id FROM res
+			ON CONFLICT DO NOTHING
+			RETURNING output_id
+		), uncanceled AS (
+			DELETE FROM canceled_utxos WHERE output_id IN (SELECT output_id FROM ins)
+		)
+		SELECT (SELECT count(*) FROM res), (SELECT count(*) FROM ins)
+	`
+	var outputIDs pq.ByteaArray
+	for _, u := range res.UTXOs {
+		outputIDs = append(outputIDs, u.OutputID.Bytes())
+	}
+	var nres, nreserved int
+	err := re.db.QueryRowContext(ctx, q, res.ID, res.Source.AccountID, res.Source.AssetID,
+		res.Change, res.Expiry, res.ClientToken, outputIDs).Scan(&nres, &nreserved)
+	if err != nil {
+		re.source(res.Source).cancel(res)
+		return nil, errors.Wrap(err, "saving reservation")
+	}
+	if nres == 1 && nreserved == len(outputIDs) {
+		re.reservationsMu.Lock()
+		re.reservations[res.ID] = res
+		re.reservationsMu.Unlock()
+		return res, nil
+	}
+
+	re.source(res.Source).cancel(res)
+	if nres == 1 {
+		// Another process reserved some of the UTXOs concurrently.
+		_, err = re.db.ExecContext(ctx, `DELETE FROM reservations WHERE id = $1`, res.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "deleting partial reservation")
+		}
+	}
+	if res.ClientToken != nil {
+		existing, err := re.findByClientToken(ctx, *res.ClientToken)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	// Some of the UTXOs were reserved by another process. Load its
+	// reservations on this source so that they're skipped next time.
+	_, err = re.load(ctx, `r.account_id = $2 AND r.asset_id = $3`, res.Source.AccountID, res.Source.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrReserved
 }
 
 // Cancel makes a best-effort attempt at canceling the reservation with
 // the provided ID.
 func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
+	_, err := re.db.ExecContext(ctx, `DELETE FROM reservations WHERE id = $1`, rid)
+	if err != nil {
+		return errors.Wrap(err, "deleting reservation")
+	}
+
 	re.reservationsMu.Lock()
 	res, ok := re.reservations[rid]
 	delete(re.reservations, rid)
@@ -206,11 +339,46 @@ func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
 	return nil
 }
 
-// ExpireReservations cleans up all reservations that have expired,
-// making their UTXOs available for reservation again.
+// CancelUTXOs cancels the reservations held by this process
+// on any of outputIDs, which must all belong to src.
+func (re *reserver) CancelUTXOs(ctx context.Context, src source, outputIDs []bc.Hash) error {
+	sr := re.source(src)
+	rids := make(map[uint64]bool)
+	sr.mu.Lock()
+	for _, id := range outputIDs {
+		if rid, ok := sr.reserved[id]; ok {
+			rids[rid] = true
+		}
+	}
+	sr.mu.Unlock()
+
+	for rid := range rids {
+		err := re.Cancel(ctx, rid)
+		if err != nil {
+			return errors.Wrapf(err, "canceling reservation %d", rid)
+		}
+	}
+	return nil
+}
+
+// ExpireReservations cleans up all reservations that have expired
+// or whose records have been deleted, making their UTXOs available
+// for reservation again.
 func (re *reserver) ExpireReservations(ctx context.Context) error {
-	// Remove records of any reservations that have expired.
+	// Remove records of any reservations that have expired,
+	// including those made by other processes.
 	now := time.Now()
+	const q = `
+		WITH canceled AS (
+			DELETE FROM canceled_utxos WHERE expiry < $1
+		)
+		DELETE FROM reservations WHERE expiry < $1
+	`
+	_, err := re.db.ExecContext(ctx, q, now)
+	if err != nil {
+		return errors.Wrap(err, "deleting expired reservations")
+	}
+
 	var canceled []*reservation
 	re.reservationsMu.Lock()
 	for rid, res := range re.reservations {
@@ -230,12 +398,302 @@ func (re *reserver) ExpireReservations(ctx context.Context) error {
 		}
 	}
 
+	// Forget reservations whose records another process deleted,
+	// such as a Core that handled a request to cancel them.
+	var rids pq.Int64Array
+	re.reservationsMu.Lock()
+	for rid := range re.reservations {
+		rids = append(rids, int64(rid))
+	}
+	re.reservationsMu.Unlock()
+	err = re.forgetDeleted(ctx, rids)
+	if err != nil {
+		return err
+	}
+
 	// TODO(jackson): Cleanup any source reservers that don't have
 	// anything reserved. It'll be a little tricky because of our
 	// locking scheme.
 	return nil
 }
 
+// Restore loads all unexpired reservations into memory, including
+// those made by other processes, and forgets any that have since been
+// canceled or expired. A process that becomes leader calls it so that
+// UTXOs reserved by the previous leader stay reserved.
+func (re *reserver) Restore(ctx context.Context) error {
+	loaded, err := re.load(ctx, `TRUE`)
+	if err != nil {
+		return err
+	}
+	found := make(map[uint64]bool, len(loaded))
+	for _, res := range loaded {
+		found[res.ID] = true
+	}
+
+	var missing pq.Int64Array
+	re.reservationsMu.Lock()
+	for rid := range re.reservations {
+		if !found[rid] {
+			missing = append(missing, int64(rid))
+		}
+	}
+	re.reservationsMu.Unlock()
+
+	// Reservations saved after the load began are still valid, as
+	// are those whose UTXOs have been spent since.
+	return re.forgetDeleted(ctx, missing)
+}
+
+// forgetDeleted forgets the in-memory reservations among rids
+// whose records are no longer in the database.
+func (re *reserver) forgetDeleted(ctx context.Context, rids pq.Int64Array) error {
+	if len(rids) == 0 {
+		return nil
+	}
+	found := make(map[uint64]bool, len(rids))
+	const q = `SELECT id FROM reservations WHERE id = ANY($1::bigint[])`
+	err := pg.ForQueryRows(ctx, re.db, q, rids, func(rid uint64) {
+		found[rid] = true
+	})
+	if err != nil {
+		return errors.Wrap(err, "checking reservations")
+	}
+
+	var gone []*reservation
+	re.reservationsMu.Lock()
+	for _, rid := range rids {
+		res, ok := re.reservations[uint64(rid)]
+		if ok && !found[uint64(rid)] {
+			gone = append(gone, res)
+			delete(re.reservations, uint64(rid))
+		}
+	}
+	re.reservationsMu.Unlock()
+	for _, res := range gone {
+		re.source(res.Source).cancel(res)
+		if res.ClientToken != nil {
+			re.idempotency.Forget(*res.ClientToken)
+		}
+	}
+	return nil
+}
+
+// List returns the unexpired reservations matching f, ordered by ID
+// and starting after the reservation with ID after. It reads the
+// reservations made by every process from the database, and leaves
+// the in-memory state alone. Reservations whose UTXOs have all been
+// spent are omitted.
+func (re *reserver) List(ctx context.Context, f ReservationFilter, after uint64, limit int) ([]*reservation, error) {
+	conds := []string{"r.expiry > $1", "r.id > $2"}
+	args := []interface{}{time.Now(), after}
+	if f.AccountID != "" {
+		args = append(args, f.AccountID)
+		conds = append(conds, fmt.Sprintf("r.account_id = $%d", len(args)))
+	}
+	if f.AssetID != nil {
+		args = append(args, *f.AssetID)
+		conds = append(conds, fmt.Sprintf("r.asset_id = $%d", len(args)))
+	}
+	if !f.ExpiresBefore.IsZero() {
+		args = append(args, f.ExpiresBefore)
+		conds = append(conds, fmt.Sprintf("r.expiry < $%d", len(args)))
+	}
+	args = append(args, limit)
+	q := `
+		WITH page AS (
+			SELECT r.id, r.account_id, r.asset_id, r.change, r.expiry, r.client_token
+			FROM reservations r
+			WHERE ` + strings.Join(conds, " AND ") + `
+				AND EXISTS (
+					SELECT 1 FROM reserved_utxos ru
+					JOIN account_utxos u ON u.output_id = ru.output_id
+					WHERE ru.reservation_id = r.id
+				)
+			ORDER BY r.id
+			LIMIT $` + strconv.Itoa(len(args)) + `
+		)
+		SELECT p.id, p.account_id, p.asset_id, p.change, p.expiry, p.client_token,
+			u.output_id, u.amount
+		FROM page p
+		JOIN reserved_utxos ru ON ru.reservation_id = p.id
+		JOIN account_utxos u ON u.output_id = ru.output_id
+		ORDER BY p.id
+	`
+	var list []*reservation
+	args = append(args, func(rid uint64, accountID string, assetID bc.AssetID, change uint64, exp time.Time, clientToken sql.NullString, oid bc.Hash, amount uint64) {
+		if len(list) == 0 || list[len(list)-1].ID != rid {
+			res := &reservation{
+				ID:     rid,
+				Source: source{AssetID: assetID, AccountID: accountID},
+				Change: change,
+				Expiry: exp,
+			}
+			if clientToken.Valid {
+				res.ClientToken = &clientToken.String
+			}
+			list = append(list, res)
+		}
+		res := list[len(list)-1]
+		res.UTXOs = append(res.UTXOs, &utxo{
+			OutputID:  oid,
+			AssetID:   assetID,
+			Amount:    amount,
+			AccountID: accountID,
+		})
+	})
+	err := pg.ForQueryRows(ctx, re.db, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing reservations")
+	}
+	return list, nil
+}
+
+// Revoke cancels the reservation with the provided ID at a client's
+// request. Unlike Cancel, it remembers the reservation's UTXOs until
+// the reservation would have expired, so that a template built on it
+// fails CheckCanceled unless the UTXOs are reserved again.
+func (re *reserver) Revoke(ctx context.Context, rid uint64) error {
+	const q = `
+		WITH res AS (
+			DELETE FROM reservations WHERE id = $1 RETURNING id, expiry
+		)
+		INSERT INTO canceled_utxos (output_id, reservation_id, expiry)
+		SELECT ru.output_id, res.id, res.expiry
+		FROM reserved_utxos ru, res WHERE ru.reservation_id = res.id
+		RETURNING reservation_id
+	`
+	var found bool
+	err := pg.ForQueryRows(ctx, re.db, q, rid, func(uint64) { found = true })
+	if err != nil {
+		return errors.Wrap(err, "canceling reservation")
+	}
+
+	re.reservationsMu.Lock()
+	res, ok := re.reservations[rid]
+	delete(re.reservations, rid)
+	re.reservationsMu.Unlock()
+	if ok {
+		re.source(res.Source).cancel(res)
+		if res.ClientToken != nil {
+			re.idempotency.Forget(*res.ClientToken)
+		}
+	}
+	if !found && !ok {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "reservation %d", rid)
+	}
+	return nil
+}
+
+// CheckCanceled returns ErrCanceled if any of outputIDs belongs to a
+// reservation canceled with Revoke and hasn't been reserved since.
+func (re *reserver) CheckCanceled(ctx context.Context, outputIDs []bc.Hash) error {
+	const q = `
+		SELECT reservation_id FROM canceled_utxos
+		WHERE output_id = ANY($1::bytea[]) AND expiry > $2
+		LIMIT 1
+	`
+	var ids pq.ByteaArray
+	for _, id := range outputIDs {
+		ids = append(ids, id.Bytes())
+	}
+	var rid uint64
+	err := re.db.QueryRowContext(ctx, q, ids, time.Now()).Scan(&rid)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "checking canceled reservations")
+	}
+	return errors.WithDetailf(ErrCanceled, "reservation %d was canceled", rid)
+}
+
+// findByClientToken returns the unexpired reservation made with
+// clientToken, or nil if there isn't one.
+func (re *reserver) findByClientToken(ctx context.Context, clientToken string) (*reservation, error) {
+	var rid uint64
+	err := re.db.QueryRowContext(ctx, `SELECT id FROM reservations WHERE client_token = $1 AND expiry > $2`, clientToken, time.Now()).Scan(&rid)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "looking up client token")
+	}
+
+	_, err = re.load(ctx, `r.id = $2`, rid)
+	if err != nil {
+		return nil, err
+	}
+	re.reservationsMu.Lock()
+	res := re.reservations[rid]
+	re.reservationsMu.Unlock()
+	return res, nil
+}
+
+// load reads the unexpired reservations matching the SQL condition
+// cond, which may refer to args starting at $2, and adds any that
+// aren't already known to this process to the in-memory state.
+// Reservations whose UTXOs have all been spent are omitted.
+func (re *reserver) load(ctx context.Context, cond string, args ...interface{}) ([]*reservation, error) {
+	q := `
+		SELECT r.id, r.account_id, r.asset_id, r.change, r.expiry, r.client_token,
+			u.output_id, u.amount, u.control_program_index, u.control_program,
+			u.source_id, u.source_pos, u.ref_data_hash, u.confirmed_in, u.key_version
+		FROM reservations r
+		JOIN reserved_utxos ru ON ru.reservation_id = r.id
+		JOIN account_utxos u ON u.output_id = ru.output_id
+		WHERE r.expiry > $1 AND ` + cond + `
+		ORDER BY r.id
+	`
+	var loaded []*reservation
+	queryArgs := append([]interface{}{time.Now()}, args...)
+	queryArgs = append(queryArgs, func(rid uint64, accountID string, assetID bc.AssetID, change uint64, exp time.Time, clientToken sql.NullString,
+		oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, sourceID bc.Hash, sourcePos uint64, refData bc.Hash, confirmedIn uint64, keyVersion int) {
+
+		if len(loaded) == 0 || loaded[len(loaded)-1].ID != rid {
+			res := &reservation{
+				ID:     rid,
+				Source: source{AssetID: assetID, AccountID: accountID},
+				Change: change,
+				Expiry: exp,
+			}
+			if clientToken.Valid {
+				res.ClientToken = &clientToken.String
+			}
+			loaded = append(loaded, res)
+		}
+		res := loaded[len(loaded)-1]
+		res.UTXOs = append(res.UTXOs, &utxo{
+			OutputID:            oid,
+			SourceID:            sourceID,
+			AssetID:             assetID,
+			Amount:              amount,
+			SourcePos:           sourcePos,
+			ControlProgram:      controlProg,
+			RefDataHash:         refData,
+			AccountID:           accountID,
+			ControlProgramIndex: cpIndex,
+			ConfirmedIn:         confirmedIn,
+			KeyVersion:          keyVersion,
+		})
+	})
+	err := pg.ForQueryRows(ctx, re.db, q, queryArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading reservations")
+	}
+
+	for _, res := range loaded {
+		re.reservationsMu.Lock()
+		_, ok := re.reservations[res.ID]
+		if !ok {
+			re.reservations[res.ID] = res
+		}
+		re.reservationsMu.Unlock()
+		if !ok {
+			re.source(res.Source).restore(res)
+		}
+	}
+	return loaded, nil
+}
+
 func (re *reserver) checkUTXO(u *utxo) bool {
 	_, s := re.c.State()
 	return s.Tree.Contains(u.OutputID.Bytes())
@@ -276,8 +734,8 @@ type sourceReserver struct {
 	lastHeight uint64
 }
 
-func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64) ([]*utxo, uint64, error) {
-	reservedUTXOs, reservedAmount, err := sr.reserveFromCache(rid, amount)
+func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64, sel Selection) ([]*utxo, uint64, error) {
+	reservedUTXOs, reservedAmount, err := sr.reserveFromCache(rid, amount, sel)
 	if err == nil {
 		return reservedUTXOs, reservedAmount, nil
 	}
@@ -288,54 +746,109 @@ func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64
 		return nil, 0, err
 	}
 
-	return sr.reserveFromCache(rid, amount)
+	return sr.reserveFromCache(rid, amount, sel)
 }
 
-func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo, uint64, error) {
-	var (
-		reserved, unavailable uint64
-		reservedUTXOs         []*utxo
-	)
+// reserveFromCache selects cached UTXOs with sel and reserves them
+// for rid. The selection runs without holding sr.mu, since some
+// strategies search many combinations of UTXOs.
+func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64, sel Selection) ([]*utxo, uint64, error) {
+	for {
+		candidates, unavailable := sr.unreserved()
+		selected, total, err := sr.selectValid(candidates, unavailable, amount, sel)
+		if err != nil {
+			return nil, 0, err
+		}
+		if sr.markReserved(rid, selected) {
+			return selected, total, nil
+		}
+		// Another reservation took some of the selected UTXOs
+		// while sr.mu was released. Select again.
+	}
+}
+
+// unreserved returns the cached UTXOs that aren't reserved
+// and the total amount of those that are.
+func (sr *sourceReserver) unreserved() (available []*utxo, unavailable uint64) {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
-
-	for o, u := range sr.cached {
-		// If the UTXO is already reserved, skip it.
+	for _, u := range sr.cached {
 		if _, ok := sr.reserved[u.OutputID]; ok {
-			unavailable += u.Amount
+			unavailable = addSaturating(unavailable, u.Amount)
 			continue
 		}
-		// Cached utxos aren't guaranteed to still be valid; they may
-		// have been spent. Verify that that the outputs are still in
-		// the state tree.
-		if !sr.validFn(u) {
-			delete(sr.cached, o)
-			continue
+		available = append(available, u)
+	}
+	return available, unavailable
+}
+
+// selectValid selects UTXOs totaling at least amount from candidates
+// with sel. Cached utxos aren't guaranteed to still be valid; they may
+// have been spent. Only the selected ones are checked against the state
+// tree; any that were spent are removed from the cache and the selection
+// is made again without them.
+func (sr *sourceReserver) selectValid(candidates []*utxo, unavailable, amount uint64, sel Selection) ([]*utxo, uint64, error) {
+	for {
+		var available uint64
+		for _, u := range candidates {
+			available = addSaturating(available, u.Amount)
+		}
+		if addSaturating(available, unavailable) < amount {
+			// Even if everything was available, this account wouldn't have
+			// enough to satisfy the request.
+			return nil, 0, ErrInsufficient
+		}
+		if available < amount {
+			// The account has enough for the request, but some is tied up in
+			// other reservations.
+			return nil, 0, ErrReserved
 		}
 
-		reserved += u.Amount
-		reservedUTXOs = append(reservedUTXOs, u)
-		if reserved >= amount {
-			break
+		selected, total := selectUTXOs(sel, candidates, amount)
+		if selected == nil {
+			// The total overflowed, but no set of UTXOs adds up
+			// to amount without overflowing.
+			return nil, 0, ErrInsufficient
 		}
+		spent := make(map[bc.Hash]bool)
+		for _, u := range selected {
+			if !sr.validFn(u) {
+				spent[u.OutputID] = true
+			}
+		}
+		if len(spent) == 0 {
+			return selected, total, nil
+		}
+
+		sr.mu.Lock()
+		for id := range spent {
+			delete(sr.cached, id)
+		}
+		sr.mu.Unlock()
+		valid := candidates[:0]
+		for _, u := range candidates {
+			if !spent[u.OutputID] {
+				valid = append(valid, u)
+			}
+		}
+		candidates = valid
 	}
-	if reserved+unavailable < amount {
-		// Even if everything was available, this account wouldn't have
-		// enough to satisfy the request.
-		return nil, 0, ErrInsufficient
-	}
-	if reserved < amount {
-		// The account has enough for the request, but some is tied up in
-		// other reservations.
-		return nil, 0, ErrReserved
-	}
+}
 
-	// We've found enough to satisfy the request.
-	for _, u := range reservedUTXOs {
+// markReserved reserves utxos for rid, unless another
+// reservation has taken any of them since they were selected.
+func (sr *sourceReserver) markReserved(rid uint64, utxos []*utxo) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for _, u := range utxos {
+		if _, ok := sr.reserved[u.OutputID]; ok {
+			return false
+		}
+	}
+	for _, u := range utxos {
 		sr.reserved[u.OutputID] = rid
 	}
-
-	return reservedUTXOs, reserved, nil
+	return true
 }
 
 func (sr *sourceReserver) reserveUTXO(rid uint64, utxo *utxo) error {
@@ -355,7 +868,19 @@ func (sr *sourceReserver) cancel(res *reservation) {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
 	for _, utxo := range res.UTXOs {
-		delete(sr.reserved, utxo.OutputID)
+		if sr.reserved[utxo.OutputID] == res.ID {
+			delete(sr.reserved, utxo.OutputID)
+		}
+	}
+}
+
+// restore marks the UTXOs of res, loaded from the database, as
+// reserved.
+func (sr *sourceReserver) restore(res *reservation) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for _, utxo := range res.UTXOs {
+		sr.reserved[utxo.OutputID] = res.ID
 	}
 }
 
@@ -389,13 +914,13 @@ func (sr *sourceReserver) refillCache(ctx context.Context) error {
 func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64) ([]*utxo, error) {
 	const q = `
 		SELECT output_id, amount, control_program_index, control_program,
-			source_id, source_pos, ref_data_hash
+			source_id, source_pos, ref_data_hash, confirmed_in, key_version
 		FROM account_utxos
 		WHERE account_id = $1 AND asset_id = $2 AND confirmed_in > $3
 	`
 	var utxos []*utxo
 	err := pg.ForQueryRows(ctx, db, q, src.AccountID, src.AssetID, height,
-		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, sourceID bc.Hash, sourcePos uint64, refData bc.Hash) {
+		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, sourceID bc.Hash, sourcePos uint64, refData bc.Hash, confirmedIn uint64, keyVersion int) {
 			utxos = append(utxos, &utxo{
 				OutputID:            oid,
 				SourceID:            sourceID,
@@ -406,6 +931,8 @@ func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64)
 				RefDataHash:         refData,
 				AccountID:           src.AccountID,
 				ControlProgramIndex: cpIndex,
+				ConfirmedIn:         confirmedIn,
+				KeyVersion:          keyVersion,
 			})
 		})
 	if err != nil {
@@ -417,7 +944,7 @@ func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64)
 func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error) {
 	const q = `
 		SELECT account_id, asset_id, amount, control_program_index, control_program,
-			source_id, source_pos, ref_data_hash
+			source_id, source_pos, ref_data_hash, confirmed_in, key_version
 		FROM account_utxos
 		WHERE output_id = $1
 	`
@@ -432,6 +959,8 @@ func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error)
 		&u.SourceID,
 		&u.SourcePos,
 		&u.RefDataHash,
+		&u.ConfirmedIn,
+		&u.KeyVersion,
 	)
 	if err == sql.ErrNoRows {
 		return nil, pg.ErrUserInputNotFound
@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"chain/database/pg"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/prottest"
 )
@@ -62,3 +64,220 @@ func TestCancelReservation(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRestoreReservations(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	_, err := db.ExecContext(ctx, sampleAccountUTXOs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outid bc.Hash
+	err = outid.UnmarshalText([]byte("9886ae2dc24b6d868c68768038c43801e905a62f1a9b826ca0dc357f00c30117"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prottest.NewChain(t, prottest.WithOutputIDs(outid))
+
+	clientToken := "a-client-token"
+	res, err := newReserver(db, c, nil).ReserveUTXO(ctx, outid, &clientToken, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A reserver that restores from the database, like a new leader
+	// does, sees the existing reservation.
+	restored := newReserver(db, c, nil)
+	err = restored.Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = restored.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != ErrReserved {
+		t.Fatalf("got=%s want=%s", err, ErrReserved)
+	}
+	got, err := restored.ReserveUTXO(ctx, outid, &clientToken, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != res.ID {
+		t.Errorf("got reservation %d, want %d", got.ID, res.ID)
+	}
+
+	// A reserver that hasn't restored is still prevented from
+	// reserving the utxo by the database.
+	_, err = newReserver(db, c, nil).ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != ErrReserved {
+		t.Fatalf("got=%s want=%s", err, ErrReserved)
+	}
+
+	// Canceling the reservation frees the utxo for everyone.
+	err = restored.Cancel(ctx, res.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newReserver(db, c, nil).ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpireReservations(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	_, err := db.ExecContext(ctx, sampleAccountUTXOs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outid bc.Hash
+	err = outid.UnmarshalText([]byte("9886ae2dc24b6d868c68768038c43801e905a62f1a9b826ca0dc357f00c30117"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prottest.NewChain(t, prottest.WithOutputIDs(outid))
+
+	_, err = newReserver(db, c, nil).ReserveUTXO(ctx, outid, nil, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Any process may expire the reservation.
+	err = newReserver(db, c, nil).ExpireReservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = db.QueryRowContext(ctx, `SELECT count(*) FROM reserved_utxos`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("got %d reserved utxos, want 0", n)
+	}
+
+	restored := newReserver(db, c, nil)
+	err = restored.Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := restored.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once another process revokes the reservation,
+	// expiring forgets it too.
+	err = newReserver(db, c, nil).Revoke(ctx, res.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = restored.ExpireReservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restored.reservations[res.ID]; ok {
+		t.Errorf("reservation %d is still in memory", res.ID)
+	}
+	_, err = restored.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokeReservation(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	_, err := db.ExecContext(ctx, sampleAccountUTXOs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outid bc.Hash
+	err = outid.UnmarshalText([]byte("9886ae2dc24b6d868c68768038c43801e905a62f1a9b826ca0dc357f00c30117"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prottest.NewChain(t, prottest.WithOutputIDs(outid))
+
+	utxoDB := newReserver(db, c, nil)
+	res, err := utxoDB.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := utxoDB.List(ctx, ReservationFilter{AccountID: "accEXAMPLE"}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != res.ID {
+		t.Fatalf("got %d reservations, want reservation %d", len(list), res.ID)
+	}
+	list, err = utxoDB.List(ctx, ReservationFilter{}, res.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("got %d reservations after %d, want 0", len(list), res.ID)
+	}
+
+	// Other processes can list the reservation without
+	// taking it into their own state.
+	other := newReserver(db, c, nil)
+	list, err = other.List(ctx, ReservationFilter{}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != res.ID {
+		t.Fatalf("got %d reservations from another process, want reservation %d", len(list), res.ID)
+	}
+	if len(other.reservations) != 0 {
+		t.Errorf("listing loaded %d reservations into memory, want 0", len(other.reservations))
+	}
+
+	list, err = utxoDB.List(ctx, ReservationFilter{ExpiresBefore: time.Now()}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("got %d reservations expiring before now, want 0", len(list))
+	}
+
+	err = utxoDB.CheckCanceled(ctx, []bc.Hash{outid})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoke the reservation from a different process.
+	err = newReserver(db, c, nil).Revoke(ctx, res.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = utxoDB.CheckCanceled(ctx, []bc.Hash{outid})
+	if errors.Root(err) != ErrCanceled {
+		t.Fatalf("got=%v want=%s", err, ErrCanceled)
+	}
+	list, err = utxoDB.List(ctx, ReservationFilter{}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("got %d reservations after cancel, want 0", len(list))
+	}
+
+	// Reserving the utxo again clears the cancellation.
+	_, err = utxoDB.ReserveUTXO(ctx, outid, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = utxoDB.CheckCanceled(ctx, []bc.Hash{outid})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = utxoDB.Revoke(ctx, 12345)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("got=%v want=%s", err, pg.ErrUserInputNotFound)
+	}
+}
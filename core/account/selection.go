@@ -0,0 +1,173 @@
+package account
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"chain/errors"
+	"chain/math/checked"
+)
+
+// ErrBadSelection indicates an unknown coin selection strategy.
+var ErrBadSelection = errors.New("invalid coin selection strategy")
+
+// Selection is a coin selection strategy. It determines which of an
+// account's available UTXOs are reserved to spend a given amount.
+type Selection string
+
+const (
+	// SmallestSufficient selects the smallest UTXO that covers the
+	// amount on its own. If there is none, it falls back to
+	// LargestFirst. It is used when neither the spend nor the account
+	// specifies a strategy.
+	SmallestSufficient Selection = "smallest_sufficient"
+
+	// LargestFirst selects UTXOs in decreasing order of amount,
+	// minimizing the number of inputs.
+	LargestFirst Selection = "largest_first"
+
+	// OldestFirst selects UTXOs in the order they were confirmed.
+	OldestFirst Selection = "oldest_first"
+
+	// MinimizeChange selects the set of UTXOs whose total exceeds the
+	// amount by as little as possible, preferring fewer inputs among
+	// equally good sets.
+	MinimizeChange Selection = "minimize_change"
+)
+
+// maxSelectionTries bounds the search done by MinimizeChange.
+const maxSelectionTries = 100000
+
+// Validate returns ErrBadSelection if s is not a known strategy.
+// The empty Selection is valid and means the default.
+func (s Selection) Validate() error {
+	switch s {
+	case "", SmallestSufficient, LargestFirst, OldestFirst, MinimizeChange:
+		return nil
+	}
+	return errors.WithDetailf(ErrBadSelection, "unknown coin selection strategy %q", s)
+}
+
+// selectUTXOs chooses from utxos a set whose total is at least amount
+// according to strategy s. It returns nil if the total of all utxos is
+// less than amount.
+func selectUTXOs(s Selection, utxos []*utxo, amount uint64) (selected []*utxo, total uint64) {
+	// Sort first by output ID so that the selection doesn't depend on
+	// the order of utxos.
+	sorted := make([]*utxo, len(utxos))
+	copy(sorted, utxos)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].OutputID.Bytes(), sorted[j].OutputID.Bytes()) < 0
+	})
+
+	switch s {
+	case LargestFirst:
+		return largestFirst(sorted, amount)
+	case OldestFirst:
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ConfirmedIn < sorted[j].ConfirmedIn })
+		return accumulate(sorted, amount)
+	case MinimizeChange:
+		return minimizeChange(sorted, amount)
+	default:
+		return smallestSufficient(sorted, amount)
+	}
+}
+
+// accumulate selects utxos in order until their total reaches amount.
+func accumulate(utxos []*utxo, amount uint64) ([]*utxo, uint64) {
+	var (
+		selected []*utxo
+		total    uint64
+	)
+	for _, u := range utxos {
+		if total >= amount {
+			break
+		}
+		sum, ok := checked.AddUint64(total, u.Amount)
+		if !ok {
+			continue
+		}
+		selected = append(selected, u)
+		total = sum
+	}
+	if total < amount {
+		return nil, 0
+	}
+	return selected, total
+}
+
+func largestFirst(utxos []*utxo, amount uint64) ([]*utxo, uint64) {
+	sort.SliceStable(utxos, func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount })
+	return accumulate(utxos, amount)
+}
+
+func smallestSufficient(utxos []*utxo, amount uint64) ([]*utxo, uint64) {
+	var best *utxo
+	for _, u := range utxos {
+		if u.Amount >= amount && (best == nil || u.Amount < best.Amount) {
+			best = u
+		}
+	}
+	if best != nil {
+		return []*utxo{best}, best.Amount
+	}
+	return largestFirst(utxos, amount)
+}
+
+// minimizeChange does a bounded depth-first search over subsets of
+// utxos, largest first, for the one with the least change. It starts
+// from the smallest-sufficient selection, so it never does worse.
+func minimizeChange(utxos []*utxo, amount uint64) ([]*utxo, uint64) {
+	best, bestTotal := smallestSufficient(utxos, amount)
+	if best == nil || bestTotal == amount {
+		return best, bestTotal
+	}
+
+	sort.SliceStable(utxos, func(i, j int) bool { return utxos[i].Amount > utxos[j].Amount })
+	// remaining[i] is the total of utxos[i:].
+	remaining := make([]uint64, len(utxos)+1)
+	for i := len(utxos) - 1; i >= 0; i-- {
+		remaining[i] = addSaturating(remaining[i+1], utxos[i].Amount)
+	}
+
+	var (
+		chosen []*utxo
+		tries  int
+		search func(i int, total uint64)
+	)
+	search = func(i int, total uint64) {
+		if tries >= maxSelectionTries || bestTotal == amount {
+			return
+		}
+		tries++
+		if total >= amount {
+			if total < bestTotal || (total == bestTotal && len(chosen) < len(best)) {
+				best = append([]*utxo(nil), chosen...)
+				bestTotal = total
+			}
+			return
+		}
+		if i == len(utxos) || addSaturating(total, remaining[i]) < amount {
+			return
+		}
+		if sum, ok := checked.AddUint64(total, utxos[i].Amount); ok && sum <= bestTotal {
+			chosen = append(chosen, utxos[i])
+			search(i+1, sum)
+			chosen = chosen[:len(chosen)-1]
+		}
+		search(i+1, total)
+	}
+	search(0, 0)
+	return best, bestTotal
+}
+
+// addSaturating returns a + b, or the largest uint64
+// if the sum would overflow.
+func addSaturating(a, b uint64) uint64 {
+	sum, ok := checked.AddUint64(a, b)
+	if !ok {
+		return math.MaxUint64
+	}
+	return sum
+}
@@ -0,0 +1,177 @@
+package account
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"chain/protocol/bc"
+)
+
+func TestSelectUTXOs(t *testing.T) {
+	// Each utxo has a distinct amount, and they were confirmed in a
+	// different order.
+	utxos := []*utxo{
+		{OutputID: bc.NewHash([32]byte{1}), Amount: 10, ConfirmedIn: 5},
+		{OutputID: bc.NewHash([32]byte{2}), Amount: 3, ConfirmedIn: 1},
+		{OutputID: bc.NewHash([32]byte{3}), Amount: 7, ConfirmedIn: 3},
+		{OutputID: bc.NewHash([32]byte{4}), Amount: 2, ConfirmedIn: 4},
+		{OutputID: bc.NewHash([32]byte{5}), Amount: 5, ConfirmedIn: 2},
+	}
+
+	cases := []struct {
+		sel       Selection
+		amount    uint64
+		want      []uint64
+		wantTotal uint64
+	}{
+		{SmallestSufficient, 4, []uint64{5}, 5},
+		{SmallestSufficient, 7, []uint64{7}, 7},
+		{SmallestSufficient, 15, []uint64{10, 7}, 17},
+		{"", 6, []uint64{7}, 7},
+		{LargestFirst, 4, []uint64{10}, 10},
+		{LargestFirst, 18, []uint64{10, 7, 5}, 22},
+		{OldestFirst, 4, []uint64{3, 5}, 8},
+		{OldestFirst, 16, []uint64{3, 5, 7, 2}, 17},
+		{MinimizeChange, 4, []uint64{5}, 5},
+		{MinimizeChange, 9, []uint64{7, 2}, 9},
+		{MinimizeChange, 15, []uint64{10, 5}, 15},
+		{MinimizeChange, 8, []uint64{5, 3}, 8},
+		{MinimizeChange, 12, []uint64{10, 2}, 12},
+		{MinimizeChange, 16, []uint64{10, 7}, 17},
+		{MinimizeChange, 27, []uint64{10, 7, 5, 3, 2}, 27},
+		{LargestFirst, 28, nil, 0},
+		{MinimizeChange, 28, nil, 0},
+	}
+	for _, c := range cases {
+		got, total := selectUTXOs(c.sel, utxos, c.amount)
+		var gotAmounts []uint64
+		for _, u := range got {
+			gotAmounts = append(gotAmounts, u.Amount)
+		}
+		if !reflect.DeepEqual(gotAmounts, c.want) || total != c.wantTotal {
+			t.Errorf("selectUTXOs(%q, %d) = %v (total %d), want %v (total %d)", c.sel, c.amount, gotAmounts, total, c.want, c.wantTotal)
+		}
+	}
+}
+
+func TestSelectUTXOsOverflow(t *testing.T) {
+	utxos := []*utxo{
+		{OutputID: bc.NewHash([32]byte{1}), Amount: math.MaxUint64 - 1},
+		{OutputID: bc.NewHash([32]byte{2}), Amount: math.MaxUint64 - 2},
+		{OutputID: bc.NewHash([32]byte{3}), Amount: 5},
+		{OutputID: bc.NewHash([32]byte{4}), Amount: 6},
+	}
+	cases := []struct {
+		sel       Selection
+		amount    uint64
+		want      []uint64
+		wantTotal uint64
+	}{
+		{LargestFirst, math.MaxUint64, nil, 0},
+		{LargestFirst, math.MaxUint64 - 1, []uint64{math.MaxUint64 - 1}, math.MaxUint64 - 1},
+		{MinimizeChange, 10, []uint64{6, 5}, 11},
+	}
+	for _, c := range cases {
+		got, total := selectUTXOs(c.sel, utxos, c.amount)
+		var gotAmounts []uint64
+		for _, u := range got {
+			gotAmounts = append(gotAmounts, u.Amount)
+		}
+		if !reflect.DeepEqual(gotAmounts, c.want) || total != c.wantTotal {
+			t.Errorf("selectUTXOs(%q, %d) = %v (total %d), want %v (total %d)", c.sel, c.amount, gotAmounts, total, c.want, c.wantTotal)
+		}
+	}
+
+	// The account's total overflows, but no selection can reach amount.
+	sr := &sourceReserver{
+		validFn:  func(*utxo) bool { return true },
+		cached:   make(map[bc.Hash]*utxo),
+		reserved: make(map[bc.Hash]uint64),
+	}
+	for _, u := range utxos {
+		sr.cached[u.OutputID] = u
+	}
+	_, _, err := sr.reserveFromCache(1, math.MaxUint64, LargestFirst)
+	if err != ErrInsufficient {
+		t.Errorf("reserveFromCache(MaxUint64) error = %v, want %v", err, ErrInsufficient)
+	}
+}
+
+func TestSelectionValidate(t *testing.T) {
+	for _, sel := range []Selection{"", SmallestSufficient, LargestFirst, OldestFirst, MinimizeChange} {
+		if err := sel.Validate(); err != nil {
+			t.Errorf("validate(%q) = %v, want nil", sel, err)
+		}
+	}
+	if err := Selection("random").Validate(); err == nil {
+		t.Error("validate(\"random\") = nil, want error")
+	}
+}
+
+func TestReserveFromCacheSelection(t *testing.T) {
+	sr := &sourceReserver{
+		validFn:  func(*utxo) bool { return true },
+		cached:   make(map[bc.Hash]*utxo),
+		reserved: make(map[bc.Hash]uint64),
+	}
+	for i, amount := range []uint64{10, 3, 7, 2, 5} {
+		u := &utxo{OutputID: bc.NewHash([32]byte{byte(i + 1)}), Amount: amount}
+		sr.cached[u.OutputID] = u
+	}
+
+	got, total, err := sr.reserveFromCache(1, 9, MinimizeChange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || total != 9 {
+		t.Fatalf("got %d utxos totaling %d, want 2 totaling 9", len(got), total)
+	}
+
+	// The 7 and 2 are now reserved, so the best remaining choice
+	// for 9 is 10.
+	got, total, err = sr.reserveFromCache(2, 9, MinimizeChange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || total != 10 {
+		t.Fatalf("got %d utxos totaling %d, want 1 totaling 10", len(got), total)
+	}
+
+	_, _, err = sr.reserveFromCache(3, 9, LargestFirst)
+	if err != ErrReserved {
+		t.Fatalf("got error %v, want %v", err, ErrReserved)
+	}
+}
+
+func TestReserveFromCacheSpent(t *testing.T) {
+	spent := bc.NewHash([32]byte{1})
+	checked := make(map[uint64]bool)
+	sr := &sourceReserver{
+		validFn: func(u *utxo) bool {
+			checked[u.Amount] = true
+			return u.OutputID != spent
+		},
+		cached:   make(map[bc.Hash]*utxo),
+		reserved: make(map[bc.Hash]uint64),
+	}
+	for i, amount := range []uint64{10, 3, 7, 2} {
+		u := &utxo{OutputID: bc.NewHash([32]byte{byte(i + 1)}), Amount: amount}
+		sr.cached[u.OutputID] = u
+	}
+
+	// The 10 was spent, so it's dropped and the selection is made again.
+	got, total, err := sr.reserveFromCache(1, 9, LargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || total != 10 {
+		t.Fatalf("got %d utxos totaling %d, want 2 totaling 10", len(got), total)
+	}
+	if _, ok := sr.cached[spent]; ok {
+		t.Error("spent utxo is still cached")
+	}
+	if checked[2] {
+		t.Error("checked an unselected utxo against the state tree")
+	}
+}
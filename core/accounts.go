@@ -5,9 +5,14 @@ import (
 	"sync"
 
 	"chain/core/account"
+	"chain/core/leader"
+	"chain/core/query"
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
+	"chain/protocol/bc"
 )
 
 // POST /create-account
@@ -22,6 +27,16 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 	// idempotency of create account requests. Duplicate create account requests
 	// with the same client_token will only create one account.
 	ClientToken string `json:"client_token"`
+
+	// CoinSelection is the default coin selection strategy for spends
+	// from the account.
+	CoinSelection account.Selection `json:"coin_selection"`
+
+	// WatchOnly creates an account whose UTXOs are tracked but never
+	// spent by this Core. A watch-only account may be defined by
+	// ControlPrograms instead of RootXPubs and Quorum.
+	WatchOnly       bool                 `json:"watch_only"`
+	ControlPrograms []chainjson.HexBytes `json:"control_programs"`
 }) interface{} {
 	responses := make([]interface{}, len(ins))
 	var wg sync.WaitGroup
@@ -33,7 +48,19 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 			defer wg.Done()
 			defer batchRecover(subctx, &responses[i])
 
-			acc, err := a.accounts.Create(subctx, ins[i].RootXPubs, ins[i].Quorum, ins[i].Alias, ins[i].Tags, ins[i].ClientToken)
+			var (
+				acc *account.Account
+				err error
+			)
+			if ins[i].WatchOnly || len(ins[i].ControlPrograms) > 0 {
+				var progs [][]byte
+				for _, p := range ins[i].ControlPrograms {
+					progs = append(progs, p)
+				}
+				acc, err = a.accounts.CreateWatchOnly(subctx, ins[i].RootXPubs, ins[i].Quorum, progs, ins[i].Alias, ins[i].Tags, ins[i].CoinSelection, ins[i].ClientToken)
+			} else {
+				acc, err = a.accounts.Create(subctx, ins[i].RootXPubs, ins[i].Quorum, ins[i].Alias, ins[i].Tags, ins[i].CoinSelection, ins[i].ClientToken)
+			}
 			if err != nil {
 				responses[i] = err
 				return
@@ -51,6 +78,105 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 	return responses
 }
 
+// POST /update-account-coin-selection
+func (a *API) updateAccountCoinSelection(ctx context.Context, ins []struct {
+	ID            *string
+	Alias         *string
+	CoinSelection account.Selection `json:"coin_selection"`
+}) interface{} {
+	responses := make([]interface{}, len(ins))
+	var wg sync.WaitGroup
+	wg.Add(len(responses))
+
+	for i := range responses {
+		go func(i int) {
+			subctx := reqid.NewSubContext(ctx, reqid.New())
+			defer wg.Done()
+			defer batchRecover(subctx, &responses[i])
+
+			err := a.accounts.UpdateCoinSelection(subctx, ins[i].ID, ins[i].Alias, ins[i].CoinSelection)
+			if err != nil {
+				responses[i] = err
+			} else {
+				responses[i] = httpjson.DefaultResponse
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return responses
+}
+
+// POST /update-account-keys
+func (a *API) updateAccountKeys(ctx context.Context, ins []struct {
+	ID        *string
+	Alias     *string
+	RootXPubs []chainkd.XPub `json:"root_xpubs"`
+	Quorum    int
+
+	// Sweep requests that the account's UTXOs locked with
+	// its previous keys be moved to its new keys.
+	Sweep bool
+}) (interface{}, error) {
+	// Only the leader's caches and consolidator are sure to see
+	// the new keys, so make the change there.
+	if a.leader.State() != leader.Leading {
+		var resp interface{}
+		err := a.forwardToLeader(ctx, "/update-account-keys", ins, &resp)
+		return resp, err
+	}
+
+	type keysUpdated struct {
+		Account *query.AnnotatedAccount `json:"account"`
+
+		// SweepTransactionIDs lists the transactions submitted
+		// to move UTXOs from the account's previous keys.
+		SweepTransactionIDs []bc.Hash `json:"sweep_transaction_ids,omitempty"`
+	}
+
+	responses := make([]interface{}, len(ins))
+	var wg sync.WaitGroup
+	wg.Add(len(responses))
+
+	for i := range responses {
+		go func(i int) {
+			subctx := reqid.NewSubContext(ctx, reqid.New())
+			defer wg.Done()
+			defer batchRecover(subctx, &responses[i])
+
+			acc, err := a.accounts.UpdateKeys(subctx, ins[i].ID, ins[i].Alias, ins[i].RootXPubs, ins[i].Quorum)
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			aa, err := account.Annotated(acc)
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			resp := keysUpdated{Account: aa}
+			if ins[i].Sweep {
+				resp.SweepTransactionIDs, err = a.consolidator.SweepOldKeys(subctx, acc.ID)
+				if err != nil {
+					responses[i] = errors.Wrap(err, "sweeping previous keys")
+					return
+				}
+			}
+			responses[i] = resp
+		}(i)
+	}
+
+	wg.Wait()
+	return responses, nil
+}
+
+// POST /cancel-reservation
+func (a *API) cancelReservation(ctx context.Context, in struct {
+	ID uint64 `json:"id"`
+}) error {
+	return a.accounts.CancelReservation(ctx, in.ID)
+}
+
 // POST /update-account-tags
 func (a *API) updateAccountTags(ctx context.Context, ins []struct {
 	ID    *string
@@ -17,6 +17,7 @@ import (
 	"chain/core/account"
 	"chain/core/asset"
 	"chain/core/config"
+	"chain/core/contract"
 	"chain/core/fetch"
 	"chain/core/generator"
 	"chain/core/leader"
@@ -39,6 +40,7 @@ import (
 	"chain/net/http/limit"
 	"chain/net/http/static"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 )
 
@@ -64,6 +66,7 @@ type API struct {
 	accounts        *account.Manager
 	indexer         *query.Indexer
 	txFeeds         *txfeed.Tracker
+	contracts       *contract.Registry
 	accessTokens    *accesstoken.CredentialStore
 	grants          *authz.Store
 	config          *config.Config
@@ -76,6 +79,8 @@ type API struct {
 	leader          leaderProcess
 	addr            string
 	signer          func(context.Context, *legacy.Block) ([]byte, error)
+	txSigner        txbuilder.TemplateSigner
+	consolidator    *account.Consolidator
 	requestLimits   []requestLimit
 	generator       *generator.Generator
 	replicator      *fetch.Replicator
@@ -143,21 +148,38 @@ func (a *API) buildHandler() {
 	m.Handle("/create-asset", needConfig(a.createAsset))
 	m.Handle("/update-account-tags", needConfig(a.updateAccountTags))
 	m.Handle("/update-asset-tags", needConfig(a.updateAssetTags))
+	m.Handle("/update-account-coin-selection", needConfig(a.updateAccountCoinSelection))
+	m.Handle("/update-account-keys", needConfig(a.updateAccountKeys))
 	m.Handle("/build-transaction", needConfig(a.build))
 	m.Handle("/submit-transaction", needConfig(a.submit))
+	m.Handle("/merge-transaction-templates", needConfig(a.mergeTemplates))
 	m.Handle("/create-control-program", needConfig(a.createControlProgram)) // DEPRECATED
 	m.Handle("/create-account-receiver", needConfig(a.createAccountReceiver))
 	m.Handle("/create-transaction-feed", needConfig(a.createTxFeed))
 	m.Handle("/get-transaction-feed", needConfig(a.getTxFeed))
 	m.Handle("/update-transaction-feed", needConfig(a.updateTxFeed))
 	m.Handle("/delete-transaction-feed", needConfig(a.deleteTxFeed))
+	m.Handle("/stream-transaction-feed", http.HandlerFunc(a.streamTxFeed))
+	m.Handle("/replay-transaction-feed", needConfig(a.replayTxFeed))
+	m.Handle("/create-contract-template", needConfig(a.createContractTemplate))
+	m.Handle("/get-contract-template", needConfig(a.getContractTemplate))
 	m.Handle("/mockhsm", alwaysError(errNoMockHSM))
 	m.Handle("/list-accounts", needConfig(a.listAccounts))
 	m.Handle("/list-assets", needConfig(a.listAssets))
 	m.Handle("/list-transaction-feeds", needConfig(a.listTxFeeds))
+	m.Handle("/list-transaction-feed-deliveries", needConfig(a.listTxFeedDeliveries))
+	m.Handle("/list-contract-templates", needConfig(a.listContractTemplates))
 	m.Handle("/list-transactions", needConfig(a.listTransactions))
 	m.Handle("/list-balances", needConfig(a.listBalances))
+	m.Handle("/list-aggregates", needConfig(a.listAggregates))
 	m.Handle("/list-unspent-outputs", needConfig(a.listUnspentOutputs))
+	m.Handle("/list-reservations", needConfig(a.listReservations))
+	m.Handle("/cancel-reservation", needConfig(a.cancelReservation))
+	m.Handle("/list-pending-transactions", needConfig(a.listPendingTxs))
+	m.Handle("/get-pending-transaction", needConfig(a.getPendingTx))
+	m.Handle("/get-transaction-status", needConfig(a.getTxStatus))
+	m.Handle("/get-transaction-proof", needConfig(a.getTxProof))
+	m.Handle("/get-output-proof", needConfig(a.getOutputProof))
 	m.Handle("/reset", resetAllowed(needConfig(a.reset)))
 
 	m.Handle(crosscoreRPCPrefix+"submit", needConfig(func(ctx context.Context, tx *legacy.Tx) error {
@@ -166,6 +188,7 @@ func (a *API) buildHandler() {
 	m.Handle(crosscoreRPCPrefix+"get-block", needConfig(a.getBlockRPC))
 	m.Handle(crosscoreRPCPrefix+"get-snapshot-info", needConfig(a.getSnapshotInfoRPC))
 	m.Handle(crosscoreRPCPrefix+"get-snapshot", http.HandlerFunc(a.getSnapshotRPC))
+	m.Handle(crosscoreRPCPrefix+"get-transaction-status", needConfig(a.getTxStatusRPC))
 	m.Handle(crosscoreRPCPrefix+"signer/sign-block", needConfig(a.leaderSignHandler(a.signer)))
 	m.Handle(crosscoreRPCPrefix+"block-height", needConfig(func(ctx context.Context) map[string]uint64 {
 		h := a.chain.Height()
@@ -224,6 +247,11 @@ type requestQuery struct {
 	SumBy        []string      `json:"sum_by,omitempty"`
 	PageSize     int           `json:"page_size"`
 
+	// These are used by /list-aggregates. Index must be
+	// "transactions" or "unspent_outputs".
+	Index      string   `json:"index,omitempty"`
+	Aggregates []string `json:"aggregates,omitempty"`
+
 	// AscLongPoll and Timeout are used by /list-transactions
 	// to facilitate notifications.
 	AscLongPoll bool          `json:"ascending_with_long_poll,omitempty"`
@@ -238,9 +266,11 @@ type requestQuery struct {
 	StartTimeMS uint64 `json:"start_time,omitempty"`
 	EndTimeMS   uint64 `json:"end_time,omitempty"`
 
-	// This is used for point-in-time queries like /list-balances
+	// These are used for point-in-time queries like /list-balances.
+	// At most one of them may be set.
 	// TODO(bobg): Different request structs for endpoints with different needs
 	TimestampMS uint64 `json:"timestamp,omitempty"`
+	BlockHeight uint64 `json:"block_height,omitempty"`
 
 	// This is used for filtering results from /list-access-tokens
 	// Value must be "client" or "network"
@@ -248,6 +278,19 @@ type requestQuery struct {
 
 	// Aliases is used to filter results from /mockshm/list-keys
 	Aliases []string `json:"aliases,omitempty"`
+
+	// These identify the feed and filter the results of
+	// /list-transaction-feed-deliveries. Status must be
+	// "delivered", "failed" or empty.
+	ID     string `json:"id,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// These filter the results of /list-reservations.
+	// ExpiresBeforeMS is in milliseconds since the Unix epoch.
+	AccountID       string      `json:"account_id,omitempty"`
+	AssetID         *bc.AssetID `json:"asset_id,omitempty"`
+	ExpiresBeforeMS uint64      `json:"expires_before,omitempty"`
 }
 
 // Used as a response object for api queries
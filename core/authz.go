@@ -13,39 +13,60 @@ var Policies = []string{
 }
 
 var policyByRoute = map[string][]string{
-	"/create-account":           {"client-readwrite"},
-	"/create-asset":             {"client-readwrite"},
-	"/update-account-tags":      {"client-readwrite"},
-	"/update-asset-tags":        {"client-readwrite"},
-	"/build-transaction":        {"client-readwrite", "internal"},
-	"/submit-transaction":       {"client-readwrite", "internal"},
-	"/create-control-program":   {"client-readwrite"},
-	"/create-account-receiver":  {"client-readwrite"},
-	"/create-transaction-feed":  {"client-readwrite"},
-	"/get-transaction-feed":     {"client-readwrite", "client-readonly"},
-	"/update-transaction-feed":  {"client-readwrite"},
-	"/delete-transaction-feed":  {"client-readwrite"},
-	"/mockhsm":                  {"client-readwrite"},
-	"/mockhsm/create-block-key": {"internal"},
-	"/mockhsm/create-key":       {"client-readwrite"},
-	"/mockhsm/list-keys":        {"client-readwrite", "client-readonly"},
-	"/mockhsm/delkey":           {"client-readwrite"},
-	"/mockhsm/sign-transaction": {"client-readwrite"},
+	"/create-account":              {"client-readwrite"},
+	"/create-asset":                {"client-readwrite"},
+	"/update-account-tags":         {"client-readwrite"},
+	"/update-asset-tags":           {"client-readwrite"},
+	"/build-transaction":           {"client-readwrite", "internal"},
+	"/submit-transaction":          {"client-readwrite", "internal"},
+	"/merge-transaction-templates": {"client-readwrite"},
+	"/create-control-program":      {"client-readwrite"},
+	"/create-account-receiver":     {"client-readwrite"},
+	"/create-transaction-feed":     {"client-readwrite"},
+	"/get-transaction-feed":        {"client-readwrite", "client-readonly"},
+	"/update-transaction-feed":     {"client-readwrite"},
+	"/delete-transaction-feed":     {"client-readwrite"},
+	"/stream-transaction-feed":     {"client-readwrite", "client-readonly"},
+	"/replay-transaction-feed":     {"client-readwrite"},
+	"/create-contract-template":    {"client-readwrite"},
+	"/get-contract-template":       {"client-readwrite", "client-readonly"},
+	"/mockhsm":                     {"client-readwrite"},
+	"/mockhsm/unlock":              {"client-readwrite"},
+	"/mockhsm/create-block-key":    {"internal"},
+	"/mockhsm/create-key":          {"client-readwrite"},
+	"/mockhsm/list-keys":           {"client-readwrite", "client-readonly"},
+	"/mockhsm/delkey":              {"client-readwrite"},
+	"/mockhsm/sign-transaction":    {"client-readwrite"},
+	"/mockhsm/export-keys":         {"client-readwrite"},
+	"/mockhsm/import-keys":         {"client-readwrite"},
 
-	"/list-accounts":          {"client-readwrite", "client-readonly"},
-	"/list-assets":            {"client-readwrite", "client-readonly"},
-	"/list-transaction-feeds": {"client-readwrite", "client-readonly"},
-	"/list-transactions":      {"client-readwrite", "client-readonly"},
-	"/list-balances":          {"client-readwrite", "client-readonly"},
-	"/list-unspent-outputs":   {"client-readwrite", "client-readonly"},
-	"/reset":                  {"client-readwrite", "internal"},
+	"/list-accounts":                    {"client-readwrite", "client-readonly"},
+	"/list-assets":                      {"client-readwrite", "client-readonly"},
+	"/list-transaction-feeds":           {"client-readwrite", "client-readonly"},
+	"/list-transaction-feed-deliveries": {"client-readwrite", "client-readonly"},
+	"/list-contract-templates":          {"client-readwrite", "client-readonly"},
+	"/list-transactions":                {"client-readwrite", "client-readonly"},
+	"/list-balances":                    {"client-readwrite", "client-readonly"},
+	"/list-aggregates":                  {"client-readwrite", "client-readonly"},
+	"/list-unspent-outputs":             {"client-readwrite", "client-readonly"},
+	"/list-reservations":                {"client-readwrite", "client-readonly"},
+	"/cancel-reservation":               {"client-readwrite"},
+	"/update-account-coin-selection":    {"client-readwrite"},
+	"/update-account-keys":              {"client-readwrite", "internal"},
+	"/list-pending-transactions":        {"client-readwrite", "client-readonly"},
+	"/get-pending-transaction":          {"client-readwrite", "client-readonly"},
+	"/get-transaction-status":           {"client-readwrite", "client-readonly"},
+	"/get-transaction-proof":            {"client-readwrite", "client-readonly"},
+	"/get-output-proof":                 {"client-readwrite", "client-readonly"},
+	"/reset":                            {"client-readwrite", "internal"},
 
-	crosscoreRPCPrefix + "submit":            {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-block":         {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot-info": {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot":      {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "signer/sign-block": {"internal", "crosscore-signblock"},
-	crosscoreRPCPrefix + "block-height":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "submit":                 {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-block":              {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot-info":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot":           {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "signer/sign-block":      {"internal", "crosscore-signblock"},
+	crosscoreRPCPrefix + "block-height":           {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-transaction-status": {"crosscore", "crosscore-signblock"},
 
 	"/list-authorization-grants":  {"client-readwrite", "client-readonly", "internal"},
 	"/create-authorization-grant": {"client-readwrite", "internal"},
@@ -2,10 +2,13 @@ package core
 
 import (
 	"context"
+	"encoding/hex"
 	"net"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"chain/core/config"
 	"chain/database/pg"
@@ -43,6 +46,64 @@ func Config(ctx context.Context, db pg.DB, sdb *sinkdb.DB) (*config.Options, err
 	// the URL, not the access token.
 	opts.DefineSet("enclave", 2, cleanEnclaveTuple, equalFirst)
 
+	// utxo_consolidation_threshold is the number of UTXOs of an asset
+	// an account may hold before the leader consolidates them.
+	opts.DefineSingle("utxo_consolidation_threshold", 1, func(tup []string) error {
+		n, err := strconv.Atoi(tup[0])
+		if err != nil || n < 2 {
+			return errors.WithDetailf(config.ErrConfigOp, "UTXO consolidation threshold must be an integer of at least 2.")
+		}
+		tup[0] = strconv.Itoa(n)
+		return nil
+	})
+
+	// The signing_* options make up the policy the MockHSM checks
+	// transaction templates against before signing them.
+	equalAll := func(a, b []string) bool { return strings.Join(a, " ") == strings.Join(b, " ") }
+
+	// signing_max_amount defines a set of (asset ID, amount) tuples
+	// limiting the net amount of an asset a transaction may send.
+	opts.DefineSet("signing_max_amount", 2, func(tup []string) error {
+		assetID, amount, err := parseMaxAmount(tup)
+		if err != nil {
+			return err
+		}
+		b, _ := assetID.MarshalText()
+		tup[0], tup[1] = string(b), strconv.FormatUint(amount, 10)
+		return nil
+	}, equalFirst)
+
+	// signing_allowed_account and signing_allowed_program define the
+	// accounts and control programs transactions may send to. If
+	// neither is set, any destination is allowed.
+	opts.DefineSet("signing_allowed_account", 1, func(tup []string) error {
+		if tup[0] == "" {
+			return errors.WithDetailf(config.ErrConfigOp, "Account ID must not be empty.")
+		}
+		return nil
+	}, equalFirst)
+	opts.DefineSet("signing_allowed_program", 1, func(tup []string) error {
+		prog, err := hex.DecodeString(tup[0])
+		if err != nil || len(prog) == 0 {
+			return errors.WithDetailf(config.ErrConfigOp, "Control program must be non-empty hex.")
+		}
+		tup[0] = hex.EncodeToString(prog)
+		return nil
+	}, equalFirst)
+
+	// signing_window defines a set of (start, end) times of day, in
+	// UTC, during which signing is allowed. If none are set, signing
+	// is allowed at any time.
+	opts.DefineSet("signing_window", 2, func(tup []string) error {
+		w, err := parseWindow(tup)
+		if err != nil {
+			return err
+		}
+		tup[0] = time.Time{}.Add(w.Start).Format("15:04")
+		tup[1] = time.Time{}.Add(w.End).Format("15:04")
+		return nil
+	}, equalAll)
+
 	// migrate any old-style existing configuration options
 	monolith, err := config.Load(ctx, db, sdb)
 	if errors.Root(err) == raft.ErrUninitialized {
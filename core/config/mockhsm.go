@@ -11,8 +11,16 @@ import (
 	"chain/log"
 )
 
+// MockHSM, if set, is the mock HSM used to generate a block-signing
+// key when a signer is configured without one. It must be unlocked.
+// If it is nil, a new, locked, mock HSM is opened on the database.
+var MockHSM *mockhsm.HSM
+
 func getOrCreateDevKey(ctx context.Context, db pg.DB, c *Config) (blockPub ed25519.PublicKey, err error) {
-	hsm := mockhsm.New(db)
+	hsm := MockHSM
+	if hsm == nil {
+		hsm = mockhsm.New(db)
+	}
 	corePub, created, err := hsm.GetOrCreate(ctx, autoBlockKeyAlias)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,244 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+)
+
+var (
+	ErrBadArguments    = errors.New("invalid contract arguments")
+	ErrProgramMismatch = errors.New("output is not locked by the contract")
+	ErrSpent           = errors.New("output already spent")
+)
+
+func (reg *Registry) DecodeLockAction(data []byte) (txbuilder.Action, error) {
+	a := &lockAction{reg: reg}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+type lockAction struct {
+	reg *Registry
+	bc.AssetAmount
+	TemplateID    string                 `json:"contract_template_id"`
+	TemplateAlias string                 `json:"contract_template_alias"`
+	Arguments     []compiler.ContractArg `json:"arguments"`
+	ReferenceData chainjson.Map          `json:"reference_data"`
+}
+
+func (a *lockAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.TemplateID == "" && a.TemplateAlias == "" {
+		missing = append(missing, "contract_template_id")
+	}
+	if a.AssetId.IsZero() {
+		missing = append(missing, "asset_id")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	tpl, err := a.reg.Find(ctx, a.TemplateID, a.TemplateAlias)
+	if err != nil {
+		return err
+	}
+	prog, err := instantiate(tpl.Contract, a.Arguments)
+	if err != nil {
+		return err
+	}
+	out := legacy.NewTxOutput(*a.AssetId, a.Amount, prog, a.ReferenceData)
+	return b.AddOutput(out)
+}
+
+func (reg *Registry) DecodeUnlockAction(data []byte) (txbuilder.Action, error) {
+	a := &unlockAction{reg: reg}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// unlockAction spends an output locked by an instance of a contract
+// template, satisfying the named clause. Contract outputs are not
+// reserved the way account outputs are, so two transactions built
+// at the same time may unlock the same output; only one of them will
+// be accepted by the blockchain.
+type unlockAction struct {
+	reg           *Registry
+	OutputID      *bc.Hash               `json:"output_id"`
+	TemplateID    string                 `json:"contract_template_id"`
+	TemplateAlias string                 `json:"contract_template_alias"`
+	Arguments     []compiler.ContractArg `json:"arguments"`
+	Clause        string                 `json:"clause"`
+
+	// ClauseArguments has one entry per clause parameter. A
+	// Signature parameter takes the key to sign with, as an object
+	// with "xpub" and "derivation_path"; every other parameter takes
+	// a contract argument.
+	ClauseArguments []json.RawMessage `json:"clause_arguments"`
+
+	ReferenceData chainjson.Map `json:"reference_data"`
+}
+
+func (a *unlockAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.OutputID == nil {
+		missing = append(missing, "output_id")
+	}
+	if a.TemplateID == "" && a.TemplateAlias == "" {
+		missing = append(missing, "contract_template_id")
+	}
+	if a.Clause == "" {
+		missing = append(missing, "clause")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	tpl, err := a.reg.Find(ctx, a.TemplateID, a.TemplateAlias)
+	if err != nil {
+		return err
+	}
+	out, err := a.reg.output(ctx, *a.OutputID)
+	if err != nil {
+		return err
+	}
+	prog, err := instantiate(tpl.Contract, a.Arguments)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(prog, out.ControlProgram.Code) {
+		return errors.WithDetailf(ErrProgramMismatch, "output %x is not locked by %s with the given arguments", a.OutputID.Bytes(), tpl.Contract.Name)
+	}
+	sigInst, err := clauseInstruction(tpl.Contract, a.Clause, a.ClauseArguments)
+	if err != nil {
+		return err
+	}
+
+	txInput := legacy.NewSpendInput(nil, *out.Source.Ref, *out.Source.Value.AssetId, out.Source.Value.Amount,
+		out.Source.Position, out.ControlProgram.Code, *out.Data, a.ReferenceData)
+	return b.AddInput(txInput, sigInst)
+}
+
+// output returns the unspent output with the given id, read from
+// the block that created it.
+func (reg *Registry) output(ctx context.Context, outputID bc.Hash) (*bc.Output, error) {
+	const q = `
+		SELECT block_height, tx_pos FROM annotated_outputs WHERE output_id=$1
+	`
+	var (
+		height uint64
+		txPos  int
+	)
+	err := reg.db.QueryRowContext(ctx, q, outputID).Scan(&height, &txPos)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "could not find output with id %x", outputID.Bytes())
+	} else if err != nil {
+		return nil, errors.Wrap(err, "looking up output")
+	}
+
+	_, snapshot := reg.chain.State()
+	if snapshot == nil || !snapshot.Tree.Contains(outputID.Bytes()) {
+		return nil, errors.WithDetailf(ErrSpent, "output %x is not in the current state", outputID.Bytes())
+	}
+
+	block, err := reg.chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", height)
+	}
+	if txPos >= len(block.Transactions) {
+		return nil, errors.Wrapf(pg.ErrUserInputNotFound, "block %d has no transaction at position %d", height, txPos)
+	}
+	out, err := block.Transactions[txPos].Output(outputID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading output %x", outputID.Bytes())
+	}
+	return out, nil
+}
+
+func instantiate(contract *compiler.Contract, args []compiler.ContractArg) ([]byte, error) {
+	prog, err := compiler.Instantiate(contract.Body, contract.Params, contract.Recursive, args)
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadArguments, err.Error())
+	}
+	return prog, nil
+}
+
+// clauseInstruction returns a signing instruction whose witness
+// satisfies the named clause of contract: one component for each
+// clause argument, in order, followed by the clause selector if the
+// contract has more than one clause.
+func clauseInstruction(contract *compiler.Contract, clauseName string, args []json.RawMessage) (*txbuilder.SigningInstruction, error) {
+	selector := -1
+	for i, c := range contract.Clauses {
+		if c.Name == clauseName {
+			selector = i
+		}
+	}
+	if selector < 0 {
+		return nil, errors.WithDetailf(ErrBadArguments, "contract %s has no clause %s", contract.Name, clauseName)
+	}
+	clause := contract.Clauses[selector]
+	if len(args) != len(clause.Params) {
+		return nil, errors.WithDetailf(ErrBadArguments, "clause %s takes %d argument(s), got %d", clauseName, len(clause.Params), len(args))
+	}
+
+	sigInst := new(txbuilder.SigningInstruction)
+	for i, param := range clause.Params {
+		if string(param.Type) == "Signature" {
+			var key struct {
+				XPub           chainkd.XPub         `json:"xpub"`
+				DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+			}
+			err := json.Unmarshal(args[i], &key)
+			if err != nil {
+				return nil, errors.WithDetailf(ErrBadArguments, "clause argument %d (%s): %s", i, param.Name, err)
+			}
+			path := make([][]byte, 0, len(key.DerivationPath))
+			for _, p := range key.DerivationPath {
+				path = append(path, p)
+			}
+			sigInst.WitnessComponents = append(sigInst.WitnessComponents, txbuilder.NewRawTxSigWitness([]chainkd.XPub{key.XPub}, path, 1))
+			continue
+		}
+
+		var arg compiler.ContractArg
+		err := json.Unmarshal(args[i], &arg)
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadArguments, "clause argument %d (%s): %s", i, param.Name, err)
+		}
+		var data []byte
+		switch string(param.Type) {
+		case "Amount", "Integer", "Time":
+			if arg.I == nil {
+				return nil, errors.WithDetailf(ErrBadArguments, "clause argument %d (%s) must be an integer", i, param.Name)
+			}
+			data = vm.Int64Bytes(*arg.I)
+		case "Boolean":
+			if arg.B == nil {
+				return nil, errors.WithDetailf(ErrBadArguments, "clause argument %d (%s) must be a boolean", i, param.Name)
+			}
+			data = vm.BoolBytes(*arg.B)
+		default:
+			if arg.S == nil {
+				return nil, errors.WithDetailf(ErrBadArguments, "clause argument %d (%s) must be a string", i, param.Name)
+			}
+			data = *arg.S
+		}
+		sigInst.WitnessComponents = append(sigInst.WitnessComponents, txbuilder.DataWitness(data))
+	}
+	if len(contract.Clauses) > 1 {
+		sigInst.WitnessComponents = append(sigInst.WitnessComponents, txbuilder.DataWitness(vm.Int64Bytes(int64(selector))))
+	}
+	return sigInst, nil
+}
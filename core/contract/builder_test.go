@@ -0,0 +1,110 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+func TestUnlockClause(t *testing.T) {
+	contracts, err := compiler.Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := contracts[0]
+
+	xprv, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := [][]byte{{1, 0, 0, 0}}
+	sellerKey := chainjson.HexBytes(xpub.Derive(path).PublicKey())
+	sellerProgram := chainjson.HexBytes{byte(vm.OP_TRUE)}
+	requestedAsset := chainjson.HexBytes(bytes.Repeat([]byte{2}, 32))
+	requestedAmount := int64(50)
+	prog, err := instantiate(contract, []compiler.ContractArg{
+		{S: &requestedAsset},
+		{I: &requestedAmount},
+		{S: &sellerProgram},
+		{S: &sellerKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offered := bc.AssetID{V0: 1}
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, bc.NewHash([32]byte{9}), offered, 10, 0, prog, bc.Hash{}, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(offered, 10, sellerProgram, nil),
+		},
+	})
+
+	keyArg := json.RawMessage(fmt.Sprintf(`{"xpub": "%s", "derivation_path": ["01000000"]}`, xpub))
+	sigInst, err := clauseInstruction(contract, "cancel", []json.RawMessage{keyArg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &txbuilder.Template{
+		Transaction:         tx,
+		SigningInstructions: []*txbuilder.SigningInstruction{sigInst},
+	}
+	signFn := func(_ context.Context, _ chainkd.XPub, path [][]byte, h [32]byte) ([]byte, error) {
+		return xprv.Derive(path).Sign(h[:]), nil
+	}
+	err = txbuilder.Sign(context.Background(), tpl, []chainkd.XPub{xpub}, signFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := tpl.Transaction.Inputs[0].Arguments()
+	if len(args) != 2 {
+		t.Fatalf("got %d witness arguments, want 2 (signature and clause selector)", len(args))
+	}
+	entry := tpl.Transaction.Entries[tpl.Transaction.InputIDs[0]]
+	err = vm.Verify(validation.NewTxVMContext(tpl.Transaction.Tx, entry, &bc.Program{VmVersion: 1, Code: prog}, args))
+	if err != nil {
+		t.Errorf("unlocking cancel clause: %v", err)
+	}
+}
+
+func TestClauseInstructionErrors(t *testing.T) {
+	contracts, err := compiler.Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := contracts[0]
+
+	cases := []struct {
+		clause string
+		args   []json.RawMessage
+	}{
+		{"steal", nil},
+		{"cancel", nil},
+		{"trade", []json.RawMessage{json.RawMessage(`{"integer": 1}`)}},
+		{"cancel", []json.RawMessage{json.RawMessage(`{"xpub": "zz"}`)}},
+	}
+	for _, c := range cases {
+		_, err := clauseInstruction(contract, c.clause, c.args)
+		if errors.Root(err) != ErrBadArguments {
+			t.Errorf("clauseInstruction(%s, %s) error = %v want %v", c.clause, c.args, err, ErrBadArguments)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package contract stores compiled Ivy contract templates and builds
+// the transaction actions that lock value with, and unlock value
+// from, instances of them.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol"
+)
+
+var (
+	ErrDuplicateAlias = errors.New("duplicate contract template alias")
+	ErrBadSource      = errors.New("invalid contract template source")
+)
+
+// Template is a compiled Ivy contract stored in the core under an
+// alias. Instantiating it with arguments produces a control program.
+type Template struct {
+	ID        string             `json:"id"`
+	Alias     string             `json:"alias"`
+	Source    string             `json:"source"`
+	Contract  *compiler.Contract `json:"contract"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// Registry stores contract templates and builds contract actions.
+type Registry struct {
+	db    pg.DB
+	chain *protocol.Chain
+}
+
+// NewRegistry returns a new Registry using db for storage and
+// reading spent outputs from chain.
+func NewRegistry(db pg.DB, chain *protocol.Chain) *Registry {
+	return &Registry{db: db, chain: chain}
+}
+
+// Create compiles source and stores the contract named name under
+// alias. If source contains a single contract, name may be empty.
+func (reg *Registry) Create(ctx context.Context, source, name, alias, clientToken string) (*Template, error) {
+	if alias == "" {
+		return nil, errors.WithDetail(ErrBadSource, "missing alias")
+	}
+	contracts, err := compiler.Compile(bytes.NewBufferString(source))
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadSource, err.Error())
+	}
+	var contract *compiler.Contract
+	switch {
+	case name != "":
+		for _, c := range contracts {
+			if c.Name == name {
+				contract = c
+			}
+		}
+		if contract == nil {
+			return nil, errors.WithDetailf(ErrBadSource, "no contract named %s", name)
+		}
+	case len(contracts) == 1:
+		contract = contracts[0]
+	default:
+		return nil, errors.WithDetailf(ErrBadSource, "source defines %d contracts; a name is required", len(contracts))
+	}
+
+	const q = `
+		INSERT INTO contract_templates (alias, source, contract, client_token)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_token) DO NOTHING
+		RETURNING id, created_at
+	`
+	contractJSON, err := json.Marshal(contract)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling contract")
+	}
+	nullToken := sql.NullString{
+		String: clientToken,
+		Valid:  clientToken != "",
+	}
+	tpl := &Template{
+		Alias:    alias,
+		Source:   source,
+		Contract: contract,
+	}
+	err = reg.db.QueryRowContext(ctx, q, alias, source, contractJSON, nullToken).Scan(&tpl.ID, &tpl.CreatedAt)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "a contract template with the provided alias already exists")
+	} else if err == sql.ErrNoRows && clientToken != "" {
+		// A template with the provided client token already exists;
+		// return it.
+		return reg.find(ctx, "client_token", clientToken)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "inserting contract template")
+	}
+	return tpl, nil
+}
+
+// Find returns the template with the given id or, if id is empty,
+// alias.
+func (reg *Registry) Find(ctx context.Context, id, alias string) (*Template, error) {
+	if id != "" {
+		return reg.find(ctx, "id", id)
+	}
+	return reg.find(ctx, "alias", alias)
+}
+
+func (reg *Registry) find(ctx context.Context, col, val string) (*Template, error) {
+	q := fmt.Sprintf(`
+		SELECT id, alias, source, contract, created_at FROM contract_templates
+		WHERE %s=$1
+	`, col)
+	tpl, err := scanTemplate(reg.db.QueryRowContext(ctx, q, val))
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "could not find contract template with %s=%s", col, val)
+	}
+	return tpl, err
+}
+
+// List returns contract templates, most recently created first.
+func (reg *Registry) List(ctx context.Context, after string, limit int) ([]*Template, string, error) {
+	const baseQ = `
+		SELECT id, alias, source, contract, created_at FROM contract_templates
+		WHERE ($1='' OR id < $1) ORDER BY id DESC LIMIT %d
+	`
+	rows, err := reg.db.QueryContext(ctx, fmt.Sprintf(baseQ, limit), after)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "executing contract templates query")
+	}
+	defer rows.Close()
+
+	tpls := make([]*Template, 0, limit)
+	for rows.Next() {
+		tpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		tpls = append(tpls, tpl)
+		after = tpl.ID
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, "", errors.Wrap(err)
+	}
+	return tpls, after, nil
+}
+
+func scanTemplate(row interface {
+	Scan(...interface{}) error
+}) (*Template, error) {
+	var (
+		tpl          Template
+		contractJSON []byte
+	)
+	err := row.Scan(&tpl.ID, &tpl.Alias, &tpl.Source, &contractJSON, &tpl.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap(err, "scanning contract template")
+	}
+	tpl.Contract = new(compiler.Contract)
+	err = json.Unmarshal(contractJSON, tpl.Contract)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling contract")
+	}
+	return &tpl, nil
+}
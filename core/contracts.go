@@ -0,0 +1,32 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/contract"
+)
+
+// POST /create-contract-template
+func (a *API) createContractTemplate(ctx context.Context, in struct {
+	Alias  string `json:"alias"`
+	Source string `json:"source"`
+
+	// Name selects the contract to store when Source defines more
+	// than one.
+	Name string `json:"name"`
+
+	// ClientToken is the application's unique token for the
+	// template. Duplicate requests with the same client_token will
+	// only create one template.
+	ClientToken string `json:"client_token"`
+}) (*contract.Template, error) {
+	return a.contracts.Create(ctx, in.Source, in.Name, in.Alias, in.ClientToken)
+}
+
+// POST /get-contract-template
+func (a *API) getContractTemplate(ctx context.Context, in struct {
+	ID    string `json:"id,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}) (*contract.Template, error) {
+	return a.contracts.Find(ctx, in.ID, in.Alias)
+}
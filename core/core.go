@@ -25,6 +25,7 @@ var (
 	errUnconfigured      = errors.New("core is not configured")
 	errNoMockHSM         = errors.New("core is not configured with a mockhsm")
 	errNoReset           = errors.New("core is not configured with reset capabilities")
+	errNoGenerator       = errors.New("core is not configured as a generator")
 	errBadBlockPub       = errors.New("supplied block pub key is invalid")
 	errNoClientTokens    = errors.New("cannot enable client auth without client access tokens")
 )
@@ -123,6 +124,9 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 		"build_config":                      config.BuildConfig,
 		"health":                            a.health(),
 	}
+	if a.consolidator != nil {
+		m["utxo_consolidation"] = a.consolidator.Status()
+	}
 
 	// Add in snapshot information if we're downloading a snapshot.
 	if snapshot != nil {
@@ -8,6 +8,7 @@ import (
 	"chain/core/asset"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/contract"
 	"chain/core/leader"
 	"chain/core/query"
 	"chain/core/query/filter"
@@ -69,6 +70,7 @@ var errorFormatter = httperror.Formatter{
 		asset.ErrDuplicateAlias:    {400, "CH050", "Alias already exists"},
 		account.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
 		txfeed.ErrDuplicateAlias:   {400, "CH050", "Alias already exists"},
+		contract.ErrDuplicateAlias: {400, "CH050", "Alias already exists"},
 		account.ErrBadIdentifier:   {400, "CH051", "Either an ID or alias must be provided, but not both"},
 		asset.ErrBadIdentifier:     {400, "CH051", "Either an ID or alias must be provided, but not both"},
 
@@ -85,7 +87,9 @@ var errorFormatter = httperror.Formatter{
 		config.ErrNoBlockPub:           {400, "CH109", "Block Pub cannot be empty when configuring a mockhsm disabled signer"},
 		errNoMockHSM:                   {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		errNoReset:                     {400, "CH110", "This endpoint is disabled for this server's configuration"},
+		errNoGenerator:                 {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		config.ErrNoBlockHSMURL:        {400, "CH111", "Block HSM URL cannot be empty when configuring a non mockhsm signer"},
+		errNoSnapshot:                  {400, "CH112", "No state snapshot is available yet"},
 		errNoClientTokens:              {400, "CH120", "Cannot enable client authentication with no client tokens"},
 		blocksigner.ErrConsensusChange: {400, "CH150", "Refuse to sign block with consensus change"},
 		errMissingAddr:                 {400, "CH160", "Address is missing"},
@@ -117,16 +121,21 @@ var errorFormatter = httperror.Formatter{
 		query.ErrBadAfter:               {400, "CH600", "Malformed pagination parameter `after`"},
 		query.ErrParameterCountMismatch: {400, "CH601", "Incorrect number of parameters to filter"},
 		filter.ErrBadFilter:             {400, "CH602", "Malformed query filter"},
+		txfeed.ErrBadWebhook:            {400, "CH603", "Invalid transaction feed webhook"},
 
 		// Transaction error namespace (7xx)
 		// Build error namespace (70x)
-		txbuilder.ErrBadRefData: {400, "CH700", "Reference data does not match previous transaction's reference data"},
-		errBadActionType:        {400, "CH701", "Invalid action type"},
-		errBadAlias:             {400, "CH702", "Invalid alias on action"},
-		errBadAction:            {400, "CH703", "Invalid action object"},
-		txbuilder.ErrBadAmount:  {400, "CH704", "Invalid asset amount"},
-		txbuilder.ErrBlankCheck: {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
-		txbuilder.ErrAction:     {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrBadRefData:       {400, "CH700", "Reference data does not match previous transaction's reference data"},
+		errBadActionType:              {400, "CH701", "Invalid action type"},
+		errBadAlias:                   {400, "CH702", "Invalid alias on action"},
+		errBadAction:                  {400, "CH703", "Invalid action object"},
+		txbuilder.ErrBadAmount:        {400, "CH704", "Invalid asset amount"},
+		txbuilder.ErrBlankCheck:       {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
+		txbuilder.ErrAction:           {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrNoTemplates:      {400, "CH707", "At least one transaction template is required"},
+		txbuilder.ErrBadTxVersion:     {400, "CH708", "Transaction templates have different versions"},
+		txbuilder.ErrDuplicateInput:   {400, "CH709", "An input appears in more than one transaction template"},
+		txbuilder.ErrBrokenCommitment: {400, "CH710", "Merging would invalidate an existing signature: see attached detail"},
 
 		// Submit error namespace (73x)
 		txbuilder.ErrMissingRawTx:          {400, "CH730", "Missing raw transaction"},
@@ -139,8 +148,19 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrNoTxSighashAttempt:    {400, "CH738", "Transaction signature was not attempted"},
 
 		// account action error namespace (76x)
-		account.ErrInsufficient: {400, "CH760", "Insufficient funds for tx"},
-		account.ErrReserved:     {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrInsufficient:  {400, "CH760", "Insufficient funds for tx"},
+		account.ErrReserved:      {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrBatchTooLarge: {400, "CH762", "Batch action is too large"},
+		account.ErrCanceled:      {400, "CH763", "Transaction spends outputs of a canceled reservation"},
+		account.ErrBadSelection:  {400, "CH764", "Invalid coin selection strategy"},
+		account.ErrWatchOnly:     {400, "CH765", "Account is watch-only"},
+		account.ErrProgramInUse:  {400, "CH766", "Control program already belongs to an account"},
+
+		// contract action error namespace (77x)
+		contract.ErrBadSource:       {400, "CH770", "Invalid contract template source: see attached detail"},
+		contract.ErrBadArguments:    {400, "CH771", "Invalid contract or clause arguments: see attached detail"},
+		contract.ErrProgramMismatch: {400, "CH772", "Output is not locked by the given contract template and arguments"},
+		contract.ErrSpent:           {400, "CH773", "Contract output has already been spent"},
 
 		// Mock HSM error namespace (80x)
 	},
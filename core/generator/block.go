@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lib/pq"
+
 	"chain/crypto/ed25519"
 	"chain/database/pg"
 	"chain/errors"
@@ -64,6 +66,11 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 			log.Fatalkv(ctx, log.KeyError, err)
 		}
 	} else {
+		err = g.loadPool(ctx)
+		if err != nil {
+			return errors.Wrap(err, "loading the pending tx pool")
+		}
+
 		g.mu.Lock()
 		txs := g.pool
 		g.pool = nil
@@ -74,10 +81,19 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 		if err != nil {
 			return errors.Wrap(err, "generate")
 		}
+
+		// The txs in the pending block leave the persisted pool, and
+		// the ones left out as invalid are rejected, in the same
+		// statement that saves the block. Any others were left out
+		// only because the block was full, and are loaded again next
+		// time. If generating the block failed, all of the txs stay
+		// in the persisted pool.
+		rejected := g.leftOut(latestSnapshot, b, txs)
 		if len(b.Transactions) == 0 {
-			return nil // don't bother making an empty block
+			// Don't bother making an empty block.
+			return errors.Wrap(rejectPendingTxs(ctx, g.db, rejected), "rejecting invalid pending txs")
 		}
-		err = savePendingBlock(ctx, g.db, b)
+		err = savePendingBlock(ctx, g.db, b, rejected)
 		if err != nil {
 			return errors.Wrap(err, "saving pending block")
 		}
@@ -85,6 +101,37 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 	return g.commitBlock(ctx, b, s, latestBlock)
 }
 
+// leftOut returns the reasons the txs that GenerateBlock
+// didn't put in b are invalid for it, found by checking them
+// in the same order against the same state. Valid txs that
+// didn't fit in b are absent.
+func (g *Generator) leftOut(snapshot *state.Snapshot, b *legacy.Block, txs []*legacy.Tx) map[bc.Hash]error {
+	included := make(map[bc.Hash]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		included[tx.ID] = true
+	}
+	if snapshot == nil {
+		snapshot = state.Empty()
+	}
+	s := state.Copy(snapshot)
+	s.PruneNonces(b.TimestampMS)
+
+	reasons := make(map[bc.Hash]error)
+	for _, tx := range txs {
+		err := g.checkTx(tx, b.TimestampMS)
+		if err == nil {
+			err = s.ApplyTx(tx.Tx)
+		}
+		if err != nil {
+			reasons[tx.ID] = err
+		} else if !included[tx.ID] {
+			// GenerateBlock stopped here because b was full.
+			break
+		}
+	}
+	return reasons
+}
+
 func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.Snapshot, prevBlock *legacy.Block) error {
 	err := g.getAndAddBlockSignatures(ctx, b, prevBlock)
 	if err != nil {
@@ -190,24 +237,49 @@ func getPendingBlock(ctx context.Context, db pg.DB) (*legacy.Block, error) {
 }
 
 // savePendingBlock persists a pending, uncommitted block to the database.
-// The generator should save a pending block *before* asking signers to
-// sign the block.
-func savePendingBlock(ctx context.Context, db pg.DB, b *legacy.Block) error {
+// In the same statement, it removes the block's txs from the persisted
+// pool and marks the txs in rejected as rejected, as rejectPendingTxs
+// does. The generator should save a pending block *before* asking
+// signers to sign the block.
+func savePendingBlock(ctx context.Context, db pg.DB, b *legacy.Block, rejected map[bc.Hash]error) error {
+	included := make([][]byte, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		included = append(included, tx.ID.Bytes())
+	}
+	var (
+		hashes [][]byte
+		msgs   []string
+	)
+	for id, err := range rejected {
+		hashes = append(hashes, id.Bytes())
+		msgs = append(msgs, err.Error())
+	}
 	const q = `
-		INSERT INTO generator_pending_block (data, height) VALUES($1, $2)
-		ON CONFLICT (singleton) DO UPDATE
-			SET data = excluded.data, height = excluded.height
-			WHERE COALESCE(generator_pending_block.height, 0) < excluded.height
+		WITH block AS (
+			INSERT INTO generator_pending_block (data, height) VALUES($1, $2)
+			ON CONFLICT (singleton) DO UPDATE
+				SET data = excluded.data, height = excluded.height
+				WHERE COALESCE(generator_pending_block.height, 0) < excluded.height
+			RETURNING height
+		), included AS (
+			DELETE FROM generator_pending_txs
+			WHERE tx_hash = ANY($3::bytea[]) AND EXISTS (SELECT 1 FROM block)
+		), rejected AS (
+			UPDATE generator_pending_txs SET rejected_at = now(), error = r.error
+			FROM (SELECT unnest($4::bytea[]) AS tx_hash, unnest($5::text[]) AS error) r
+			WHERE generator_pending_txs.tx_hash = r.tx_hash AND EXISTS (SELECT 1 FROM block)
+		), expired AS (
+			DELETE FROM generator_pending_txs WHERE rejected_at < now() - interval '1 day'
+		)
+		SELECT count(*) FROM block
 	`
-	res, err := db.ExecContext(ctx, q, b, b.Height)
+	var saved int
+	err := db.QueryRowContext(ctx, q, b, b.Height, pq.ByteaArray(included),
+		pq.ByteaArray(hashes), pq.StringArray(msgs)).Scan(&saved)
 	if err != nil {
 		return errors.Wrap(err, "generator_pending_block insert query")
 	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "generator_pending_block rows affected")
-	}
-	if affected == 0 {
+	if saved == 0 {
 		return errDuplicateBlock
 	}
 	return nil
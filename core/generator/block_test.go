@@ -4,7 +4,10 @@ import (
 	"context"
 	"testing"
 
+	"chain/database/pg"
 	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
 )
 
@@ -13,26 +16,59 @@ func TestSavePendingBlock(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 
 	// Save a pending block.
-	err := savePendingBlock(ctx, db, fakeBlock(100))
+	err := savePendingBlock(ctx, db, fakeBlock(100), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Saving another block at the same height or lower should error.
-	err = savePendingBlock(ctx, db, fakeBlock(20))
+	err = savePendingBlock(ctx, db, fakeBlock(20), nil)
 	if err != errDuplicateBlock {
 		t.Errorf("got %s, want %s", err, errDuplicateBlock)
 	}
-	err = savePendingBlock(ctx, db, fakeBlock(100))
+	err = savePendingBlock(ctx, db, fakeBlock(100), nil)
 	if err != errDuplicateBlock {
 		t.Errorf("got %s, want %s", err, errDuplicateBlock)
 	}
 
 	// Saving a higher block should succeed.
-	err = savePendingBlock(ctx, db, fakeBlock(101))
+	err = savePendingBlock(ctx, db, fakeBlock(101), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// The txs of a block leave the pool only if the block is saved.
+	tx := bctest.NewIssuanceTx(t, bc.Hash{})
+	err = savePendingTx(ctx, db, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := fakeBlock(101)
+	b.Transactions = []*legacy.Tx{tx}
+	err = savePendingBlock(ctx, db, b, nil)
+	if err != errDuplicateBlock {
+		t.Errorf("got %v, want %s", err, errDuplicateBlock)
+	}
+	if n := countPendingTxs(ctx, t, db); n != 1 {
+		t.Errorf("got %d pending txs after failing to save the block, want 1", n)
+	}
+	b.Height = 102
+	err = savePendingBlock(ctx, db, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countPendingTxs(ctx, t, db); n != 0 {
+		t.Errorf("got %d pending txs after saving the block, want 0", n)
+	}
+}
+
+func countPendingTxs(ctx context.Context, t *testing.T, db pg.DB) int {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT count(*) FROM generator_pending_txs`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
 }
 
 func fakeBlock(height uint64) *legacy.Block {
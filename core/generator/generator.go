@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"chain/database/pg"
+	"chain/errors"
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
@@ -63,14 +64,26 @@ func (g *Generator) PendingTxs() []*legacy.Tx {
 }
 
 // Submit adds a new pending tx to the pending tx pool.
+// The tx is persisted, so it isn't lost if the generator
+// exits or loses leadership before the tx lands in a block.
 func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
 	g.mu.Lock()
-	defer g.mu.Unlock()
+	inPool := g.poolHashes[tx.ID]
+	g.mu.Unlock()
+	if inPool {
+		return nil
+	}
+
+	err := savePendingTx(ctx, g.db, tx)
+	if err != nil {
+		return errors.Wrap(err, "saving pending tx")
+	}
 
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if g.poolHashes[tx.ID] {
 		return nil
 	}
-
 	g.poolHashes[tx.ID] = true
 	g.pool = append(g.pool, tx)
 	return nil
@@ -8,8 +8,11 @@ import (
 	"time"
 
 	"chain/crypto/ed25519"
+	"chain/database/pg"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -28,7 +31,7 @@ func TestGeneratorRecovery(t *testing.T) {
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
-	err = savePendingBlock(ctx, dbtx, pendingBlock)
+	err = savePendingBlock(ctx, dbtx, pendingBlock, nil)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
@@ -88,6 +91,158 @@ func TestGeneratorSignatureFailures(t *testing.T) {
 	}
 }
 
+func TestPendingTxsPersisted(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c).Hash()
+
+	tx := bctest.NewIssuanceTx(t, initial)
+	expired := bctest.NewIssuanceTx(t, initial, func(tx *legacy.Tx) {
+		tx.MaxTime = bc.Millis(time.Now().Add(-time.Minute))
+		tx.Tx = legacy.MapTx(&tx.TxData)
+	})
+
+	// Submit to one generator, as if to a leader that then exits.
+	g1 := New(c, nil, db)
+	for _, tx := range []*legacy.Tx{tx, expired} {
+		err := g1.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	pending, err := g1.Pending(ctx, 0, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("got %d pending txs, want 2", len(pending))
+	}
+	if pending[0].Tx.ID != tx.ID || pending[0].Err != nil {
+		t.Errorf("pending[0] = %x, %v; want %x, nil", pending[0].Tx.ID.Bytes(), pending[0].Err, tx.ID.Bytes())
+	}
+	if pending[1].Tx.ID != expired.ID || errors.Root(pending[1].Err) != errExpired {
+		t.Errorf("pending[1] = %x, %v; want %x, %v", pending[1].Tx.ID.Bytes(), pending[1].Err, expired.ID.Bytes(), errExpired)
+	}
+
+	// Page through the pool one tx at a time.
+	first, err := g1.Pending(ctx, 0, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(first) != 1 || first[0].Tx.ID != tx.ID {
+		t.Fatalf("first page = %v, want only %x", first, tx.ID.Bytes())
+	}
+	second, err := g1.Pending(ctx, first[0].Seq, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(second) != 1 || second[0].Tx.ID != expired.ID {
+		t.Fatalf("second page = %v, want only %x", second, expired.ID.Bytes())
+	}
+	third, err := g1.Pending(ctx, second[0].Seq, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(third) != 0 {
+		t.Errorf("third page = %v, want none", third)
+	}
+
+	p, err := g1.GetPending(ctx, expired.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if errors.Root(p.Err) != errExpired {
+		t.Errorf("GetPending(expired).Err = %v, want %v", p.Err, errExpired)
+	}
+
+	// The next leader should load the valid tx and drop the expired one.
+	g2 := New(c, nil, db)
+	err = g2.loadPool(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got := g2.PendingTxs()
+	if len(got) != 1 || got[0].ID != tx.ID {
+		t.Fatalf("got pool %v, want only %x", got, tx.ID.Bytes())
+	}
+	_, err = g2.GetPending(ctx, expired.ID)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("GetPending(expired) error = %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+	r, err := g2.GetRejection(ctx, expired.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Reason != errExpired.Error() {
+		t.Errorf("rejection reason = %q, want %q", r.Reason, errExpired.Error())
+	}
+
+	err = g2.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	pending, err = g2.Pending(ctx, 0, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("got %d pending txs after making a block, want 0", len(pending))
+	}
+}
+
+func TestRejectedTxs(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c).Hash()
+	g := New(c, nil, db)
+
+	tx := bctest.NewIssuanceTx(t, initial)
+	expiring := bctest.NewIssuanceTx(t, initial, func(tx *legacy.Tx) {
+		tx.MaxTime = bc.Millis(time.Now().Add(10 * time.Millisecond))
+		tx.Tx = legacy.MapTx(&tx.TxData)
+	})
+	for _, tx := range []*legacy.Tx{tx, expiring} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The block leaves out the expired tx, and the
+	// generator records why.
+	err := g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = g.GetRejection(ctx, tx.ID)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("GetRejection(included tx) error = %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+	r, err := g.GetRejection(ctx, expiring.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Reason != errExpired.Error() {
+		t.Errorf("rejection reason = %q, want %q", r.Reason, errExpired.Error())
+	}
+
+	// Submitting the tx again puts it back in the pool.
+	err = g.Submit(ctx, expiring)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	p, err := g.GetPending(ctx, expiring.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if errors.Root(p.Err) != errExpired {
+		t.Errorf("resubmitted tx has error %v, want %v", p.Err, errExpired)
+	}
+}
+
 func TestGetAndAddBlockSignatures(t *testing.T) {
 	c := prottest.NewChain(t, prottest.WithBlockSigners(1, 1))
 	pubkeys, privkeys := prottest.BlockKeyPairs(c)
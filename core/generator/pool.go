@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+)
+
+var (
+	errNotYetValid = errors.New("transaction is not yet valid")
+	errExpired     = errors.New("transaction has expired")
+)
+
+// PendingTx is a transaction in the pending tx pool.
+type PendingTx struct {
+	Tx          *legacy.Tx
+	SubmittedAt time.Time
+
+	// Seq orders the pending txs by when they were submitted.
+	Seq uint64
+
+	// Err is the reason the tx would be left out of
+	// a block made now, or nil if it would be included.
+	Err error
+}
+
+// A Rejection records why the generator dropped a tx from the pool.
+type Rejection struct {
+	TxID       bc.Hash
+	RejectedAt time.Time
+
+	// Reason is the message of the error that made
+	// the tx invalid for a block.
+	Reason string
+}
+
+// Pending returns up to limit pending txs submitted after the one
+// with sequence number after, in the order they were submitted.
+// Each is checked against the current state of the blockchain and
+// the pending txs before it, as if it were being added to the next
+// block.
+//
+// The pool is read from the database, so Pending reflects
+// txs submitted to any process, not just the leader.
+func (g *Generator) Pending(ctx context.Context, after uint64, limit int) ([]*PendingTx, error) {
+	const q = `
+		SELECT seq, data, submitted_at FROM generator_pending_txs
+		WHERE rejected_at IS NULL AND seq <= (
+			SELECT max(seq) FROM (
+				SELECT seq FROM generator_pending_txs
+				WHERE rejected_at IS NULL AND seq > $1 ORDER BY seq LIMIT $2
+			) page
+		)
+		ORDER BY seq
+	`
+	pending, err := g.checkPending(ctx, q, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	for len(pending) > 0 && pending[0].Seq <= after {
+		pending = pending[1:]
+	}
+	return pending, nil
+}
+
+// GetPending returns the pending tx with the given ID, checked
+// as by Pending. It returns pg.ErrUserInputNotFound if the tx
+// isn't in the pool.
+func (g *Generator) GetPending(ctx context.Context, id bc.Hash) (*PendingTx, error) {
+	const q = `
+		SELECT seq, data, submitted_at FROM generator_pending_txs
+		WHERE rejected_at IS NULL
+			AND seq <= (SELECT seq FROM generator_pending_txs WHERE tx_hash = $1)
+		ORDER BY seq
+	`
+	pending, err := g.checkPending(ctx, q, id.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 || pending[len(pending)-1].Tx.ID != id {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s is not pending", id.String())
+	}
+	return pending[len(pending)-1], nil
+}
+
+// GetRejection returns the reason the tx with the given ID was
+// dropped from the pool. It returns pg.ErrUserInputNotFound if
+// the tx wasn't dropped, or was dropped more than a day ago.
+func (g *Generator) GetRejection(ctx context.Context, id bc.Hash) (*Rejection, error) {
+	const q = `
+		SELECT rejected_at, error FROM generator_pending_txs
+		WHERE tx_hash = $1 AND rejected_at IS NOT NULL
+	`
+	r := &Rejection{TxID: id}
+	err := g.db.QueryRowContext(ctx, q, id.Bytes()).Scan(&r.RejectedAt, &r.Reason)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s was not rejected", id.String())
+	} else if err != nil {
+		return nil, errors.Wrap(err, "looking up rejected tx")
+	}
+	return r, nil
+}
+
+// checkPending runs q, which selects the sequence number, data
+// and submission time of pending txs in order, with args. It
+// checks each tx against the current state of the blockchain
+// and the txs before it.
+func (g *Generator) checkPending(ctx context.Context, q string, args ...interface{}) ([]*PendingTx, error) {
+	var pending []*PendingTx
+	args = append(args, func(seq uint64, tx legacy.Tx, submittedAt time.Time) {
+		pending = append(pending, &PendingTx{Tx: &tx, SubmittedAt: submittedAt, Seq: seq})
+	})
+	err := pg.ForQueryRows(ctx, g.db, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing pending txs")
+	}
+
+	_, snapshot := g.chain.State()
+	if snapshot == nil {
+		snapshot = state.Empty()
+	}
+	s := state.Copy(snapshot)
+	ts := bc.Millis(time.Now())
+	s.PruneNonces(ts)
+	for _, p := range pending {
+		p.Err = g.checkTx(p.Tx, ts)
+		if p.Err == nil {
+			p.Err = s.ApplyTx(p.Tx.Tx)
+		}
+	}
+	return pending, nil
+}
+
+// checkTx performs the checks that don't depend on the
+// other txs in the pool: well-formedness and the tx's
+// time range.
+func (g *Generator) checkTx(tx *legacy.Tx, timestampMS uint64) error {
+	err := g.chain.ValidateTx(tx.Tx)
+	if err != nil {
+		return err
+	}
+	if tx.Tx.MinTimeMs > 0 && tx.Tx.MinTimeMs > timestampMS {
+		return errNotYetValid
+	}
+	if tx.Tx.MaxTimeMs > 0 && tx.Tx.MaxTimeMs < timestampMS {
+		return errExpired
+	}
+	return nil
+}
+
+// loadPool adds to the pool the persisted txs it doesn't
+// already contain. These are txs submitted to a previous
+// leader or to another process in the cluster. Txs that
+// are no longer valid are rejected instead.
+func (g *Generator) loadPool(ctx context.Context) error {
+	g.mu.Lock()
+	hashes := make([][]byte, 0, len(g.poolHashes))
+	for h := range g.poolHashes {
+		hashes = append(hashes, h.Bytes())
+	}
+	g.mu.Unlock()
+
+	const q = `
+		SELECT data FROM generator_pending_txs
+		WHERE rejected_at IS NULL AND tx_hash <> ALL($1::bytea[])
+		ORDER BY seq
+	`
+	var (
+		loaded  []*legacy.Tx
+		invalid = make(map[bc.Hash]error)
+		ts      = bc.Millis(time.Now())
+	)
+	err := pg.ForQueryRows(ctx, g.db, q, pq.ByteaArray(hashes), func(tx legacy.Tx) {
+		if err := g.checkTx(&tx, ts); err != nil {
+			invalid[tx.ID] = err
+			return
+		}
+		loaded = append(loaded, &tx)
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading pending txs")
+	}
+	err = rejectPendingTxs(ctx, g.db, invalid)
+	if err != nil {
+		return errors.Wrap(err, "rejecting invalid pending txs")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, tx := range loaded {
+		if g.poolHashes[tx.ID] {
+			continue
+		}
+		g.poolHashes[tx.ID] = true
+		g.pool = append(g.pool, tx)
+	}
+	return nil
+}
+
+// savePendingTx persists a tx submitted to the pool.
+// A tx that was rejected before is put back in the pool,
+// in case whatever made it invalid has changed.
+func savePendingTx(ctx context.Context, db pg.DB, tx *legacy.Tx) error {
+	const q = `
+		INSERT INTO generator_pending_txs (tx_hash, data) VALUES($1, $2)
+		ON CONFLICT (tx_hash) DO UPDATE SET
+			rejected_at = NULL, error = NULL, submitted_at = now(),
+			seq = nextval('generator_pending_txs_seq')
+		WHERE generator_pending_txs.rejected_at IS NOT NULL
+	`
+	_, err := db.ExecContext(ctx, q, tx.ID.Bytes(), tx)
+	return errors.Wrap(err, "insert query")
+}
+
+// rejectPendingTxs marks txs in the persisted pool as rejected,
+// with the errors that made them invalid, and forgets txs that
+// were rejected more than a day ago.
+func rejectPendingTxs(ctx context.Context, db pg.DB, reasons map[bc.Hash]error) error {
+	var (
+		hashes [][]byte
+		msgs   []string
+	)
+	for id, err := range reasons {
+		hashes = append(hashes, id.Bytes())
+		msgs = append(msgs, err.Error())
+	}
+	const q = `
+		WITH rejected AS (
+			UPDATE generator_pending_txs SET rejected_at = now(), error = r.error
+			FROM (SELECT unnest($1::bytea[]) AS tx_hash, unnest($2::text[]) AS error) r
+			WHERE generator_pending_txs.tx_hash = r.tx_hash
+		)
+		DELETE FROM generator_pending_txs WHERE rejected_at < now() - interval '1 day'
+	`
+	_, err := db.ExecContext(ctx, q, pq.ByteaArray(hashes), pq.StringArray(msgs))
+	return errors.Wrap(err, "reject query")
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 
 	"chain/core/mockhsm"
+	"chain/core/signpolicy"
 	"chain/core/txbuilder"
 	"chain/crypto/ed25519/chainkd"
 	"chain/net/http/httperror"
@@ -16,25 +17,46 @@ func init() {
 	errorFormatter.Errors[mockhsm.ErrDuplicateKeyAlias] = httperror.Info{400, "CH050", "Alias already exists"}
 	errorFormatter.Errors[mockhsm.ErrInvalidAfter] = httperror.Info{400, "CH801", "Invalid `after` in query"}
 	errorFormatter.Errors[mockhsm.ErrTooManyAliasesToList] = httperror.Info{400, "CH802", "Too many aliases to list"}
+	errorFormatter.Errors[mockhsm.ErrLocked] = httperror.Info{400, "CH803", "MockHSM is locked"}
+	errorFormatter.Errors[mockhsm.ErrBadPassphrase] = httperror.Info{400, "CH804", "Incorrect MockHSM passphrase"}
+	errorFormatter.Errors[mockhsm.ErrBadBackup] = httperror.Info{400, "CH805", "Invalid key backup"}
+	errorFormatter.Errors[signpolicy.ErrViolation] = httperror.Info{400, "CH806", "Signing policy violation: see attached data"}
 }
 
 // MockHSM configures the Core to expose the MockHSM endpoints. It
 // is only included in non-production builds.
 func MockHSM(hsm *mockhsm.HSM) RunOption {
 	return func(a *API) {
-		h := &mockHSMHandler{MockHSM: hsm}
+		h := &mockHSMHandler{MockHSM: hsm, checkPolicy: a.signingPolicyChecker()}
+		if a.txSigner == nil {
+			a.txSigner = txbuilder.SignWith(h.mockhsmSignTemplate)
+		}
 
 		needConfig := a.needConfig()
+		a.mux.Handle("/mockhsm/unlock", jsonHandler(h.mockhsmUnlock))
 		a.mux.Handle("/mockhsm/create-block-key", jsonHandler(h.mockhsmCreateBlockKey))
 		a.mux.Handle("/mockhsm/create-key", needConfig(h.mockhsmCreateKey))
 		a.mux.Handle("/mockhsm/list-keys", needConfig(h.mockhsmListKeys))
 		a.mux.Handle("/mockhsm/delkey", needConfig(h.mockhsmDelKey))
 		a.mux.Handle("/mockhsm/sign-transaction", needConfig(h.mockhsmSignTemplates))
+		a.mux.Handle("/mockhsm/export-keys", needConfig(h.mockhsmExportKeys))
+		a.mux.Handle("/mockhsm/import-keys", needConfig(h.mockhsmImportKeys))
 	}
 }
 
 type mockHSMHandler struct {
 	MockHSM *mockhsm.HSM
+
+	// checkPolicy, if not nil, is called before signing a template
+	// on behalf of a client and returns an error if the template
+	// breaks the Core's signing policy.
+	checkPolicy func(context.Context, *txbuilder.Template) error
+}
+
+func (h *mockHSMHandler) mockhsmUnlock(ctx context.Context, in struct {
+	Passphrase string `json:"passphrase"`
+}) error {
+	return h.MockHSM.Unlock(ctx, in.Passphrase)
 }
 
 func (h *mockHSMHandler) mockhsmCreateBlockKey(ctx context.Context) (result *mockhsm.Pub, err error) {
@@ -74,13 +96,33 @@ func (h *mockHSMHandler) mockhsmDelKey(ctx context.Context, xpub chainkd.XPub) e
 	return h.MockHSM.DeleteChainKDKey(ctx, xpub)
 }
 
+func (h *mockHSMHandler) mockhsmExportKeys(ctx context.Context, in struct {
+	HSMPassphrase string `json:"hsm_passphrase"`
+	Passphrase    string `json:"passphrase"`
+}) (*mockhsm.Backup, error) {
+	return h.MockHSM.ExportKeys(ctx, in.HSMPassphrase, in.Passphrase)
+}
+
+func (h *mockHSMHandler) mockhsmImportKeys(ctx context.Context, in struct {
+	Backup     *mockhsm.Backup `json:"backup"`
+	Passphrase string          `json:"passphrase"`
+}) ([]*mockhsm.ImportedKey, error) {
+	return h.MockHSM.ImportKeys(ctx, in.Backup, in.Passphrase)
+}
+
 func (h *mockHSMHandler) mockhsmSignTemplates(ctx context.Context, x struct {
 	Txs   []*txbuilder.Template `json:"transactions"`
 	XPubs []chainkd.XPub        `json:"xpubs"`
 }) []interface{} {
 	resp := make([]interface{}, 0, len(x.Txs))
 	for _, tx := range x.Txs {
-		err := txbuilder.Sign(ctx, tx, x.XPubs, h.mockhsmSignTemplate)
+		var err error
+		if h.checkPolicy != nil {
+			err = h.checkPolicy(ctx, tx)
+		}
+		if err == nil {
+			err = txbuilder.Sign(ctx, tx, x.XPubs, h.mockhsmSignTemplate)
+		}
 		if err != nil {
 			info := errorFormatter.Format(err)
 			resp = append(resp, info)
@@ -0,0 +1,286 @@
+// Package lightclient follows a blockchain by its block headers
+// alone.
+//
+// A Client downloads blocks from a Chain Core with the crosscore
+// get-block RPC, checks each block's header against the previous
+// one and its signatures against the previous block's consensus
+// program, and keeps only what it needs to check proofs: each
+// block's hash and Merkle roots. It needs no database and no copy
+// of the blockchain state. Transaction proofs (from a Core's
+// /get-transaction-proof) and output proofs (from /get-output-proof)
+// can then be checked against the verified headers.
+//
+// The client trusts the initial block whose hash is the blockchain
+// ID it is given. Everything after that is verified.
+package lightclient
+
+import (
+	"context"
+	"sync"
+
+	"chain/core/fetch"
+	"chain/core/rpc"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+	"chain/protocol/validation"
+	"chain/protocol/vm/vmutil"
+)
+
+var (
+	// ErrBadHeader is returned when a block header does not
+	// extend the verified headers or is not properly signed.
+	ErrBadHeader = errors.New("invalid block header")
+
+	// ErrUnknownHeight is returned when asked about a block
+	// whose header has not been verified yet.
+	ErrUnknownHeight = errors.New("block header not yet verified")
+
+	// ErrBadProof is returned when a proof does not verify
+	// against the header at the given height.
+	ErrBadProof = errors.New("invalid proof")
+)
+
+// header is the part of a verified block header the client
+// keeps for each height.
+type header struct {
+	hash       bc.Hash
+	txRoot     bc.Hash
+	assetsRoot bc.Hash
+}
+
+// A SignerSet is a consensus program in effect on the blockchain.
+type SignerSet struct {
+	// Height is the height of the block that set the program.
+	// The program validates the blocks after it.
+	Height uint64
+
+	// Program is the consensus program.
+	Program []byte
+
+	// Pubkeys and Quorum are the block signers' keys and the number
+	// of signatures required, if Program is a standard block
+	// multisig program. Otherwise they are empty.
+	Pubkeys []ed25519.PublicKey
+	Quorum  int
+}
+
+// Client verifies and keeps block headers.
+// It is safe for concurrent use.
+type Client struct {
+	blockchainID bc.Hash
+
+	mu         sync.Mutex
+	cond       sync.Cond // broadcasts when headers grows
+	headers    []header  // headers[i] is at height i+1
+	last       *legacy.BlockHeader
+	signerSets []SignerSet
+}
+
+// New returns a Client for the blockchain whose initial block
+// has the given hash. It has no headers until the initial block
+// is added with Sync or AddHeader.
+func New(blockchainID bc.Hash) *Client {
+	c := &Client{blockchainID: blockchainID}
+	c.cond.L = &c.mu
+	return c
+}
+
+// Sync downloads blocks from peer, starting after the last verified
+// header, and adds their headers to c. It runs until ctx is
+// canceled. Network errors are logged and retried. An invalid header
+// means peer is not following the blockchain c trusts, so Sync
+// returns the error.
+func (c *Client) Sync(ctx context.Context, peer *rpc.Client) error {
+	ctx, cancel := context.WithCancel(ctx)
+	blockch, errch := fetch.DownloadBlocks(ctx, peer, c.Height()+1)
+	defer func() {
+		// Unblock the downloader so it sees ctx is done.
+		cancel()
+		go func() {
+			for range blockch {
+			}
+		}()
+		for range errch {
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errch:
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		case b := <-blockch:
+			if b == nil {
+				continue
+			}
+			err := c.AddHeader(&b.BlockHeader)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// AddHeader verifies h and adds it to c.
+// The header at height 1 must hash to the blockchain ID.
+// Later headers must follow the last verified header
+// and satisfy its consensus program.
+func (c *Client) AddHeader(h *legacy.BlockHeader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := h.Hash()
+	if h.Height != uint64(len(c.headers))+1 {
+		return errors.WithDetailf(ErrBadHeader, "got height %d, want %d", h.Height, len(c.headers)+1)
+	}
+	if h.Height == 1 {
+		if hash != c.blockchainID {
+			return errors.WithDetailf(ErrBadHeader, "initial block %x does not match blockchain ID %x", hash.Bytes(), c.blockchainID.Bytes())
+		}
+	} else {
+		err := checkHeader(h, hash, c.last)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.headers = append(c.headers, header{
+		hash:       hash,
+		txRoot:     h.TransactionsMerkleRoot,
+		assetsRoot: h.AssetsMerkleRoot,
+	})
+	if c.last == nil || string(h.ConsensusProgram) != string(c.last.ConsensusProgram) {
+		c.signerSets = append(c.signerSets, newSignerSet(h.Height, h.ConsensusProgram))
+	}
+	c.last = h
+	c.cond.Broadcast()
+	return nil
+}
+
+// checkHeader checks that h, with the given hash,
+// can follow prev on the blockchain.
+func checkHeader(h *legacy.BlockHeader, hash bc.Hash, prev *legacy.BlockHeader) error {
+	prevHash := prev.Hash()
+	switch {
+	case h.Version < prev.Version:
+		return errors.WithDetailf(ErrBadHeader, "previous block version %d, current block version %d", prev.Version, h.Version)
+	case h.PreviousBlockHash != prevHash:
+		return errors.WithDetailf(ErrBadHeader, "previous block ID %x, current block wants %x", prevHash.Bytes(), h.PreviousBlockHash.Bytes())
+	case h.TimestampMS <= prev.TimestampMS:
+		return errors.WithDetailf(ErrBadHeader, "previous block time %d, current block time %d", prev.TimestampMS, h.TimestampMS)
+	}
+
+	b := legacy.MapBlock(&legacy.Block{BlockHeader: *h})
+	err := validation.ValidateBlockSig(b, prev.ConsensusProgram)
+	if err != nil {
+		return errors.Sub(ErrBadHeader, errors.Wrapf(err, "block %x at height %d", hash.Bytes(), h.Height))
+	}
+	return nil
+}
+
+func newSignerSet(height uint64, prog []byte) SignerSet {
+	s := SignerSet{Height: height, Program: prog}
+	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prog)
+	if err == nil {
+		s.Pubkeys, s.Quorum = pubkeys, quorum
+	}
+	return s
+}
+
+// Height returns the height of the last verified header,
+// or 0 if there is none.
+func (c *Client) Height() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return uint64(len(c.headers))
+}
+
+// WaitForHeight waits until the header at the given height
+// is verified, or ctx is done.
+func (c *Client) WaitForHeight(ctx context.Context, height uint64) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uint64(len(c.headers)) < height {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.cond.Wait()
+	}
+	return nil
+}
+
+// BlockHash returns the hash of the verified block
+// at the given height.
+func (c *Client) BlockHash(height uint64) (bc.Hash, error) {
+	h, err := c.header(height)
+	return h.hash, err
+}
+
+// SignerSets returns the consensus programs that have been in effect,
+// in order. The first is set by the initial block.
+func (c *Client) SignerSets() []SignerSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]SignerSet(nil), c.signerSets...)
+}
+
+// VerifyTx checks that p proves the transaction with the
+// given ID is in the verified block at the given height.
+func (c *Client) VerifyTx(height uint64, txID bc.Hash, p *bc.MerkleProof) error {
+	h, err := c.header(height)
+	if err != nil {
+		return err
+	}
+	if !bc.VerifyMerkleProof(h.txRoot, txID, p) {
+		return errors.WithDetailf(ErrBadProof, "transaction %x is not proven to be in block %d", txID.Bytes(), height)
+	}
+	return nil
+}
+
+// VerifyOutput checks that p proves the output with the given ID
+// is unspent (if unspent is true) or not unspent (if false) as of
+// the verified block at the given height.
+func (c *Client) VerifyOutput(height uint64, outputID bc.Hash, unspent bool, p *patricia.Proof) error {
+	h, err := c.header(height)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	if unspent {
+		ok = patricia.VerifyMembership(h.assetsRoot, outputID.Bytes(), p)
+	} else {
+		ok = patricia.VerifyNonMembership(h.assetsRoot, outputID.Bytes(), p)
+	}
+	if !ok {
+		return errors.WithDetailf(ErrBadProof, "output %x is not proven to be unspent=%t at block %d", outputID.Bytes(), unspent, height)
+	}
+	return nil
+}
+
+func (c *Client) header(height uint64) (header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height == 0 || height > uint64(len(c.headers)) {
+		return header{}, errors.WithDetailf(ErrUnknownHeight, "height %d, verified through %d", height, len(c.headers))
+	}
+	return c.headers[height-1], nil
+}
@@ -0,0 +1,195 @@
+package lightclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/core/rpc"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+func TestVerifyProofs(t *testing.T) {
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c)
+	tx1 := bctest.NewIssuanceTx(t, initial.Hash())
+	tx2 := bctest.NewIssuanceTx(t, initial.Hash())
+	time.Sleep(time.Millisecond) // block times must increase
+	b := prottest.MakeBlock(t, c, []*legacy.Tx{tx1, tx2})
+	_, snapshot := c.State()
+
+	lc := New(initial.Hash())
+	for _, h := range []*legacy.BlockHeader{&initial.BlockHeader, &b.BlockHeader} {
+		err := lc.AddHeader(h)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	txProof, err := bc.NewMerkleProof([]*bc.Tx{tx1.Tx, tx2.Tx}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = lc.VerifyTx(b.Height, tx2.ID, txProof)
+	if err != nil {
+		t.Errorf("VerifyTx(tx2) = %v, want nil", err)
+	}
+	err = lc.VerifyTx(b.Height, tx1.ID, txProof)
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("VerifyTx(tx1) = %v, want %v", err, ErrBadProof)
+	}
+	err = lc.VerifyTx(b.Height+1, tx2.ID, txProof)
+	if errors.Root(err) != ErrUnknownHeight {
+		t.Errorf("VerifyTx at unknown height = %v, want %v", err, ErrUnknownHeight)
+	}
+
+	unspent := *tx1.ResultIds[0]
+	spent := bc.NewHash([32]byte{1})
+	err = lc.VerifyOutput(b.Height, unspent, true, snapshot.Tree.Prove(unspent.Bytes()))
+	if err != nil {
+		t.Errorf("VerifyOutput(unspent) = %v, want nil", err)
+	}
+	err = lc.VerifyOutput(b.Height, spent, false, snapshot.Tree.Prove(spent.Bytes()))
+	if err != nil {
+		t.Errorf("VerifyOutput(spent) = %v, want nil", err)
+	}
+	err = lc.VerifyOutput(b.Height, spent, true, snapshot.Tree.Prove(spent.Bytes()))
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("VerifyOutput(spent, unspent=true) = %v, want %v", err, ErrBadProof)
+	}
+
+	// The initial block's state doesn't have the output.
+	err = lc.VerifyOutput(initial.Height, unspent, true, snapshot.Tree.Prove(unspent.Bytes()))
+	if errors.Root(err) != ErrBadProof {
+		t.Errorf("VerifyOutput(unspent) at initial block = %v, want %v", err, ErrBadProof)
+	}
+}
+
+func TestAddHeader(t *testing.T) {
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c)
+
+	lc := New(bc.NewHash([32]byte{1}))
+	err := lc.AddHeader(&initial.BlockHeader)
+	if errors.Root(err) != ErrBadHeader {
+		t.Fatalf("AddHeader with wrong blockchain ID = %v, want %v", err, ErrBadHeader)
+	}
+
+	lc = New(initial.Hash())
+	err = lc.AddHeader(&initial.BlockHeader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Change from the initial 0-of-0 signer set to a 1-of-1.
+	pubkey, privkey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	prog, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pubkey}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	h2 := nextHeader(&initial.BlockHeader)
+	h2.ConsensusProgram = prog
+	err = lc.AddHeader(h2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	h3 := nextHeader(h2)
+	err = lc.AddHeader(h3)
+	if errors.Root(err) != ErrBadHeader {
+		t.Errorf("AddHeader(unsigned) = %v, want %v", err, ErrBadHeader)
+	}
+
+	h3.PreviousBlockHash = initial.Hash()
+	h3.Witness = [][]byte{ed25519.Sign(privkey, h3.Hash().Bytes())}
+	err = lc.AddHeader(h3)
+	if errors.Root(err) != ErrBadHeader {
+		t.Errorf("AddHeader(wrong previous block) = %v, want %v", err, ErrBadHeader)
+	}
+
+	h3.PreviousBlockHash = h2.Hash()
+	h3.Witness = [][]byte{ed25519.Sign(privkey, h3.Hash().Bytes())}
+	err = lc.AddHeader(h3)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got := lc.Height(); got != 3 {
+		t.Errorf("Height() = %d, want 3", got)
+	}
+
+	sets := lc.SignerSets()
+	if len(sets) != 2 {
+		t.Fatalf("got %d signer sets, want 2", len(sets))
+	}
+	if sets[0].Height != 1 || sets[0].Quorum != 0 || len(sets[0].Pubkeys) != 0 {
+		t.Errorf("signer set 0 = %+v, want the initial 0-of-0", sets[0])
+	}
+	if sets[1].Height != 2 || sets[1].Quorum != 1 || len(sets[1].Pubkeys) != 1 || !bytes.Equal(pubkey, sets[1].Pubkeys[0]) {
+		t.Errorf("signer set 1 = %+v, want 1-of-1 at height 2", sets[1])
+	}
+}
+
+func TestSync(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c)
+	blocks := []*legacy.Block{initial}
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond) // block times must increase
+		blocks = append(blocks, prottest.MakeBlock(t, c, nil))
+	}
+
+	// The peer serves a block with a forged header after the valid ones.
+	forged := *blocks[len(blocks)-1]
+	forged.Height++
+	forged.PreviousBlockHash = bc.NewHash([32]byte{1})
+	blocks = append(blocks, &forged)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var height uint64
+		err := json.NewDecoder(req.Body).Decode(&height)
+		if err != nil || height == 0 || height > uint64(len(blocks)) {
+			http.Error(w, "bad height", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(blocks[height-1])
+	}))
+	defer server.Close()
+
+	lc := New(initial.Hash())
+	err := lc.Sync(ctx, &rpc.Client{BaseURL: server.URL})
+	if errors.Root(err) != ErrBadHeader {
+		t.Errorf("Sync() = %v, want %v", err, ErrBadHeader)
+	}
+	if got, want := lc.Height(), c.Height(); got != want {
+		t.Errorf("Height() = %d, want %d", got, want)
+	}
+}
+
+func nextHeader(prev *legacy.BlockHeader) *legacy.BlockHeader {
+	return &legacy.BlockHeader{
+		Version:           prev.Version,
+		Height:            prev.Height + 1,
+		PreviousBlockHash: prev.Hash(),
+		TimestampMS:       prev.TimestampMS + 1,
+		BlockCommitment: legacy.BlockCommitment{
+			ConsensusProgram: prev.ConsensusProgram,
+		},
+	}
+}
@@ -81,4 +81,133 @@ var migrations = []migration{
 		ALTER TABLE ONLY core_id
 			ADD CONSTRAINT core_id_pkey PRIMARY KEY (singleton);
 	`},
+	{Name: `2017-07-05.0.txfeed.webhooks.sql`, SQL: `
+		ALTER TABLE txfeeds
+			ADD COLUMN webhook_url text,
+			ADD COLUMN webhook_secret text;
+		CREATE TABLE txfeed_deliveries (
+			id text DEFAULT next_chain_id('dlv'::text) NOT NULL,
+			feed_id text NOT NULL,
+			tx_id bytea NOT NULL,
+			cursor text NOT NULL,
+			status text NOT NULL,
+			attempts integer NOT NULL,
+			last_error text DEFAULT ''::text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY txfeed_deliveries
+			ADD CONSTRAINT txfeed_deliveries_pkey PRIMARY KEY (id);
+		CREATE INDEX txfeed_deliveries_feed_id_idx ON txfeed_deliveries USING btree (feed_id, id);
+	`},
+	{Name: `2017-07-10.0.query.output-heightspan.sql`, SQL: `
+		ALTER TABLE annotated_outputs ADD COLUMN heightspan int8range;
+		UPDATE annotated_outputs
+			SET heightspan = CASE WHEN type='retire' THEN int8range(block_height, block_height) ELSE int8range(block_height, NULL) END;
+		UPDATE annotated_outputs AS out SET heightspan = int8range(out.block_height, txs.block_height)
+			FROM annotated_inputs AS inp, annotated_txs AS txs
+			WHERE inp.spent_output_id = out.output_id AND txs.tx_hash = inp.tx_hash;
+		ALTER TABLE annotated_outputs ALTER COLUMN heightspan SET NOT NULL;
+		CREATE INDEX annotated_outputs_heightspan_idx ON annotated_outputs USING gist (heightspan);
+	`},
+	{Name: `2017-07-12.0.contract.templates.sql`, SQL: `
+		CREATE TABLE contract_templates (
+			id text DEFAULT next_chain_id('ctm'::text) NOT NULL,
+			alias text NOT NULL,
+			source text NOT NULL,
+			contract jsonb NOT NULL,
+			client_token text,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY contract_templates
+			ADD CONSTRAINT contract_templates_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY contract_templates
+			ADD CONSTRAINT contract_templates_alias_key UNIQUE (alias);
+		ALTER TABLE ONLY contract_templates
+			ADD CONSTRAINT contract_templates_client_token_key UNIQUE (client_token);
+	`},
+	{Name: `2017-07-13.0.account.reservations.sql`, SQL: `
+		CREATE SEQUENCE reservations_id_seq;
+		CREATE TABLE reservations (
+			id bigint NOT NULL,
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			change bigint NOT NULL,
+			expiry timestamp with time zone NOT NULL,
+			client_token text
+		);
+		ALTER TABLE ONLY reservations
+			ADD CONSTRAINT reservations_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY reservations
+			ADD CONSTRAINT reservations_client_token_key UNIQUE (client_token);
+		CREATE INDEX reservations_expiry_idx ON reservations USING btree (expiry);
+		CREATE TABLE reserved_utxos (
+			output_id bytea NOT NULL,
+			reservation_id bigint NOT NULL
+		);
+		ALTER TABLE ONLY reserved_utxos
+			ADD CONSTRAINT reserved_utxos_pkey PRIMARY KEY (output_id);
+		ALTER TABLE ONLY reserved_utxos
+			ADD CONSTRAINT reserved_utxos_reservation_id_fkey FOREIGN KEY (reservation_id) REFERENCES reservations(id) ON DELETE CASCADE;
+		CREATE INDEX reserved_utxos_reservation_id_idx ON reserved_utxos USING btree (reservation_id);
+	`},
+	{Name: `2017-07-14.0.account.canceled-utxos.sql`, SQL: `
+		CREATE TABLE canceled_utxos (
+			output_id bytea NOT NULL,
+			reservation_id bigint NOT NULL,
+			expiry timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY canceled_utxos
+			ADD CONSTRAINT canceled_utxos_pkey PRIMARY KEY (output_id);
+	`},
+	{Name: `2017-07-15.0.account.coin-selection.sql`, SQL: `
+		ALTER TABLE accounts ADD COLUMN coin_selection text;
+	`},
+	{Name: `2017-07-16.0.mockhsm.encrypt-keys.sql`, SQL: `
+		ALTER TABLE mockhsm ADD COLUMN encrypted boolean DEFAULT false NOT NULL;
+		CREATE TABLE mockhsm_kek (
+			singleton boolean DEFAULT true NOT NULL PRIMARY KEY,
+			salt bytea NOT NULL,
+			verifier bytea NOT NULL,
+			CONSTRAINT mockhsm_kek_singleton CHECK (singleton)
+		);
+	`},
+	{Name: `2017-07-17.0.signers.key-versions.sql`, SQL: `
+		ALTER TABLE signers ADD COLUMN key_version integer DEFAULT 1 NOT NULL;
+		ALTER TABLE account_control_programs ADD COLUMN key_version integer DEFAULT 1 NOT NULL;
+		ALTER TABLE account_utxos ADD COLUMN key_version integer DEFAULT 1 NOT NULL;
+		CREATE TABLE signer_key_versions (
+			signer_id text NOT NULL,
+			version integer NOT NULL,
+			xpubs bytea[] NOT NULL,
+			quorum integer NOT NULL,
+			retired_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY signer_key_versions
+			ADD CONSTRAINT signer_key_versions_pkey PRIMARY KEY (signer_id, version);
+	`},
+	{Name: `2017-07-18.0.account.watch-only.sql`, SQL: `
+		ALTER TABLE accounts ADD COLUMN watch_only boolean DEFAULT false NOT NULL;
+		ALTER TABLE annotated_accounts ADD COLUMN watch_only boolean DEFAULT false NOT NULL;
+	`},
+	{Name: `2017-07-19.0.generator.pending-txs.sql`, SQL: `
+		CREATE SEQUENCE generator_pending_txs_seq;
+		CREATE TABLE generator_pending_txs (
+			tx_hash bytea NOT NULL,
+			data bytea NOT NULL,
+			submitted_at timestamp with time zone DEFAULT now() NOT NULL,
+			seq bigint DEFAULT nextval('generator_pending_txs_seq') NOT NULL
+		);
+		ALTER TABLE ONLY generator_pending_txs
+			ADD CONSTRAINT generator_pending_txs_pkey PRIMARY KEY (tx_hash);
+	`},
+	{Name: `2017-07-20.0.query.annotated-txs-hash.sql`, SQL: `
+		CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+	`},
+	{Name: `2017-07-21.0.generator.pending-txs-seq.sql`, SQL: `
+		CREATE UNIQUE INDEX generator_pending_txs_seq_idx ON generator_pending_txs USING btree (seq);
+	`},
+	{Name: `2017-07-22.0.generator.pending-txs-rejected.sql`, SQL: `
+		ALTER TABLE generator_pending_txs ADD COLUMN rejected_at timestamp with time zone;
+		ALTER TABLE generator_pending_txs ADD COLUMN error text;
+	`},
 }
@@ -0,0 +1,243 @@
+package mockhsm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	"github.com/lib/pq"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/crypto/kek"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+)
+
+// BackupVersion is the version of the backup format
+// produced by ExportKeys.
+const BackupVersion = 1
+
+// ErrBadBackup indicates a key backup that can't be imported.
+var ErrBadBackup = errors.New("invalid key backup")
+
+// Import statuses reported in ImportedKey.
+const (
+	// KeyImported means the key was stored under its original alias.
+	KeyImported = "imported"
+
+	// KeyRenamed means the key's alias belonged to a different key,
+	// so the key was stored under a new alias.
+	KeyRenamed = "renamed"
+
+	// KeyExists means the key was already in the HSM and was left
+	// unchanged, alias included.
+	KeyExists = "exists"
+)
+
+// Backup is a passphrase-encrypted bundle of keys
+// exported from a mockhsm.
+type Backup struct {
+	Version    int                `json:"version"`
+	Salt       chainjson.HexBytes `json:"salt"`
+	Ciphertext chainjson.HexBytes `json:"ciphertext"`
+}
+
+// ImportedKey reports what ImportKeys did with one key of a backup.
+type ImportedKey struct {
+	Type          string             `json:"type"`
+	Pub           chainjson.HexBytes `json:"pub"`
+	Alias         *string            `json:"alias"`
+	OriginalAlias *string            `json:"original_alias"`
+	Status        string             `json:"status"`
+}
+
+// backupKey is a single key in the plaintext of a backup.
+type backupKey struct {
+	Type  string             `json:"type"`
+	Alias *string            `json:"alias"`
+	Pub   chainjson.HexBytes `json:"pub"`
+	Prv   chainjson.HexBytes `json:"prv"`
+}
+
+// backupAD is the additional data authenticated along with
+// the keys in a backup, binding them to the backup version.
+func backupAD(version int) []byte {
+	return []byte("chain mockhsm backup v" + strconv.Itoa(version))
+}
+
+// ExportKeys returns all of the HSM's chain_kd and ed25519 keys,
+// with their aliases, encrypted with a key derived from passphrase.
+// Since the backup is protected only by passphrase, which the caller
+// chooses, the caller must also supply the HSM's own passphrase,
+// hsmPassphrase, or ExportKeys fails with ErrBadPassphrase.
+func (h *HSM) ExportKeys(ctx context.Context, hsmPassphrase, passphrase string) (*Backup, error) {
+	aead, err := h.checkPassphrase(ctx, hsmPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []backupKey
+	const q = `SELECT key_type, alias, pub, prv, encrypted FROM mockhsm ORDER BY sort_id`
+	err = pg.ForQueryRows(ctx, h.db, q, func(keyType string, alias sql.NullString, pub, prv []byte, encrypted bool) error {
+		prv, err := decryptKey(aead, pub, prv, encrypted)
+		if err != nil {
+			return err
+		}
+		k := backupKey{Type: keyType, Pub: pub, Prv: prv}
+		if alias.Valid {
+			k.Alias = &alias.String
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading keys")
+	}
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	salt, err := kek.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	backupAEAD, err := kek.New(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := kek.Seal(backupAEAD, plaintext, backupAD(BackupVersion))
+	if err != nil {
+		return nil, err
+	}
+	return &Backup{Version: BackupVersion, Salt: salt, Ciphertext: ciphertext}, nil
+}
+
+// ImportKeys decrypts b with passphrase and stores its keys in the HSM,
+// in the order they were exported. The HSM must be unlocked.
+//
+// Keys already in the HSM are left unchanged. If a key's alias is used
+// by a different key, the key is stored under the first free alias of
+// the form "<alias>-N", for N = 2, 3, and so on.
+func (h *HSM) ImportKeys(ctx context.Context, b *Backup, passphrase string) ([]*ImportedKey, error) {
+	if b == nil || b.Version != BackupVersion {
+		return nil, errors.WithDetailf(ErrBadBackup, "unsupported backup version; this core supports version %d", BackupVersion)
+	}
+	backupAEAD, err := kek.New(passphrase, b.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := kek.Open(backupAEAD, b.Ciphertext, backupAD(b.Version))
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadBackup, "wrong passphrase or corrupted backup")
+	}
+	var keys []backupKey
+	err = json.Unmarshal(plaintext, &keys)
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadBackup, "malformed key list")
+	}
+	for i, k := range keys {
+		if err := k.validate(); err != nil {
+			return nil, errors.WithDetailf(err, "key %d", i)
+		}
+	}
+
+	var imported []*ImportedKey
+	for _, k := range keys {
+		ik, err := h.importKey(ctx, k)
+		if err != nil {
+			return imported, errors.Wrapf(err, "importing key %x", []byte(k.Pub))
+		}
+		imported = append(imported, ik)
+	}
+	return imported, nil
+}
+
+func (h *HSM) importKey(ctx context.Context, k backupKey) (*ImportedKey, error) {
+	ik, err := h.existingKey(ctx, k)
+	if err != nil || ik != nil {
+		return ik, err
+	}
+	sealed, err := h.seal(k.Prv, k.Pub)
+	if err != nil {
+		return nil, err
+	}
+	ik = &ImportedKey{Type: k.Type, Pub: k.Pub, OriginalAlias: k.Alias, Status: KeyImported}
+
+	const q = `
+		INSERT INTO mockhsm (pub, prv, alias, key_type, encrypted) VALUES ($1, $2, $3, $4, true)
+	`
+	for n := 1; ; n++ {
+		var alias sql.NullString
+		if k.Alias != nil {
+			alias = sql.NullString{String: *k.Alias, Valid: true}
+			if n > 1 {
+				alias.String += "-" + strconv.Itoa(n)
+				ik.Status = KeyRenamed
+			}
+		}
+		_, err = h.db.ExecContext(ctx, q, []byte(k.Pub), sealed, alias, k.Type)
+		if err == nil {
+			if alias.Valid {
+				ik.Alias = &alias.String
+			}
+			return ik, nil
+		}
+		if !pg.IsUniqueViolation(err) {
+			return nil, errors.Wrap(err, "storing key")
+		}
+		if err.(*pq.Error).Constraint != "mockhsm_alias_key" {
+			// Another process stored the key after we checked.
+			return h.existingKey(ctx, k)
+		}
+	}
+}
+
+// existingKey returns the import result for a key that is already
+// in the HSM, or nil if it isn't there.
+func (h *HSM) existingKey(ctx context.Context, k backupKey) (*ImportedKey, error) {
+	var alias sql.NullString
+	err := h.db.QueryRowContext(ctx, `SELECT alias FROM mockhsm WHERE pub = $1`, []byte(k.Pub)).Scan(&alias)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading existing key")
+	}
+	ik := &ImportedKey{Type: k.Type, Pub: k.Pub, OriginalAlias: k.Alias, Status: KeyExists}
+	if alias.Valid {
+		ik.Alias = &alias.String
+	}
+	return ik, nil
+}
+
+// validate checks that k is a well-formed key
+// whose private key matches its public key.
+func (k backupKey) validate() error {
+	switch k.Type {
+	case "chain_kd":
+		var xprv chainkd.XPrv
+		if len(k.Prv) != len(xprv) {
+			return errors.WithDetail(ErrBadBackup, "invalid chain_kd private key size")
+		}
+		copy(xprv[:], k.Prv)
+		if xpub := xprv.XPub(); !bytes.Equal(xpub[:], k.Pub) {
+			return errors.WithDetail(ErrBadBackup, "chain_kd private key doesn't match public key")
+		}
+	case "ed25519":
+		if len(k.Prv) != ed25519.PrivateKeySize {
+			return errors.WithDetail(ErrBadBackup, "invalid ed25519 private key size")
+		}
+		pub := ed25519.PrivateKey(k.Prv).Public().(ed25519.PublicKey)
+		if !bytes.Equal(pub, k.Pub) {
+			return errors.WithDetail(ErrBadBackup, "ed25519 private key doesn't match public key")
+		}
+	default:
+		return errors.WithDetailf(ErrBadBackup, "unknown key type %q", k.Type)
+	}
+	return nil
+}
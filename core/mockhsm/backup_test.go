@@ -0,0 +1,134 @@
+package mockhsm
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+)
+
+func TestExportImportKeys(t *testing.T) {
+	ctx := context.Background()
+
+	_, db1 := pgtest.NewDB(t, pgtest.SchemaPath)
+	src := newUnlocked(t, db1)
+	xpub1, err := src.XCreate(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub2, err := src.XCreate(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := src.Create(ctx, "block_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = src.ExportKeys(ctx, "", "backup passphrase")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Fatalf("ExportKeys without the HSM passphrase: error = %v, want %v", err, ErrBadPassphrase)
+	}
+	_, err = src.ExportKeys(ctx, "wrong passphrase", "backup passphrase")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Fatalf("ExportKeys with the wrong HSM passphrase: error = %v, want %v", err, ErrBadPassphrase)
+	}
+	backup, err := src.ExportKeys(ctx, testPassphrase, "backup passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The destination already has bob, and a different key named alice
+	// and alice-2.
+	_, db2 := pgtest.NewDB(t, pgtest.SchemaPath)
+	dst := newUnlocked(t, db2)
+	_, err = dst.XCreate(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dst.XCreate(ctx, "alice-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dst.ImportKeys(ctx, backup, "wrong passphrase")
+	if errors.Root(err) != ErrBadBackup {
+		t.Fatalf("ImportKeys error = %v, want %v", err, ErrBadBackup)
+	}
+
+	got, err := dst.ImportKeys(ctx, backup, "backup passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ alias, status string }{
+		{"alice-3", KeyRenamed},
+		{"bob", KeyImported},
+		{"block_key", KeyImported},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("imported %d keys, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Alias == nil || *got[i].Alias != w.alias || got[i].Status != w.status {
+			t.Errorf("key %d: got alias %v status %s, want %s %s", i, got[i].Alias, got[i].Status, w.alias, w.status)
+		}
+	}
+
+	// Importing again changes nothing.
+	got, err = dst.ImportKeys(ctx, backup, "backup passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range got {
+		if k.Status != KeyExists || *k.Alias != want[i].alias {
+			t.Errorf("reimported key %d: got alias %v status %s, want %s %s", i, k.Alias, k.Status, want[i].alias, KeyExists)
+		}
+	}
+
+	msg := []byte("msg")
+	for _, xpub := range []*XPub{xpub1, xpub2} {
+		sig, err := dst.XSign(ctx, xpub.XPub, nil, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !xpub.XPub.Verify(msg, sig) {
+			t.Error("expected verify to succeed")
+		}
+	}
+	_, err = dst.loadEd25519Key(ctx, pub.Pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportBadBackup(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	hsm := newUnlocked(t, db)
+
+	_, err := hsm.ImportKeys(ctx, &Backup{Version: BackupVersion + 1}, "passphrase")
+	if errors.Root(err) != ErrBadBackup {
+		t.Errorf("ImportKeys error = %v, want %v", err, ErrBadBackup)
+	}
+
+	_, err = hsm.ImportKeys(ctx, &Backup{Version: BackupVersion, Salt: []byte{1}, Ciphertext: []byte("garbage")}, "passphrase")
+	if errors.Root(err) != ErrBadBackup {
+		t.Errorf("ImportKeys error = %v, want %v", err, ErrBadBackup)
+	}
+}
+
+func TestBackupKeyValidate(t *testing.T) {
+	k := backupKey{Type: "chain_kd", Pub: make([]byte, 64), Prv: make([]byte, 64)}
+	if errors.Root(k.validate()) != ErrBadBackup {
+		t.Error("expected mismatched chain_kd key to be invalid")
+	}
+	k = backupKey{Type: "ed25519", Pub: make([]byte, 32), Prv: make([]byte, 10)}
+	if errors.Root(k.validate()) != ErrBadBackup {
+		t.Error("expected short ed25519 key to be invalid")
+	}
+	k = backupKey{Type: "rsa"}
+	if errors.Root(k.validate()) != ErrBadBackup {
+		t.Error("expected unknown key type to be invalid")
+	}
+}
@@ -0,0 +1,162 @@
+package mockhsm
+
+import (
+	"context"
+	"crypto/cipher"
+	"database/sql"
+
+	"chain/crypto/kek"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+var (
+	ErrLocked        = errors.New("mockhsm is locked")
+	ErrBadPassphrase = errors.New("incorrect mockhsm passphrase")
+)
+
+// verifierPlaintext is sealed with the key-encryption key when it is
+// first set up, so that later unlocks can detect a wrong passphrase
+// before touching any keys.
+var verifierPlaintext = []byte("chain mockhsm key-encryption key")
+
+// Unlock derives the key-encryption key from passphrase and uses it to
+// encrypt and decrypt private keys from then on. The first call on a
+// database sets the passphrase; later calls must supply the same one
+// or fail with ErrBadPassphrase. Unlock also encrypts any keys that
+// are still stored in plaintext.
+func (h *HSM) Unlock(ctx context.Context, passphrase string) error {
+	if passphrase == "" {
+		return errors.WithDetail(ErrBadPassphrase, "the passphrase must not be empty")
+	}
+	aead, err := h.checkPassphrase(ctx, passphrase)
+	if errors.Root(err) == ErrLocked {
+		aead, err = h.setPassphrase(ctx, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = h.encryptPlaintextKeys(ctx, aead)
+	if err != nil {
+		return err
+	}
+
+	h.cacheMu.Lock()
+	h.kek = aead
+	h.cacheMu.Unlock()
+	return nil
+}
+
+// setPassphrase stores the parameters of a new key-encryption key
+// derived from passphrase and returns it. If another process set the
+// passphrase first, setPassphrase checks passphrase against that one.
+func (h *HSM) setPassphrase(ctx context.Context, passphrase string) (cipher.AEAD, error) {
+	salt, err := kek.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := kek.New(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := kek.Seal(aead, verifierPlaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		INSERT INTO mockhsm_kek (salt, verifier) VALUES ($1, $2)
+		ON CONFLICT (singleton) DO NOTHING
+		RETURNING true
+	`
+	var inserted bool
+	err = h.db.QueryRowContext(ctx, q, salt, verifier).Scan(&inserted)
+	if err == sql.ErrNoRows {
+		return h.checkPassphrase(ctx, passphrase)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "storing key-encryption key parameters")
+	}
+	return aead, nil
+}
+
+// checkPassphrase returns the key-encryption key derived from
+// passphrase, or ErrBadPassphrase if passphrase isn't the one
+// the HSM was first unlocked with.
+func (h *HSM) checkPassphrase(ctx context.Context, passphrase string) (cipher.AEAD, error) {
+	var salt, verifier []byte
+	err := h.db.QueryRowContext(ctx, `SELECT salt, verifier FROM mockhsm_kek`).Scan(&salt, &verifier)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetail(ErrLocked, "no mockhsm passphrase has been set")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading key-encryption key parameters")
+	}
+	aead, err := kek.New(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := kek.Open(aead, verifier, nil); err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return aead, nil
+}
+
+// encryptPlaintextKeys encrypts the private keys stored
+// before the mockhsm encrypted keys at rest.
+func (h *HSM) encryptPlaintextKeys(ctx context.Context, aead cipher.AEAD) error {
+	type row struct{ pub, prv []byte }
+	var rows []row
+	err := pg.ForQueryRows(ctx, h.db, `SELECT pub, prv FROM mockhsm WHERE NOT encrypted`, func(pub, prv []byte) {
+		rows = append(rows, row{pub, prv})
+	})
+	if err != nil {
+		return errors.Wrap(err, "reading plaintext keys")
+	}
+	const q = `UPDATE mockhsm SET prv = $2, encrypted = true WHERE pub = $1 AND NOT encrypted`
+	for _, r := range rows {
+		sealed, err := kek.Seal(aead, r.prv, r.pub)
+		if err != nil {
+			return err
+		}
+		_, err = h.db.ExecContext(ctx, q, r.pub, sealed)
+		if err != nil {
+			return errors.Wrapf(err, "encrypting key %x", r.pub)
+		}
+	}
+	return nil
+}
+
+// aead returns the cipher used to encrypt private keys,
+// or ErrLocked if the HSM hasn't been unlocked.
+// The caller must hold h.cacheMu.
+func (h *HSM) aead() (cipher.AEAD, error) {
+	if h.kek == nil {
+		return nil, errors.WithDetail(ErrLocked, "unlock the mockhsm with its passphrase first")
+	}
+	return h.kek, nil
+}
+
+// seal encrypts the private key prv of pub for storage.
+func (h *HSM) seal(prv, pub []byte) ([]byte, error) {
+	h.cacheMu.Lock()
+	aead, err := h.aead()
+	h.cacheMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return kek.Seal(aead, prv, pub)
+}
+
+// decryptKey returns the plaintext private key stored for pub.
+func decryptKey(aead cipher.AEAD, pub, prv []byte, encrypted bool) ([]byte, error) {
+	if !encrypted {
+		// Written by an older core after this
+		// HSM was unlocked.
+		return prv, nil
+	}
+	b, err := kek.Open(aead, prv, pub)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypting key %x", pub)
+	}
+	return b, nil
+}
@@ -1,9 +1,14 @@
 // Package mockhsm provides a mock HSM for development environments.
 // It is unsafe for use in production.
+//
+// Private keys are stored in the database encrypted with a key derived
+// from an operator passphrase. The HSM starts out locked and can't
+// create keys or sign until it is unlocked with that passphrase.
 package mockhsm
 
 import (
 	"context"
+	"crypto/cipher"
 	"database/sql"
 	"fmt"
 	"strconv"
@@ -33,6 +38,7 @@ type HSM struct {
 	db pg.DB
 
 	cacheMu sync.Mutex
+	kek     cipher.AEAD // nil while locked
 	kdCache map[chainkd.XPub]chainkd.XPrv
 	edCache map[string]ed25519.PrivateKey // ed25519.PublicKeys must be turned into strings before being used as map keys
 }
@@ -66,13 +72,17 @@ func (h *HSM) createChainKDKey(ctx context.Context, alias string, get bool) (*XP
 	if err != nil {
 		return nil, false, err
 	}
+	sealed, err := h.seal(xprv.Bytes(), xpub.Bytes())
+	if err != nil {
+		return nil, false, err
+	}
 	sqlAlias := sql.NullString{String: alias, Valid: alias != ""}
 	var ptrAlias *string
 	if alias != "" {
 		ptrAlias = &alias
 	}
-	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type) VALUES ($1, $2, $3, 'chain_kd')`
-	_, err = h.db.ExecContext(ctx, q, xpub.Bytes(), xprv.Bytes(), sqlAlias)
+	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type, encrypted) VALUES ($1, $2, $3, 'chain_kd', true)`
+	_, err = h.db.ExecContext(ctx, q, xpub.Bytes(), sealed, sqlAlias)
 	if err != nil {
 		if pg.IsUniqueViolation(err) {
 			if !get {
@@ -110,14 +120,18 @@ func (h *HSM) createEd25519Key(ctx context.Context, alias string, get bool) (*Pu
 	if err != nil {
 		return nil, false, err
 	}
+	sealed, err := h.seal(prv, pub)
+	if err != nil {
+		return nil, false, err
+	}
 
 	sqlAlias := sql.NullString{String: alias, Valid: alias != ""}
 	var ptrAlias *string
 	if alias != "" {
 		ptrAlias = &alias
 	}
-	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type) VALUES ($1, $2, $3, 'ed25519')`
-	_, err = h.db.ExecContext(ctx, q, []byte(pub), []byte(prv), sqlAlias)
+	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type, encrypted) VALUES ($1, $2, $3, 'ed25519', true)`
+	_, err = h.db.ExecContext(ctx, q, []byte(pub), sealed, sqlAlias)
 	if err != nil {
 		if pg.IsUniqueViolation(err) {
 			if !get {
@@ -199,18 +213,29 @@ func (h *HSM) loadChainKDKey(ctx context.Context, xpub chainkd.XPub) (xprv chain
 	h.cacheMu.Lock()
 	defer h.cacheMu.Unlock()
 
+	aead, err := h.aead()
+	if err != nil {
+		return xprv, err
+	}
 	if xprv, ok := h.kdCache[xpub]; ok {
 		return xprv, nil
 	}
 
-	var b []byte
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes()).Scan(&b)
+	var (
+		b         []byte
+		encrypted bool
+	)
+	err = h.db.QueryRowContext(ctx, "SELECT prv, encrypted FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes()).Scan(&b, &encrypted)
 	if err == sql.ErrNoRows {
 		return xprv, ErrNoKey
 	}
 	if err != nil {
 		return xprv, err
 	}
+	b, err = decryptKey(aead, xpub.Bytes(), b, encrypted)
+	if err != nil {
+		return xprv, err
+	}
 	copy(xprv[:], b)
 	h.kdCache[xpub] = xprv
 	return xprv, nil
@@ -242,19 +267,33 @@ func (h *HSM) loadEd25519Key(ctx context.Context, pub ed25519.PublicKey) (prv ed
 	h.cacheMu.Lock()
 	defer h.cacheMu.Unlock()
 
+	aead, err := h.aead()
+	if err != nil {
+		return nil, err
+	}
+
 	pubStr := string(pub)
 
 	if prv, ok := h.edCache[pubStr]; ok {
 		return prv, nil
 	}
 
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='ed25519'", []byte(pub)).Scan(&prv)
+	var (
+		b         []byte
+		encrypted bool
+	)
+	err = h.db.QueryRowContext(ctx, "SELECT prv, encrypted FROM mockhsm WHERE pub = $1 AND key_type='ed25519'", []byte(pub)).Scan(&b, &encrypted)
 	if err == sql.ErrNoRows {
 		return prv, ErrNoKey
 	}
 	if err != nil {
 		return prv, err
 	}
+	b, err = decryptKey(aead, pub, b, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	prv = ed25519.PrivateKey(b)
 	h.edCache[pubStr] = prv
 	return prv, nil
 }
@@ -1,12 +1,15 @@
 package mockhsm
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 
 	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
 	"chain/database/pg/pgtest"
 	"chain/errors"
 	"chain/protocol/bc/legacy"
@@ -16,7 +19,7 @@ import (
 func TestMockHSMChainKDKeys(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(t, db)
 	xpub, err := hsm.XCreate(ctx, "")
 	if err != nil {
 		t.Fatal(err)
@@ -59,7 +62,7 @@ func TestMockHSMChainKDKeys(t *testing.T) {
 func TestMockHSMEd25519Keys(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(t, db)
 	pub, err := hsm.Create(ctx, "")
 	if err != nil {
 		t.Fatal(err)
@@ -93,7 +96,7 @@ func TestMockHSMEd25519Keys(t *testing.T) {
 func TestKeyWithAlias(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(t, db)
 	xpub, err := hsm.XCreate(ctx, "some-alias")
 	if err != nil {
 		t.Fatal(err)
@@ -146,7 +149,7 @@ func TestKeyWithAlias(t *testing.T) {
 func TestKeyWithEmptyAlias(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(t, db)
 	for i := 0; i < 2; i++ {
 		_, err := hsm.XCreate(ctx, "")
 		if errors.Root(err) != nil {
@@ -158,7 +161,7 @@ func TestKeyWithEmptyAlias(t *testing.T) {
 func TestKeyOrdering(t *testing.T) {
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(t, db)
 
 	xpub1, err := hsm.XCreate(ctx, "first-key")
 	if err != nil {
@@ -201,7 +204,7 @@ func BenchmarkSign(b *testing.B) {
 
 	_, db := pgtest.NewDB(b, pgtest.SchemaPath)
 	ctx := context.Background()
-	hsm := New(db)
+	hsm := newUnlocked(b, db)
 	xpub, err := hsm.XCreate(ctx, "")
 	if err != nil {
 		b.Fatal(err)
@@ -217,3 +220,91 @@ func BenchmarkSign(b *testing.B) {
 		}
 	}
 }
+
+func TestLocked(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+	xpub, err := newUnlocked(t, db).XCreate(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hsm := New(db)
+	_, err = hsm.XSign(ctx, xpub.XPub, nil, []byte("msg"))
+	if errors.Root(err) != ErrLocked {
+		t.Errorf("XSign error = %v, want %v", err, ErrLocked)
+	}
+	_, err = hsm.Sign(ctx, ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), &legacy.BlockHeader{})
+	if errors.Root(err) != ErrLocked {
+		t.Errorf("Sign error = %v, want %v", err, ErrLocked)
+	}
+	_, err = hsm.XCreate(ctx, "")
+	if errors.Root(err) != ErrLocked {
+		t.Errorf("XCreate error = %v, want %v", err, ErrLocked)
+	}
+
+	err = hsm.Unlock(ctx, "")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Fatalf("Unlock(\"\") error = %v, want %v", err, ErrBadPassphrase)
+	}
+	err = hsm.Unlock(ctx, "wrong passphrase")
+	if err != ErrBadPassphrase {
+		t.Fatalf("Unlock error = %v, want %v", err, ErrBadPassphrase)
+	}
+	err = hsm.Unlock(ctx, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = hsm.XSign(ctx, xpub.XPub, nil, []byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptPlaintextKeys(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+
+	// Store a key the way the mockhsm did before encryption.
+	xprv, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO mockhsm (pub, prv, key_type) VALUES ($1, $2, 'chain_kd')`, xpub.Bytes(), xprv.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hsm := newUnlocked(t, db)
+	var (
+		prv       []byte
+		encrypted bool
+	)
+	err = db.QueryRowContext(ctx, `SELECT prv, encrypted FROM mockhsm WHERE pub = $1`, xpub.Bytes()).Scan(&prv, &encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !encrypted || bytes.Contains(prv, xprv.Bytes()) {
+		t.Fatal("expected the plaintext key to be encrypted")
+	}
+
+	msg := []byte("msg")
+	sig, err := hsm.XSign(ctx, xpub, nil, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !xpub.Verify(msg, sig) {
+		t.Error("expected verify to succeed")
+	}
+}
+
+const testPassphrase = "correct horse battery staple"
+
+func newUnlocked(tb testing.TB, db pg.DB) *HSM {
+	hsm := New(db)
+	err := hsm.Unlock(context.Background(), testPassphrase)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return hsm
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+)
+
+var (
+	errNoSnapshot       = errors.New("no state snapshot available")
+	errSnapshotMismatch = errors.New("state snapshot does not match block")
+)
+
+// outputProof proves that an output is or is not in the set of
+// unspent outputs as of a block. To check it, verify the block
+// header's signature against the consensus program, then verify
+// the proof against the header's AssetsMerkleRoot with
+// patricia.VerifyMembership or patricia.VerifyNonMembership.
+type outputProof struct {
+	OutputID    bc.Hash             `json:"output_id"`
+	Unspent     bool                `json:"unspent"`
+	BlockHeight uint64              `json:"block_height"`
+	BlockHeader *legacy.BlockHeader `json:"block_header"`
+	Proof       struct {
+		Leaf *patriciaPath `json:"leaf,omitempty"`
+		Prev *patriciaPath `json:"prev,omitempty"`
+		Next *patriciaPath `json:"next,omitempty"`
+	} `json:"proof"`
+}
+
+type patriciaPath struct {
+	Item     chainjson.HexBytes `json:"item"`
+	Right    []bool             `json:"right"`
+	Siblings []bc.Hash          `json:"siblings"`
+}
+
+func newPatriciaPath(p *patricia.Path) *patriciaPath {
+	if p == nil {
+		return nil
+	}
+	return &patriciaPath{
+		Item:     p.Item,
+		Right:    append([]bool{}, p.Right...),
+		Siblings: append([]bc.Hash{}, p.Siblings...),
+	}
+}
+
+// getOutputProof is an http handler for getting a proof that an
+// output is or is not unspent, along with the signed header of
+// the block the proof is against. It uses the current state if
+// this core has one, or the latest saved state snapshot if not.
+//
+// POST /get-output-proof
+func (a *API) getOutputProof(ctx context.Context, in struct {
+	OutputID bc.Hash `json:"output_id"`
+}) (*outputProof, error) {
+	block, snapshot := a.chain.State()
+	if block == nil || snapshot == nil {
+		var (
+			height uint64
+			err    error
+		)
+		snapshot, height, err = a.store.LatestSnapshot(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting latest snapshot")
+		}
+		if snapshot == nil || height == 0 {
+			return nil, errors.Wrap(errNoSnapshot)
+		}
+		block, err = a.chain.GetBlock(ctx, height)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting block %d", height)
+		}
+	}
+	if root := snapshot.Tree.RootHash(); root != block.AssetsMerkleRoot {
+		return nil, errors.Wrapf(errSnapshotMismatch, "root %x, block %d", root.Bytes(), block.Height)
+	}
+
+	p := snapshot.Tree.Prove(in.OutputID.Bytes())
+	resp := &outputProof{
+		OutputID:    in.OutputID,
+		Unspent:     p.Leaf != nil,
+		BlockHeight: block.Height,
+		BlockHeader: &block.BlockHeader,
+	}
+	resp.Proof.Leaf = newPatriciaPath(p.Leaf)
+	resp.Proof.Prev = newPatriciaPath(p.Prev)
+	resp.Proof.Next = newPatriciaPath(p.Next)
+	return resp, nil
+}
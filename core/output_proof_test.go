@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestGetOutputProof(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	a := &API{chain: c}
+
+	tx := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	prottest.MakeBlock(t, c, []*legacy.Tx{tx})
+
+	type req struct {
+		OutputID bc.Hash `json:"output_id"`
+	}
+	cases := []struct {
+		id      bc.Hash
+		unspent bool
+	}{
+		{*tx.ResultIds[0], true},
+		{bc.NewHash([32]byte{1}), false},
+	}
+	for _, tc := range cases {
+		resp, err := a.getOutputProof(ctx, req{tc.id})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if resp.Unspent != tc.unspent {
+			t.Errorf("output %x: got unspent %v, want %v", tc.id.Bytes(), resp.Unspent, tc.unspent)
+		}
+
+		// Verify the proof as a counterparty would, from the JSON response.
+		j, err := json.Marshal(resp)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		var got struct {
+			BlockHeader legacy.BlockHeader `json:"block_header"`
+			Proof       struct {
+				Leaf, Prev, Next *patriciaPath
+			} `json:"proof"`
+		}
+		err = json.Unmarshal(j, &got)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		toPath := func(p *patriciaPath) *patricia.Path {
+			if p == nil {
+				return nil
+			}
+			return &patricia.Path{Item: p.Item, Right: p.Right, Siblings: p.Siblings}
+		}
+		proof := &patricia.Proof{
+			Leaf: toPath(got.Proof.Leaf),
+			Prev: toPath(got.Proof.Prev),
+			Next: toPath(got.Proof.Next),
+		}
+		root := got.BlockHeader.AssetsMerkleRoot
+		var ok bool
+		if tc.unspent {
+			ok = patricia.VerifyMembership(root, tc.id.Bytes(), proof)
+		} else {
+			ok = patricia.VerifyNonMembership(root, tc.id.Bytes(), proof)
+		}
+		if !ok {
+			t.Errorf("output %x: proof did not verify", tc.id.Bytes())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"chain/core/generator"
+	"chain/core/txbuilder"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httperror"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Statuses of a pending transaction.
+const (
+	pendingValid   = "valid"
+	pendingInvalid = "invalid"
+)
+
+// pendingTx describes a transaction in the generator's
+// pending tx pool.
+type pendingTx struct {
+	ID          bc.Hash             `json:"id"`
+	Transaction *legacy.Tx          `json:"raw_transaction"`
+	SubmittedAt time.Time           `json:"submitted_at"`
+	Age         json.Duration       `json:"age"`
+	Status      string              `json:"status"`
+	Error       *httperror.Response `json:"validation_error,omitempty"`
+}
+
+func newPendingTx(p *generator.PendingTx, now time.Time) *pendingTx {
+	tx := &pendingTx{
+		ID:          p.Tx.ID,
+		Transaction: p.Tx,
+		SubmittedAt: p.SubmittedAt,
+		Age:         json.Duration{Duration: now.Sub(p.SubmittedAt)},
+		Status:      pendingValid,
+	}
+	if p.Err != nil {
+		tx.Status = pendingInvalid
+		tx.Error = formatRejection(p.Err)
+	}
+	return tx
+}
+
+// formatRejection formats the reason a tx failed validation
+// as a "Transaction rejected" error response.
+func formatRejection(err error) *httperror.Response {
+	resp := errorFormatter.Format(errors.Sub(txbuilder.ErrRejected, err))
+	if resp.Detail == "" {
+		resp.Detail = err.Error()
+	}
+	return &resp
+}
+
+// listPendingTxs is an http handler for listing the transactions
+// submitted to the generator but not yet in a block, in the order
+// they were submitted. Each is marked valid or invalid according
+// to whether it would be included in a block made now.
+//
+// POST /list-pending-transactions
+func (a *API) listPendingTxs(ctx context.Context, in requestQuery) (page, error) {
+	if a.generator == nil {
+		return page{}, errNoGenerator
+	}
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	} else if limit < 0 {
+		return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid page_size %d", limit)
+	}
+	var after uint64
+	if in.After != "" {
+		var err error
+		after, err = strconv.ParseUint(in.After, 10, 64)
+		if err != nil {
+			return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid `after` cursor %q", in.After)
+		}
+	}
+
+	pending, err := a.generator.Pending(ctx, after, limit)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing pending txs")
+	}
+
+	now := time.Now()
+	txs := make([]*pendingTx, 0, len(pending))
+	for _, p := range pending {
+		txs = append(txs, newPendingTx(p, now))
+	}
+	out := in
+	if len(pending) > 0 {
+		out.After = strconv.FormatUint(pending[len(pending)-1].Seq, 10)
+	}
+	return page{
+		Items:    httpjson.Array(txs),
+		LastPage: len(pending) < limit,
+		Next:     out,
+	}, nil
+}
+
+// POST /get-pending-transaction
+func (a *API) getPendingTx(ctx context.Context, in struct {
+	ID bc.Hash `json:"id"`
+}) (*pendingTx, error) {
+	if a.generator == nil {
+		return nil, errNoGenerator
+	}
+	p, err := a.generator.GetPending(ctx, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newPendingTx(p, time.Now()), nil
+}
@@ -3,11 +3,16 @@ package core
 import (
 	"context"
 	"math"
+	"strconv"
+	"time"
 
+	"chain/core/account"
 	"chain/core/query"
 	"chain/core/query/filter"
+	"chain/core/txfeed"
 	"chain/errors"
 	"chain/net/http/httpjson"
+	"chain/protocol"
 )
 
 // listAccounts is an http handler for listing accounts matching
@@ -81,15 +86,13 @@ func (a *API) listBalances(ctx context.Context, in requestQuery) (result page, e
 		sumBy = append(sumBy, f)
 	}
 
-	timestampMS := in.TimestampMS
-	if timestampMS == 0 {
-		timestampMS = math.MaxInt64
-	} else if timestampMS > math.MaxInt64 {
-		return result, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
+	timestampMS, err := a.pointInTime(in)
+	if err != nil {
+		return result, err
 	}
 
 	// TODO(jackson): paginate this endpoint.
-	balances, err := a.indexer.Balances(ctx, in.Filter, in.FilterParams, sumBy, timestampMS)
+	balances, err := a.indexer.Balances(ctx, in.Filter, in.FilterParams, sumBy, timestampMS, in.BlockHeight)
 	if err != nil {
 		return result, err
 	}
@@ -100,6 +103,58 @@ func (a *API) listBalances(ctx context.Context, in requestQuery) (result page, e
 	return result, nil
 }
 
+// listAggregates is an http handler for grouping the transactions or
+// unspent outputs matching a filter and computing aggregates, such as
+// count() or sum(amount), over each group.
+//
+// POST /list-aggregates
+func (a *API) listAggregates(ctx context.Context, in requestQuery) (result page, err error) {
+	var groupBy []filter.Field
+	for _, field := range in.SumBy {
+		f, err := filter.ParseField(field)
+		if err != nil {
+			return result, err
+		}
+		groupBy = append(groupBy, f)
+	}
+
+	if len(in.Aggregates) == 0 {
+		in.Aggregates = []string{"count()"}
+	}
+	var aggs []filter.Aggregate
+	for _, s := range in.Aggregates {
+		agg, err := filter.ParseAggregate(s)
+		if err != nil {
+			return result, err
+		}
+		aggs = append(aggs, agg)
+	}
+
+	var items []interface{}
+	switch in.Index {
+	case "transactions":
+		items, err = a.indexer.TransactionAggregates(ctx, in.Filter, in.FilterParams, groupBy, aggs)
+	case "unspent_outputs":
+		var timestampMS uint64
+		timestampMS, err = a.pointInTime(in)
+		if err != nil {
+			return result, err
+		}
+		items, err = a.indexer.OutputAggregates(ctx, in.Filter, in.FilterParams, groupBy, aggs, timestampMS, in.BlockHeight)
+	default:
+		return result, errors.WithDetailf(httpjson.ErrBadRequest, "invalid index %q", in.Index)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// TODO(jackson): paginate this endpoint.
+	result.Items = httpjson.Array(items)
+	result.LastPage = true
+	result.Next = in
+	return result, nil
+}
+
 // listTransactions is an http handler for listing transactions matching
 // an index or an ad-hoc filter.
 //
@@ -177,6 +232,107 @@ func (a *API) listTxFeeds(ctx context.Context, in requestQuery) (page, error) {
 	}, nil
 }
 
+// listContractTemplates is an http handler for listing the
+// contract templates stored in the core.
+//
+// POST /list-contract-templates
+func (a *API) listContractTemplates(ctx context.Context, in requestQuery) (page, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+
+	tpls, after, err := a.contracts.List(ctx, in.After, limit)
+	if err != nil {
+		return page{}, errors.Wrap(err, "running contract templates query")
+	}
+
+	out := in
+	out.After = after
+	return page{
+		Items:    httpjson.Array(tpls),
+		LastPage: len(tpls) < limit,
+		Next:     out,
+	}, nil
+}
+
+// listReservations is an http handler for listing the unexpired
+// utxo reservations, such as those held by transaction templates.
+//
+// POST /list-reservations
+func (a *API) listReservations(ctx context.Context, in requestQuery) (page, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	} else if limit < 0 {
+		return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid page_size %d", limit)
+	}
+
+	var after uint64
+	if in.After != "" {
+		var err error
+		after, err = strconv.ParseUint(in.After, 10, 64)
+		if err != nil {
+			return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid `after` cursor %q", in.After)
+		}
+	}
+
+	f := account.ReservationFilter{
+		AccountID: in.AccountID,
+		AssetID:   in.AssetID,
+	}
+	if in.ExpiresBeforeMS > 0 {
+		f.ExpiresBefore = time.Unix(0, int64(in.ExpiresBeforeMS)*int64(time.Millisecond))
+	}
+	reservations, err := a.accounts.ListReservations(ctx, f, after, limit)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing reservations")
+	}
+
+	out := in
+	if len(reservations) > 0 {
+		out.After = strconv.FormatUint(reservations[len(reservations)-1].ID, 10)
+	}
+	return page{
+		Items:    httpjson.Array(reservations),
+		LastPage: len(reservations) < limit,
+		Next:     out,
+	}, nil
+}
+
+// listTxFeedDeliveries is an http handler for listing the webhook
+// deliveries of a txfeed, most recent first.
+//
+// POST /list-transaction-feed-deliveries
+func (a *API) listTxFeedDeliveries(ctx context.Context, in requestQuery) (page, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+	switch in.Status {
+	case "", txfeed.StatusDelivered, txfeed.StatusFailed:
+	default:
+		return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid status %q", in.Status)
+	}
+
+	feed, err := a.txFeeds.Find(ctx, in.ID, in.Alias)
+	if err != nil {
+		return page{}, err
+	}
+	deliveries, after, err := a.txFeeds.Deliveries(ctx, feed.ID, in.Status, in.After, limit)
+	if err != nil {
+		return page{}, errors.Wrap(err, "running deliveries query")
+	}
+
+	out := in
+	out.After = after
+	return page{
+		Items:    httpjson.Array(deliveries),
+		LastPage: len(deliveries) < limit,
+		Next:     out,
+	}, nil
+}
+
 // POST /list-unspent-outputs
 func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result page, err error) {
 	limit := in.PageSize
@@ -192,13 +348,11 @@ func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result p
 		}
 	}
 
-	timestampMS := in.TimestampMS
-	if timestampMS == 0 {
-		timestampMS = math.MaxInt64
-	} else if timestampMS > math.MaxInt64 {
-		return result, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
+	timestampMS, err := a.pointInTime(in)
+	if err != nil {
+		return result, err
 	}
-	outputs, nextAfter, err := a.indexer.Outputs(ctx, in.Filter, in.FilterParams, timestampMS, after, limit)
+	outputs, nextAfter, err := a.indexer.Outputs(ctx, in.Filter, in.FilterParams, timestampMS, in.BlockHeight, after, limit)
 	if err != nil {
 		return result, errors.Wrap(err, "querying outputs")
 	}
@@ -211,3 +365,26 @@ func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result p
 		Next:     outQuery,
 	}, nil
 }
+
+// pointInTime validates the timestamp and block height of a
+// point-in-time query and returns the timestamp to use when no block
+// height is given.
+func (a *API) pointInTime(in requestQuery) (timestampMS uint64, err error) {
+	if in.BlockHeight > 0 {
+		if in.TimestampMS != 0 {
+			return 0, errors.WithDetail(httpjson.ErrBadRequest, "timestamp and block_height cannot both be set")
+		}
+		if in.BlockHeight > a.chain.Height() {
+			return 0, errors.WithDetailf(protocol.ErrTheDistantFuture, "block_height %d is after the latest block", in.BlockHeight)
+		}
+		return 0, nil
+	}
+
+	timestampMS = in.TimestampMS
+	if timestampMS == 0 {
+		timestampMS = math.MaxInt64
+	} else if timestampMS > math.MaxInt64 {
+		return 0, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
+	}
+	return timestampMS, nil
+}
@@ -0,0 +1,151 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/lib/pq"
+
+	"chain/core/query/filter"
+	"chain/errors"
+)
+
+// TransactionAggregates performs an aggregate query against the
+// annotated transactions. Transactions matching the filter are
+// grouped by the groupBy fields and each of aggs is evaluated over
+// every group.
+func (ind *Indexer) TransactionAggregates(ctx context.Context, filt string, vals []interface{}, groupBy []filter.Field, aggs []filter.Aggregate) ([]interface{}, error) {
+	return ind.aggregates(ctx, transactionsTable, filt, vals, groupBy, aggs, func(vals []interface{}) (string, []interface{}) {
+		return "", vals
+	})
+}
+
+// OutputAggregates performs an aggregate query against the annotated
+// outputs that were unspent at timestampMS, or, if blockHeight is
+// nonzero, at blockHeight.
+func (ind *Indexer) OutputAggregates(ctx context.Context, filt string, vals []interface{}, groupBy []filter.Field, aggs []filter.Aggregate, timestampMS, blockHeight uint64) ([]interface{}, error) {
+	return ind.aggregates(ctx, outputsTable, filt, vals, groupBy, aggs, func(vals []interface{}) (string, []interface{}) {
+		return spanCondition(vals, timestampMS, blockHeight)
+	})
+}
+
+func (ind *Indexer) aggregates(ctx context.Context, tbl *filter.SQLTable, filt string, vals []interface{}, groupBy []filter.Field, aggs []filter.Aggregate, cond func([]interface{}) (string, []interface{})) ([]interface{}, error) {
+	p, err := filter.Parse(filt, tbl, vals)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != p.Parameters {
+		return nil, ErrParameterCountMismatch
+	}
+	expr, err := filter.AsSQL(p, tbl, vals)
+	if err != nil {
+		return nil, err
+	}
+	extra, vals := cond(vals)
+	queryStr, err := constructAggregatesQuery(tbl, expr, extra, groupBy, aggs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ind.db.QueryContext(ctx, queryStr, vals...)
+	if err != nil {
+		return nil, errors.Wrap(err, "executing aggregates query")
+	}
+	defer rows.Close()
+
+	var results []interface{}
+	for rows.Next() {
+		scanArguments := make([]interface{}, 0, len(groupBy)+len(aggs))
+		for range groupBy {
+			scanArguments = append(scanArguments, new(*string))
+		}
+		for range aggs {
+			// Sums of bigints can exceed 64 bits, so keep the
+			// numeric value's decimal representation.
+			scanArguments = append(scanArguments, new(*json.Number))
+		}
+		err := rows.Scan(scanArguments...)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning aggregate row")
+		}
+
+		// This struct enforces JSON field ordering in API output.
+		item := struct {
+			SumBy      map[string]interface{} `json:"sum_by,omitempty"`
+			Aggregates map[string]interface{} `json:"aggregates"`
+		}{
+			Aggregates: make(map[string]interface{}, len(aggs)),
+		}
+		if len(groupBy) > 0 {
+			item.SumBy = make(map[string]interface{}, len(groupBy))
+			for i, f := range groupBy {
+				item.SumBy[f.String()] = scanArguments[i]
+			}
+		}
+		for i, a := range aggs {
+			item.Aggregates[a.String()] = scanArguments[len(groupBy)+i]
+		}
+		results = append(results, item)
+	}
+	return results, errors.Wrap(rows.Err())
+}
+
+func constructAggregatesQuery(tbl *filter.SQLTable, expr, extra string, groupBy []filter.Field, aggs []filter.Aggregate) (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString("SELECT ")
+	for i, field := range groupBy {
+		fieldSQL, err := filter.FieldAsSQL(tbl, field)
+		if err != nil {
+			return "", err
+		}
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(fieldSQL)
+	}
+	for i, a := range aggs {
+		aggSQL, err := filter.AggregateAsSQL(tbl, a)
+		if err != nil {
+			return "", err
+		}
+		if i != 0 || len(groupBy) > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(aggSQL)
+	}
+	buf.WriteString(" FROM ")
+	buf.WriteString(pq.QuoteIdentifier(tbl.Name))
+	buf.WriteString(" AS ")
+	buf.WriteString(tbl.Alias)
+
+	switch {
+	case expr != "" && extra != "":
+		buf.WriteString(" WHERE (")
+		buf.WriteString(expr)
+		buf.WriteString(") AND ")
+		buf.WriteString(extra)
+	case expr != "":
+		buf.WriteString(" WHERE ")
+		buf.WriteString(expr)
+	case extra != "":
+		buf.WriteString(" WHERE ")
+		buf.WriteString(extra)
+	}
+
+	if len(groupBy) > 0 {
+		var cols bytes.Buffer
+		for i := range groupBy {
+			if i != 0 {
+				cols.WriteString(", ")
+			}
+			cols.WriteString(strconv.Itoa(i + 1)) // 1-indexed
+		}
+		buf.WriteString(" GROUP BY ")
+		buf.Write(cols.Bytes())
+		buf.WriteString(" ORDER BY ")
+		buf.Write(cols.Bytes())
+	}
+	return buf.String(), nil
+}
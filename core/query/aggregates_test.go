@@ -0,0 +1,88 @@
+package query
+
+import (
+	"testing"
+
+	"chain/core/query/filter"
+)
+
+func TestConstructAggregatesQuery(t *testing.T) {
+	testCases := []struct {
+		tbl        *filter.SQLTable
+		predicate  string
+		extra      string
+		groupBy    []string
+		aggregates []string
+		wantQuery  string
+	}{
+		{
+			tbl:        transactionsTable,
+			aggregates: []string{"count()"},
+			wantQuery:  `SELECT COUNT(*) FROM "annotated_txs" AS txs`,
+		},
+		{
+			tbl:        transactionsTable,
+			predicate:  "is_local = 'yes'",
+			groupBy:    []string{"day(timestamp)"},
+			aggregates: []string{"count()", "max(block_height)"},
+			wantQuery:  `SELECT date_trunc('day', txs."timestamp"), COUNT(*), MAX(txs."block_height") FROM "annotated_txs" AS txs WHERE txs."local" = 'yes' GROUP BY 1 ORDER BY 1`,
+		},
+		{
+			tbl:        outputsTable,
+			predicate:  "account_alias = 'alice'",
+			extra:      "timespan @> $1::int8",
+			groupBy:    []string{"asset_id", "hour(timestamp)"},
+			aggregates: []string{"sum(amount)", "min(amount)"},
+			wantQuery:  ``, // outputs have no timestamp attribute
+		},
+		{
+			tbl:        outputsTable,
+			predicate:  "account_alias = 'alice'",
+			extra:      "timespan @> $1::int8",
+			groupBy:    []string{"asset_id", "account_tags.region"},
+			aggregates: []string{"sum(amount)", "min(amount)"},
+			wantQuery:  `SELECT encode(out."asset_id", 'hex'), out."account_tags"->>'region', SUM(out."amount"), MIN(out."amount") FROM "annotated_outputs" AS out WHERE (out."account_alias" = 'alice') AND timespan @> $1::int8 GROUP BY 1, 2 ORDER BY 1, 2`,
+		},
+	}
+
+	for i, tc := range testCases {
+		p, err := filter.Parse(tc.predicate, tc.tbl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expr, err := filter.AsSQL(p, tc.tbl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var groupBy []filter.Field
+		for _, s := range tc.groupBy {
+			f, err := filter.ParseField(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			groupBy = append(groupBy, f)
+		}
+		var aggs []filter.Aggregate
+		for _, s := range tc.aggregates {
+			a, err := filter.ParseAggregate(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			aggs = append(aggs, a)
+		}
+
+		query, err := constructAggregatesQuery(tc.tbl, expr, tc.extra, groupBy, aggs)
+		if tc.wantQuery == "" {
+			if err == nil {
+				t.Errorf("case %d: got query %s, want error", i, query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != tc.wantQuery {
+			t.Errorf("case %d: got\n%s\nwant\n%s", i, query, tc.wantQuery)
+		}
+	}
+}
@@ -66,11 +66,12 @@ type AnnotatedOutput struct {
 }
 
 type AnnotatedAccount struct {
-	ID     string           `json:"id"`
-	Alias  string           `json:"alias,omitempty"`
-	Keys   []*AccountKey    `json:"keys"`
-	Quorum int              `json:"quorum"`
-	Tags   *json.RawMessage `json:"tags"`
+	ID          string           `json:"id"`
+	Alias       string           `json:"alias,omitempty"`
+	Keys        []*AccountKey    `json:"keys"`
+	Quorum      int              `json:"quorum"`
+	Tags        *json.RawMessage `json:"tags"`
+	IsWatchOnly Bool             `json:"is_watch_only"`
 }
 
 type AccountKey struct {
@@ -3,7 +3,6 @@ package query
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"strconv"
 
 	"github.com/lib/pq"
@@ -13,7 +12,9 @@ import (
 )
 
 // Balances performs a balances query against the annotated_outputs.
-func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{}, sumBy []filter.Field, timestampMS uint64) ([]interface{}, error) {
+// If blockHeight is nonzero, balances are computed as of that block
+// and timestampMS is ignored.
+func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{}, sumBy []filter.Field, timestampMS, blockHeight uint64) ([]interface{}, error) {
 	p, err := filter.Parse(filt, outputsTable, vals)
 	if err != nil {
 		return nil, err
@@ -25,7 +26,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 	if err != nil {
 		return nil, err
 	}
-	queryStr, queryArgs, err := constructBalancesQuery(expr, vals, sumBy, timestampMS)
+	queryStr, queryArgs, err := constructBalancesQuery(expr, vals, sumBy, timestampMS, blockHeight)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +70,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 	return balances, errors.Wrap(rows.Err())
 }
 
-func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Field, timestampMS uint64) (string, []interface{}, error) {
+func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Field, timestampMS, blockHeight uint64) (string, []interface{}, error) {
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT COALESCE(SUM(amount), 0)")
@@ -91,9 +92,9 @@ func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Fiel
 		buf.WriteString(") AND ")
 	}
 
-	vals = append(vals, timestampMS)
-	timestampValIndex := len(vals)
-	buf.WriteString(fmt.Sprintf("timespan @> $%d::int8", timestampValIndex))
+	var spanCond string
+	spanCond, vals = spanCondition(vals, timestampMS, blockHeight)
+	buf.WriteString(spanCond)
 
 	if len(sumBy) > 0 {
 		buf.WriteString(" GROUP BY ")
@@ -13,6 +13,7 @@ func TestConstructBalancesQuery(t *testing.T) {
 		predicate  string
 		sumBy      []string
 		values     []interface{}
+		height     uint64
 		wantQuery  string
 		wantValues []interface{}
 	}{
@@ -42,6 +43,14 @@ func TestConstructBalancesQuery(t *testing.T) {
 			wantQuery:  `SELECT COALESCE(SUM(amount), 0), out."asset_tags"->>'currency' FROM "annotated_outputs" AS out WHERE (out."account_id" = $1) AND timespan @> $2::int8 GROUP BY 2`,
 			wantValues: []interface{}{`foo`, now},
 		},
+		{
+			predicate:  "account_id = $1",
+			sumBy:      []string{"asset_id"},
+			values:     []interface{}{"abc"},
+			height:     7,
+			wantQuery:  `SELECT COALESCE(SUM(amount), 0), encode(out."asset_id", 'hex') FROM "annotated_outputs" AS out WHERE (out."account_id" = $1) AND heightspan @> $2::int8 GROUP BY 2`,
+			wantValues: []interface{}{`abc`, uint64(7)},
+		},
 	}
 
 	for i, tc := range testCases {
@@ -62,7 +71,7 @@ func TestConstructBalancesQuery(t *testing.T) {
 			fields = append(fields, f)
 		}
 
-		query, values, err := constructBalancesQuery(expr, tc.values, fields, now)
+		query, values, err := constructBalancesQuery(expr, tc.values, fields, now, tc.height)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chain/errors"
+)
+
+// aggregateFuncs are the functions accepted by ParseAggregate.
+// count takes no argument; the rest take an integer expression.
+var aggregateFuncs = map[string]string{
+	"count": "COUNT(*)",
+	"sum":   "SUM(%s)",
+	"min":   "MIN(%s)",
+	"max":   "MAX(%s)",
+}
+
+// timeBuckets are the functions accepted by ParseField for
+// grouping a timestamp attribute into fixed-size intervals.
+var timeBuckets = map[string]bool{
+	"hour":  true,
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// Aggregate is an aggregate function, such as count() or
+// sum(amount), evaluated over each group of rows in a query.
+type Aggregate struct {
+	fn  string
+	arg expr // nil for count()
+}
+
+func (a Aggregate) String() string {
+	if a.arg == nil {
+		return a.fn + "()"
+	}
+	return a.fn + "(" + a.arg.String() + ")"
+}
+
+// ParseAggregate parses an aggregate expression of the form
+// fn "(" [expr] ")", where fn is one of count, sum, min or max.
+// The argument is typechecked when the aggregate is translated
+// to SQL, since that is when its table is known.
+func ParseAggregate(s string) (a Aggregate, err error) {
+	defer func() {
+		r := recover()
+		if perr, ok := r.(parseError); ok {
+			err = errors.WithDetail(ErrBadFilter, perr.Error())
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	p := newParser([]byte(s))
+	a.fn = p.lit
+	p.parseTok(tokIdent)
+	p.parseLit("(")
+	if p.lit != ")" {
+		a.arg = parseExpr(p)
+	}
+	p.parseLit(")")
+	p.parseTok(tokEOF)
+
+	if _, ok := aggregateFuncs[a.fn]; !ok {
+		return a, errors.WithDetailf(ErrBadFilter, "unknown aggregate function: %s", a.fn)
+	}
+	if a.fn == "count" && a.arg != nil {
+		return a, errors.WithDetail(ErrBadFilter, "count takes no arguments")
+	}
+	if a.fn != "count" && a.arg == nil {
+		return a, errors.WithDetailf(ErrBadFilter, "%s requires an argument", a.fn)
+	}
+	return a, nil
+}
+
+// AggregateAsSQL type checks a against tbl and returns a SQL
+// representation of it.
+func AggregateAsSQL(tbl *SQLTable, a Aggregate) (q string, err error) {
+	defer func() {
+		r := recover()
+		if e, ok := r.(error); ok {
+			err = e
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	if a.arg == nil {
+		return aggregateFuncs[a.fn], nil
+	}
+
+	selectorTypes := make(map[string]Type)
+	typ, err := typeCheckExpr(a.arg, tbl, nil, selectorTypes)
+	if err != nil {
+		return "", errors.WithDetail(ErrBadFilter, err.Error())
+	}
+	ok, err := assertType(a.arg, typ, Integer, selectorTypes)
+	if err != nil {
+		return "", errors.WithDetail(ErrBadFilter, err.Error())
+	}
+	if !ok {
+		return "", errors.WithDetailf(ErrBadFilter, "%s expects an integer argument, got %s", a.fn, typ)
+	}
+
+	b := &sqlBuilder{baseTbl: tbl, selectorTypes: selectorTypes}
+	err = asSQL(&sqlContext{sqlBuilder: b, tbl: tbl}, a.arg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(aggregateFuncs[a.fn], b.buf.String()), nil
+}
+
+// bucketAsSQL returns a SQL representation of a time bucket field,
+// such as day(timestamp).
+func bucketAsSQL(tbl *SQLTable, e envExpr) (string, error) {
+	attr, ok := e.expr.(attrExpr)
+	if !ok {
+		return "", errors.WithDetailf(ErrBadFilter, "%s expects a timestamp attribute", e.ident)
+	}
+	col, ok := tbl.Columns[attr.attr]
+	if !ok {
+		return "", errors.WithDetailf(ErrBadFilter, "invalid attribute: %s", attr.attr)
+	}
+	if col.SQLType != SQLTimestamp {
+		return "", errors.WithDetailf(ErrBadFilter, "%s expects a timestamp attribute, got %s", e.ident, attr.attr)
+	}
+	return fmt.Sprintf("date_trunc('%s', %s.%s)", e.ident, tbl.Alias, pq.QuoteIdentifier(col.Name)), nil
+}
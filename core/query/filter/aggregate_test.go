@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"testing"
+
+	"chain/errors"
+)
+
+func TestAggregateAsSQL(t *testing.T) {
+	testCases := []struct {
+		tbl *SQLTable
+		agg string
+		sql string
+	}{
+		{tbl: inputsSQLTable, agg: `count()`, sql: `COUNT(*)`},
+		{tbl: inputsSQLTable, agg: `sum(amount)`, sql: `SUM(inp."amount")`},
+		{tbl: inputsSQLTable, agg: `max( amount )`, sql: `MAX(inp."amount")`},
+		{tbl: inputsSQLTable, agg: `min(account_tags.limit)`, sql: `MIN((inp."account_tags"->>'limit')::bigint)`},
+		{tbl: transactionsSQLTable, agg: `max(position)`, sql: `MAX(txs."position"::bigint)`},
+	}
+
+	for _, tc := range testCases {
+		a, err := ParseAggregate(tc.agg)
+		if err != nil {
+			t.Errorf("ParseAggregate(%s) error = %s", tc.agg, err)
+			continue
+		}
+		got, err := AggregateAsSQL(tc.tbl, a)
+		if err != nil {
+			t.Errorf("AggregateAsSQL(%s) error = %s", tc.agg, err)
+			continue
+		}
+		if got != tc.sql {
+			t.Errorf("AggregateAsSQL(%s) = %s, want %s", tc.agg, got, tc.sql)
+		}
+	}
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	testCases := []string{
+		``,
+		`amount`,
+		`avg(amount)`,
+		`count(amount)`,
+		`sum()`,
+		`sum(amount`,
+		`sum(amount) x`,
+		`sum(a)`,            // string attribute
+		`sum(asset_id)`,     // string attribute
+		`max(account_tags)`, // object attribute
+		`sum(amount = 1)`,
+		`min(nonexistent)`,
+	}
+
+	for _, tc := range testCases {
+		a, err := ParseAggregate(tc)
+		if err == nil {
+			_, err = AggregateAsSQL(inputsSQLTable, a)
+		}
+		if errors.Root(err) != ErrBadFilter {
+			t.Errorf("aggregate %q: got error %v, want ErrBadFilter", tc, err)
+		}
+	}
+}
@@ -40,5 +40,15 @@ expression as a whole is true.
 Filters are statically type-checked: if a subexpression doesn't have
 the appropriate type, Parse will return an error.
 
+Queries that group their results accept fields: identifiers and
+selectors such as 'asset_alias' or 'reference_data.type', and time
+buckets of timestamp attributes such as 'day(timestamp)'. The
+supported buckets are hour, day, week and month.
+
+Aggregate expressions reduce each group to a single value. They
+have the forms 'count()', 'sum(expr)', 'min(expr)' and 'max(expr)',
+where expr must have type int. Aggregates are type-checked against
+the queried table in the same way as predicates.
+
 */
 package filter
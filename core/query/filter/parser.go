@@ -53,7 +53,8 @@ func Parse(predicate string, tbl *SQLTable, vals []interface{}) (p Predicate, er
 }
 
 // Field is a type for simple expressions that simply access an attribute of
-// the queried object. They're used for GROUP BYs.
+// the queried object, or that truncate a timestamp attribute to a time
+// bucket such as day(timestamp). They're used for GROUP BYs.
 type Field struct {
 	expr expr
 }
@@ -62,7 +63,8 @@ func (f Field) String() string {
 	return f.expr.String()
 }
 
-// ParseField parses a field expression (either an attrExpr or a selectorExpr).
+// ParseField parses a field expression (either an attrExpr, a selectorExpr
+// or a time bucket of an attrExpr).
 func ParseField(s string) (f Field, err error) {
 	expr, _, err := parse(s)
 	if err != nil {
@@ -72,9 +74,14 @@ func ParseField(s string) (f Field, err error) {
 		return f, errors.WithDetail(ErrBadFilter, "empty field expression")
 	}
 
-	switch expr.(type) {
+	switch e := expr.(type) {
 	case attrExpr, selectorExpr:
 		return Field{expr: expr}, nil
+	case envExpr:
+		if timeBuckets[e.ident] {
+			return Field{expr: expr}, nil
+		}
+		return f, errors.WithDetailf(ErrBadFilter, "unknown time bucket: %s", e.ident)
 	default:
 		return f, errors.WithDetailf(ErrBadFilter, "%q is not a valid field expression", s)
 	}
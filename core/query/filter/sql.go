@@ -37,6 +37,9 @@ func AsSQL(p Predicate, tbl *SQLTable, values []interface{}) (q string, err erro
 
 // FieldAsSQL returns a SQL representation of the field.
 func FieldAsSQL(tbl *SQLTable, f Field) (string, error) {
+	if e, ok := f.expr.(envExpr); ok {
+		return bucketAsSQL(tbl, e)
+	}
 	path := jsonbPath(f.expr)
 
 	base, rest := path[0], path[1:]
@@ -54,7 +57,7 @@ func FieldAsSQL(tbl *SQLTable, f Field) (string, error) {
 	}
 	buf.WriteString(tbl.Alias)
 	buf.WriteRune('.')
-	buf.WriteString(pq.QuoteIdentifier(base))
+	buf.WriteString(pq.QuoteIdentifier(col.Name))
 	if col.SQLType == SQLBytea {
 		buf.WriteString(", 'hex')")
 	}
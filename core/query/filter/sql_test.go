@@ -37,6 +37,7 @@ var (
 			"ref":      {Name: "ref", Type: Object, SQLType: SQLJSONB},
 			"position": {Name: "position", Type: Integer, SQLType: SQLInteger},
 			"is_local": {Name: "local", Type: Bool, SQLType: SQLBool},
+			"time":     {Name: "timestamp", Type: String, SQLType: SQLTimestamp},
 		},
 		ForeignKeys: map[string]*SQLForeignKey{
 			"inputs":  {Table: inputsSQLTable, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
@@ -54,6 +55,8 @@ func TestFieldAsSQL(t *testing.T) {
 		{tbl: inputsSQLTable, field: `a`, sql: `inp."a"`},
 		{tbl: inputsSQLTable, field: `asset_id`, sql: `encode(inp."asset_id", 'hex')`},
 		{tbl: transactionsSQLTable, field: `ref.buyer.address.state`, sql: `txs."ref"->'buyer'->'address'->>'state'`},
+		{tbl: transactionsSQLTable, field: `is_local`, sql: `txs."local"`},
+		{tbl: transactionsSQLTable, field: `day(time)`, sql: `date_trunc('day', txs."timestamp")`},
 	}
 
 	for _, tc := range testCases {
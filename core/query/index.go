@@ -273,12 +273,13 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash,
 			timespan, output_id, type, purpose, asset_id, asset_alias, asset_definition,
 			asset_tags, asset_local, amount, account_id, account_alias, account_tags,
-			control_program, reference_data, local)
+			control_program, reference_data, local, heightspan)
 		SELECT $1, tx_pos, output_index, tx_hash,
 		CASE WHEN type='retire' THEN int8range($5, $5) ELSE int8range($5, NULL) END,
 		output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags,
 		asset_local, amount, account_id, account_alias, account_tags, control_program,
-		reference_data, local
+		reference_data, local,
+		CASE WHEN type='retire' THEN int8range($1, $1) ELSE int8range($1, NULL) END
 		FROM utxos
 		ON CONFLICT (block_height, tx_pos, output_index) DO NOTHING;
 	`
@@ -294,9 +295,10 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 	}
 
 	const updateQ = `
-		UPDATE annotated_outputs SET timespan = INT8RANGE(LOWER(timespan), $1)
-		WHERE (output_id) IN (SELECT unnest($2::bytea[]))
+		UPDATE annotated_outputs
+		SET timespan = INT8RANGE(LOWER(timespan), $1), heightspan = INT8RANGE(LOWER(heightspan), $2)
+		WHERE (output_id) IN (SELECT unnest($3::bytea[]))
 	`
-	_, err = ind.db.ExecContext(ctx, updateQ, b.TimestampMS, prevoutIDs)
+	_, err = ind.db.ExecContext(ctx, updateQ, b.TimestampMS, b.Height, prevoutIDs)
 	return errors.Wrap(err, "updating spent annotated outputs")
 }
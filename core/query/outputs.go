@@ -47,7 +47,10 @@ func DecodeOutputsAfter(str string) (c *OutputsAfter, err error) {
 	}, nil
 }
 
-func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) ([]*AnnotatedOutput, *OutputsAfter, error) {
+// Outputs queries the annotated_outputs for outputs matching the
+// filter predicate `filt` that were unspent at timestampMS, or, if
+// blockHeight is nonzero, at blockHeight.
+func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}, timestampMS, blockHeight uint64, after *OutputsAfter, limit int) ([]*AnnotatedOutput, *OutputsAfter, error) {
 	p, err := filter.Parse(filt, outputsTable, vals)
 	if err != nil {
 		return nil, nil, err
@@ -59,7 +62,7 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 	if err != nil {
 		return nil, nil, err
 	}
-	queryStr, queryArgs := constructOutputsQuery(expr, vals, timestampMS, after, limit)
+	queryStr, queryArgs := constructOutputsQuery(expr, vals, timestampMS, blockHeight, after, limit)
 	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, nil, err
@@ -130,7 +133,7 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 	return outputs, &newAfter, nil
 }
 
-func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) (string, []interface{}) {
+func constructOutputsQuery(where string, vals []interface{}, timestampMS, blockHeight uint64, after *OutputsAfter, limit int) (string, []interface{}) {
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT ")
@@ -148,9 +151,9 @@ func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64,
 		buf.WriteString(") AND ")
 	}
 
-	vals = append(vals, timestampMS)
-	timestampValIndex := len(vals)
-	buf.WriteString(fmt.Sprintf("timespan @> $%d::int8", timestampValIndex))
+	var spanCond string
+	spanCond, vals = spanCondition(vals, timestampMS, blockHeight)
+	buf.WriteString(spanCond)
 
 	if after != nil {
 		vals = append(vals, after.lastBlockHeight)
@@ -169,3 +172,23 @@ func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64,
 
 	return buf.String(), vals
 }
+
+// spanCondition returns a SQL condition selecting the annotated
+// outputs that were unspent at the given point in the blockchain,
+// and vals with the condition's parameter appended. Outputs are live
+// in the half-open range [created, spent) of both their timespan and
+// their heightspan. A timestamp selects the state as of the last block
+// at or before it; a block height names the block directly, so a
+// client that knows a height needn't look up its block's timestamp.
+//
+// The block height here is a parameter of the query, not a filter
+// attribute: outputs can't be filtered on the height at which they
+// were created, which is out of scope for point-in-time queries.
+func spanCondition(vals []interface{}, timestampMS, blockHeight uint64) (string, []interface{}) {
+	if blockHeight > 0 {
+		vals = append(vals, blockHeight)
+		return fmt.Sprintf("heightspan @> $%d::int8", len(vals)), vals
+	}
+	vals = append(vals, timestampMS)
+	return fmt.Sprintf("timespan @> $%d::int8", len(vals)), vals
+}
@@ -22,10 +22,11 @@ var (
 		Name:  "annotated_accounts",
 		Alias: "acc",
 		Columns: map[string]*filter.SQLColumn{
-			"id":     {Name: "id", Type: filter.String, SQLType: filter.SQLText},
-			"alias":  {Name: "alias", Type: filter.String, SQLType: filter.SQLText},
-			"quorum": {Name: "quorum", Type: filter.Integer, SQLType: filter.SQLInteger},
-			"tags":   {Name: "tags", Type: filter.Object, SQLType: filter.SQLJSONB},
+			"id":            {Name: "id", Type: filter.String, SQLType: filter.SQLText},
+			"alias":         {Name: "alias", Type: filter.String, SQLType: filter.SQLText},
+			"quorum":        {Name: "quorum", Type: filter.Integer, SQLType: filter.SQLInteger},
+			"tags":          {Name: "tags", Type: filter.Object, SQLType: filter.SQLJSONB},
+			"is_watch_only": {Name: "watch_only", Type: filter.String, SQLType: filter.SQLBool},
 		},
 	}
 	outputsTable = &filter.SQLTable{
@@ -3,6 +3,7 @@ package query
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -10,6 +11,7 @@ import (
 
 	"chain/core/query/filter"
 	"chain/errors"
+	"chain/protocol/bc"
 )
 
 var (
@@ -74,6 +76,20 @@ func (ind *Indexer) LookupTxAfter(ctx context.Context, begin, end uint64) (TxAft
 	}, nil
 }
 
+// LookupTxHeight returns the height of the block containing
+// the indexed transaction with the given ID. The found result
+// reports whether the transaction has been indexed.
+func (ind *Indexer) LookupTxHeight(ctx context.Context, txID bc.Hash) (height uint64, found bool, err error) {
+	const q = `SELECT block_height FROM annotated_txs WHERE tx_hash = $1`
+	err = ind.db.QueryRowContext(ctx, q, txID).Scan(&height)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrap(err, "querying annotated_txs")
+	}
+	return height, true, nil
+}
+
 // Transactions queries the blockchain for transactions matching the
 // filter predicate `filt`.
 func (ind *Indexer) Transactions(ctx context.Context, filt string, vals []interface{}, after TxAfter, limit int, asc bool) ([]*AnnotatedTx, *TxAfter, error) {
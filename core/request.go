@@ -41,6 +41,24 @@ func (a *API) filterAliases(ctx context.Context, br *buildRequest) error {
 			}
 			m["account_id"] = acc.ID
 		}
+
+		// Batch actions identify an account per recipient.
+		recipients, _ := m["recipients"].([]interface{})
+		for j, r := range recipients {
+			r, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ = r["account_id"].(string)
+			alias, _ = r["account_alias"].(string)
+			if id == "" && alias != "" {
+				acc, err := a.accounts.FindByAlias(ctx, alias)
+				if err != nil {
+					return errors.WithDetailf(err, "invalid account alias %s on recipient %d of action %d", alias, j, i)
+				}
+				r["account_id"] = acc.ID
+			}
+		}
 	}
 	return nil
 }
@@ -7,12 +7,14 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"chain/core/accesstoken"
 	"chain/core/account"
 	"chain/core/asset"
 	"chain/core/config"
+	"chain/core/contract"
 	"chain/core/fetch"
 	"chain/core/generator"
 	"chain/core/leader"
@@ -35,6 +37,8 @@ var blockPeriod = env.Duration("BLOCK_PERIOD", 1000*time.Millisecond)
 
 const (
 	expireReservationsPeriod = time.Second
+	webhookPeriod            = 5 * time.Second
+	consolidationPeriod      = time.Minute
 )
 
 // RunOption describes a runtime configuration option.
@@ -77,6 +81,14 @@ func BlockSigner(signFn func(context.Context, *legacy.Block) ([]byte, error)) Ru
 	return func(a *API) { a.signer = signFn }
 }
 
+// TransactionSigner configures the Core to use signFn to sign the
+// transactions it builds itself, such as UTXO consolidations. Without
+// it, the Core uses the MockHSM if there is one. To sign with a remote
+// HSM such as signerd, use the Sign method of a txbuilder.RemoteSigner.
+func TransactionSigner(signFn txbuilder.TemplateSigner) RunOption {
+	return func(a *API) { a.txSigner = signFn }
+}
+
 // GeneratorLocal configures the launched Core to run as a Generator.
 func GeneratorLocal(gen *generator.Generator) RunOption {
 	return func(a *API) {
@@ -186,6 +198,7 @@ func Run(
 		assets:       assets,
 		accounts:     accounts,
 		txFeeds:      &txfeed.Tracker{DB: db},
+		contracts:    contract.NewRegistry(db, c),
 		indexer:      indexer,
 		accessTokens: &accesstoken.CredentialStore{DB: db},
 		grants:       authz.NewStore(sdb, GrantPrefix),
@@ -203,6 +216,22 @@ func Run(
 		return nil, errors.New("no generator configured")
 	}
 
+	// The leader consolidates an account's UTXOs of an asset once
+	// there are more of them than the configured threshold.
+	consolidationThreshold := func() int { return 0 }
+	if confOpts != nil {
+		get := confOpts.GetFunc("utxo_consolidation_threshold")
+		consolidationThreshold = func() int {
+			tup := get()
+			if len(tup) == 0 {
+				return 0
+			}
+			n, _ := strconv.Atoi(tup[0]) // validated when set
+			return n
+		}
+	}
+	a.consolidator = accounts.NewConsolidator(consolidationThreshold, a.txSigner, a.submitter)
+
 	if a.replicator != nil {
 		go a.replicator.PollRemoteHeight(ctx)
 	}
@@ -270,6 +299,13 @@ func (a *API) lead(ctx context.Context) {
 		}
 	}
 
+	// Reservations made by the previous leader must remain in
+	// effect; the templates built with them may still be submitted.
+	err = a.accounts.RestoreReservations(ctx)
+	if err != nil {
+		log.Fatalkv(ctx, log.KeyError, err)
+	}
+
 	if a.config.IsGenerator {
 		go a.generator.Generate(ctx, blockPeriod, a.healthSetter("generator"))
 	} else {
@@ -283,7 +319,11 @@ func (a *API) lead(ctx context.Context) {
 	}
 	go a.accounts.ProcessBlocks(ctx)
 	go a.assets.ProcessBlocks(ctx)
+	go a.consolidator.Run(ctx, consolidationPeriod)
 	if a.indexTxs {
 		go a.indexer.ProcessBlocks(ctx)
+
+		d := &txfeed.Deliverer{Tracker: a.txFeeds, Indexer: a.indexer}
+		go d.Run(ctx, webhookPeriod)
 	}
 }
@@ -151,7 +151,8 @@ CREATE TABLE account_control_programs (
     key_index bigint NOT NULL,
     control_program bytea NOT NULL,
     change boolean NOT NULL,
-    expires_at timestamp with time zone
+    expires_at timestamp with time zone,
+    key_version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -167,7 +168,8 @@ CREATE TABLE account_utxos (
     source_id bytea NOT NULL,
     source_pos bigint NOT NULL,
     ref_data_hash bytea NOT NULL,
-    change boolean NOT NULL
+    change boolean NOT NULL,
+    key_version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -175,7 +177,9 @@ CREATE TABLE account_utxos (
 CREATE TABLE accounts (
     account_id text NOT NULL,
     tags jsonb,
-    alias text
+    alias text,
+    coin_selection text,
+    watch_only boolean DEFAULT false NOT NULL
 );
 
 
@@ -185,7 +189,8 @@ CREATE TABLE annotated_accounts (
     alias text NOT NULL,
     keys jsonb NOT NULL,
     quorum integer NOT NULL,
-    tags jsonb NOT NULL
+    tags jsonb NOT NULL,
+    watch_only boolean DEFAULT false NOT NULL
 );
 
 
@@ -245,7 +250,8 @@ CREATE TABLE annotated_outputs (
     account_tags jsonb,
     control_program bytea NOT NULL,
     reference_data jsonb NOT NULL,
-    local boolean NOT NULL
+    local boolean NOT NULL,
+    heightspan int8range NOT NULL
 );
 
 
@@ -312,6 +318,14 @@ CREATE TABLE blocks (
 
 
 
+CREATE TABLE canceled_utxos (
+    output_id bytea NOT NULL,
+    reservation_id bigint NOT NULL,
+    expiry timestamp with time zone NOT NULL
+);
+
+
+
 CREATE SEQUENCE chain_id_seq
     START WITH 1
     INCREMENT BY 1
@@ -340,6 +354,17 @@ CREATE TABLE config (
 
 
 
+CREATE TABLE contract_templates (
+    id text DEFAULT next_chain_id('ctm'::text) NOT NULL,
+    alias text NOT NULL,
+    source text NOT NULL,
+    contract jsonb NOT NULL,
+    client_token text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE core_id (
     singleton boolean DEFAULT true NOT NULL,
     id text,
@@ -357,6 +382,26 @@ CREATE TABLE generator_pending_block (
 
 
 
+CREATE SEQUENCE generator_pending_txs_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+
+CREATE TABLE generator_pending_txs (
+    tx_hash bytea NOT NULL,
+    data bytea NOT NULL,
+    submitted_at timestamp with time zone DEFAULT now() NOT NULL,
+    seq bigint DEFAULT nextval('generator_pending_txs_seq'::regclass) NOT NULL,
+    rejected_at timestamp with time zone,
+    error text
+);
+
+
+
 CREATE TABLE leader (
     singleton boolean DEFAULT true NOT NULL,
     leader_key text NOT NULL,
@@ -389,7 +434,17 @@ CREATE TABLE mockhsm (
     prv bytea NOT NULL,
     alias text,
     sort_id bigint DEFAULT nextval('mockhsm_sort_id_seq'::regclass) NOT NULL,
-    key_type text DEFAULT 'chain_kd'::text NOT NULL
+    key_type text DEFAULT 'chain_kd'::text NOT NULL,
+    encrypted boolean DEFAULT false NOT NULL
+);
+
+
+
+CREATE TABLE mockhsm_kek (
+    singleton boolean DEFAULT true NOT NULL,
+    salt bytea NOT NULL,
+    verifier bytea NOT NULL,
+    CONSTRAINT mockhsm_kek_singleton CHECK (singleton)
 );
 
 
@@ -401,6 +456,33 @@ CREATE TABLE query_blocks (
 
 
 
+CREATE TABLE reservations (
+    id bigint NOT NULL,
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    change bigint NOT NULL,
+    expiry timestamp with time zone NOT NULL,
+    client_token text
+);
+
+
+
+CREATE SEQUENCE reservations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+
+CREATE TABLE reserved_utxos (
+    output_id bytea NOT NULL,
+    reservation_id bigint NOT NULL
+);
+
+
+
 CREATE TABLE signed_blocks (
     block_height bigint NOT NULL,
     block_hash bytea NOT NULL
@@ -408,13 +490,24 @@ CREATE TABLE signed_blocks (
 
 
 
+CREATE TABLE signer_key_versions (
+    signer_id text NOT NULL,
+    version integer NOT NULL,
+    xpubs bytea[] NOT NULL,
+    quorum integer NOT NULL,
+    retired_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE signers (
     id text NOT NULL,
     type text NOT NULL,
     key_index bigint NOT NULL,
     quorum integer NOT NULL,
     client_token text,
-    xpubs bytea[] NOT NULL
+    xpubs bytea[] NOT NULL,
+    key_version integer DEFAULT 1 NOT NULL
 );
 
 
@@ -448,12 +541,27 @@ CREATE TABLE submitted_txs (
 
 
 
+CREATE TABLE txfeed_deliveries (
+    id text DEFAULT next_chain_id('dlv'::text) NOT NULL,
+    feed_id text NOT NULL,
+    tx_id bytea NOT NULL,
+    cursor text NOT NULL,
+    status text NOT NULL,
+    attempts integer NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE txfeeds (
     id text DEFAULT next_chain_id('cur'::text) NOT NULL,
     alias text,
     filter text,
     after text,
-    client_token text
+    client_token text,
+    webhook_url text,
+    webhook_secret text
 );
 
 
@@ -552,11 +660,31 @@ ALTER TABLE ONLY blocks
 
 
 
+ALTER TABLE ONLY canceled_utxos
+    ADD CONSTRAINT canceled_utxos_pkey PRIMARY KEY (output_id);
+
+
+
 ALTER TABLE ONLY config
     ADD CONSTRAINT config_pkey PRIMARY KEY (singleton);
 
 
 
+ALTER TABLE ONLY contract_templates
+    ADD CONSTRAINT contract_templates_alias_key UNIQUE (alias);
+
+
+
+ALTER TABLE ONLY contract_templates
+    ADD CONSTRAINT contract_templates_client_token_key UNIQUE (client_token);
+
+
+
+ALTER TABLE ONLY contract_templates
+    ADD CONSTRAINT contract_templates_pkey PRIMARY KEY (id);
+
+
+
 ALTER TABLE ONLY core_id
     ADD CONSTRAINT core_id_pkey PRIMARY KEY (singleton);
 
@@ -567,6 +695,11 @@ ALTER TABLE ONLY generator_pending_block
 
 
 
+ALTER TABLE ONLY generator_pending_txs
+    ADD CONSTRAINT generator_pending_txs_pkey PRIMARY KEY (tx_hash);
+
+
+
 ALTER TABLE ONLY leader
     ADD CONSTRAINT leader_singleton_key UNIQUE (singleton);
 
@@ -582,6 +715,11 @@ ALTER TABLE ONLY mockhsm
 
 
 
+ALTER TABLE ONLY mockhsm_kek
+    ADD CONSTRAINT mockhsm_kek_pkey PRIMARY KEY (singleton);
+
+
+
 ALTER TABLE ONLY mockhsm
     ADD CONSTRAINT mockhsm_pkey PRIMARY KEY (pub);
 
@@ -592,6 +730,26 @@ ALTER TABLE ONLY query_blocks
 
 
 
+ALTER TABLE ONLY reservations
+    ADD CONSTRAINT reservations_client_token_key UNIQUE (client_token);
+
+
+
+ALTER TABLE ONLY reservations
+    ADD CONSTRAINT reservations_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY reserved_utxos
+    ADD CONSTRAINT reserved_utxos_pkey PRIMARY KEY (output_id);
+
+
+
+ALTER TABLE ONLY signer_key_versions
+    ADD CONSTRAINT signer_key_versions_pkey PRIMARY KEY (signer_id, version);
+
+
+
 ALTER TABLE ONLY signers
     ADD CONSTRAINT signers_client_token_key UNIQUE (client_token);
 
@@ -617,6 +775,11 @@ ALTER TABLE ONLY submitted_txs
 
 
 
+ALTER TABLE ONLY txfeed_deliveries
+    ADD CONSTRAINT txfeed_deliveries_pkey PRIMARY KEY (id);
+
+
+
 ALTER TABLE ONLY txfeeds
     ADD CONSTRAINT txfeeds_alias_key UNIQUE (alias);
 
@@ -640,6 +803,10 @@ CREATE INDEX annotated_assets_sort_id ON annotated_assets USING btree (sort_id);
 
 
 
+CREATE INDEX annotated_outputs_heightspan_idx ON annotated_outputs USING gist (heightspan);
+
+
+
 CREATE INDEX annotated_outputs_timespan_idx ON annotated_outputs USING gist (timespan);
 
 
@@ -648,14 +815,39 @@ CREATE INDEX annotated_txs_data_idx ON annotated_txs USING gin (data jsonb_path_
 
 
 
+CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+
+
+
+CREATE UNIQUE INDEX generator_pending_txs_seq_idx ON generator_pending_txs USING btree (seq);
+
+
+
 CREATE INDEX query_blocks_timestamp_idx ON query_blocks USING btree ("timestamp");
 
 
 
+CREATE INDEX reservations_expiry_idx ON reservations USING btree (expiry);
+
+
+
+CREATE INDEX reserved_utxos_reservation_id_idx ON reserved_utxos USING btree (reservation_id);
+
+
+
 CREATE UNIQUE INDEX signed_blocks_block_height_idx ON signed_blocks USING btree (block_height);
 
 
 
+CREATE INDEX txfeed_deliveries_feed_id_idx ON txfeed_deliveries USING btree (feed_id, id);
+
+
+
+ALTER TABLE ONLY reserved_utxos
+    ADD CONSTRAINT reserved_utxos_reservation_id_fkey FOREIGN KEY (reservation_id) REFERENCES reservations(id) ON DELETE CASCADE;
+
+
+
 
 insert into migrations (filename, hash) values ('2017-02-03.0.core.schema-snapshot.sql', '1d55668affe0be9f3c19ead9d67bc75cfd37ec430651434d0f2af2706d9f08cd');
 insert into migrations (filename, hash) values ('2017-02-07.0.query.non-null-alias.sql', '17028a0bdbc95911e299dc65fe641184e54c87a0d07b3c576d62d023b9a8defc');
@@ -668,3 +860,16 @@ insert into migrations (filename, hash) values ('2017-04-17.0.core.null-token-ty
 insert into migrations (filename, hash) values ('2017-04-27.0.generator.pending-block-height.sql', 'bfe4fe5eec143e4367a91fd952cb5e3879f1c311f649ec13bfe95b202e94d4ec');
 insert into migrations (filename, hash) values ('2017-05-08.0.core.drop-redundant-indexes.sql', '5140e53b287b058c57ddf361d61cff3d3d1cbc3259a9de413b11574a71d09bec');
 insert into migrations (filename, hash) values ('2017-06-28.0.core.coreid.sql', 'a147b93ba1bf404265efedde066532c937070a87e15123b1d9277daba431ee01');
+insert into migrations (filename, hash) values ('2017-07-05.0.txfeed.webhooks.sql', '6bc3399edb58a3b9173d312cbf4cbe0623997a92ff6d9bf7361e7a856bd2fcb4');
+insert into migrations (filename, hash) values ('2017-07-10.0.query.output-heightspan.sql', 'd884ca18d94eeffcfaf02a43a4f99f017fe7c42c5d4cc359275490c43edaa897');
+insert into migrations (filename, hash) values ('2017-07-12.0.contract.templates.sql', '3a8e644bb105734774a25a95f0697233d05524a1bf95fdf1fa8756a1d84ab0d2');
+insert into migrations (filename, hash) values ('2017-07-13.0.account.reservations.sql', '08393b34b01fcc0ceab08525beebaedf6ebb9d9ece94a021718a53ffbae03ab6');
+insert into migrations (filename, hash) values ('2017-07-14.0.account.canceled-utxos.sql', 'e646893b40a77395085d655ebf2882e4b5d6088099b4c3d2999cf60ac36ccfd2');
+insert into migrations (filename, hash) values ('2017-07-15.0.account.coin-selection.sql', '80af47e7c0d30863930e49bf0242209bbba6003ae5205a43a191c8b50961e268');
+insert into migrations (filename, hash) values ('2017-07-16.0.mockhsm.encrypt-keys.sql', '2097ed17b9b81e8b06f625612fb15276824b1f167528c5d0288c00275b11de82');
+insert into migrations (filename, hash) values ('2017-07-17.0.signers.key-versions.sql', '9f663a89d9ccfd6b9fadba5e08b81add816bd6b29867537181187b4bd378c910');
+insert into migrations (filename, hash) values ('2017-07-18.0.account.watch-only.sql', '9899e45bfea4ac2fb752b93831f2a797f928f9ab81d4601c9aaef9ad13f02964');
+insert into migrations (filename, hash) values ('2017-07-19.0.generator.pending-txs.sql', '0068c46ab13cdd2bf2913c1870edfc8367c0af62c5d0e8b103944ad024e8ef27');
+insert into migrations (filename, hash) values ('2017-07-20.0.query.annotated-txs-hash.sql', 'fcccb200a6befbd28334bf81b6d24cbe12ef3b885abc7423b9d43996ef31b662');
+insert into migrations (filename, hash) values ('2017-07-21.0.generator.pending-txs-seq.sql', 'ad0130e532fb5308c9bc9a5a0411dfe23b39c1f51f9b7a9dfe6abbe3a439e63b');
+insert into migrations (filename, hash) values ('2017-07-22.0.generator.pending-txs-rejected.sql', '3ae71bffd162d1a3717c8ba2ec93741690989b23e319b046ffb03bb59ea7ecc3');
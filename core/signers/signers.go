@@ -59,6 +59,10 @@ type Signer struct {
 	XPubs    []chainkd.XPub
 	Quorum   int
 	KeyIndex uint64
+
+	// KeyVersion identifies the set of XPubs and the Quorum.
+	// It starts at 1 and increases each time the keys are updated.
+	KeyVersion int
 }
 
 // Path returns the complete path for derived keys
@@ -77,27 +81,21 @@ func Path(s *Signer, ks keySpace, itemIndexes ...uint64) [][]byte {
 
 // Create creates and stores a Signer in the database
 func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quorum int, clientToken string) (*Signer, error) {
-	if len(xpubs) == 0 {
-		return nil, errors.Wrap(ErrNoXPubs)
-	}
-
-	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
-	for i := 1; i < len(xpubs); i++ {
-		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
-			return nil, errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
-		}
-	}
-
-	if quorum == 0 || quorum > len(xpubs) {
-		return nil, errors.Wrap(ErrBadQuorum)
+	xpubBytes, err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
 	}
+	return insert(ctx, db, typ, xpubs, xpubBytes, quorum, clientToken)
+}
 
-	var xpubBytes [][]byte
-	for _, key := range xpubs {
-		key := key
-		xpubBytes = append(xpubBytes, key[:])
-	}
+// CreateKeyless creates and stores a Signer with no keys, for
+// objects, such as watch-only accounts, that this Core never
+// signs for.
+func CreateKeyless(ctx context.Context, db pg.DB, typ string, clientToken string) (*Signer, error) {
+	return insert(ctx, db, typ, nil, [][]byte{}, 0, clientToken)
+}
 
+func insert(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, xpubBytes [][]byte, quorum int, clientToken string) (*Signer, error) {
 	nullToken := sql.NullString{
 		String: clientToken,
 		Valid:  clientToken != "",
@@ -123,31 +121,127 @@ func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quo
 	}
 
 	return &Signer{
-		ID:       id,
-		Type:     typ,
-		XPubs:    xpubs,
-		Quorum:   quorum,
-		KeyIndex: keyIndex,
+		ID:         id,
+		Type:       typ,
+		XPubs:      xpubs,
+		Quorum:     quorum,
+		KeyIndex:   keyIndex,
+		KeyVersion: 1,
 	}, nil
 }
 
+// UpdateKeys replaces the keys and quorum of the Signer of type typ
+// with the given id, and increments its key version. The previous
+// keys and quorum remain available through FindVersion. If xpubs and
+// quorum are the same as the Signer's current ones, UpdateKeys
+// returns the Signer unchanged.
+func UpdateKeys(ctx context.Context, db pg.DB, typ, id string, xpubs []chainkd.XPub, quorum int) (*Signer, error) {
+	xpubBytes, err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		WITH retired AS (
+			INSERT INTO signer_key_versions (signer_id, version, xpubs, quorum)
+			SELECT id, key_version, xpubs, quorum FROM signers
+			WHERE id=$1 AND type=$2 AND (xpubs <> $3 OR quorum <> $4)
+			RETURNING signer_id, version
+		)
+		UPDATE signers SET xpubs=$3, quorum=$4, key_version=retired.version+1
+		FROM retired WHERE signers.id=retired.signer_id
+		RETURNING key_index, key_version
+	`
+	s := Signer{ID: id, Type: typ, XPubs: xpubs, Quorum: quorum}
+	err = db.QueryRowContext(ctx, q, id, typ, pq.ByteaArray(xpubBytes), quorum).Scan(&s.KeyIndex, &s.KeyVersion)
+	if err == sql.ErrNoRows {
+		// Either there's no such signer,
+		// or its keys are unchanged.
+		return Find(ctx, db, typ, id)
+	}
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(err, "the keys were updated concurrently; try again")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return &s, nil
+}
+
+// FindVersion returns s as it was at the given key version,
+// with the XPubs and Quorum in effect at that version.
+func FindVersion(ctx context.Context, db pg.DB, s *Signer, version int) (*Signer, error) {
+	if version == s.KeyVersion {
+		return s, nil
+	}
+
+	// The version may be newer than s, if s was read
+	// before the keys were updated.
+	const q = `
+		SELECT xpubs, quorum FROM signer_key_versions
+		WHERE signer_id=$1 AND version=$2
+		UNION ALL
+		SELECT xpubs, quorum FROM signers
+		WHERE id=$1 AND key_version=$2
+	`
+	var xpubBytes [][]byte
+	old := *s
+	old.KeyVersion = version
+	err := db.QueryRowContext(ctx, q, s.ID, version).Scan((*pq.ByteaArray)(&xpubBytes), &old.Quorum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading key version %d", version)
+	}
+	old.XPubs, err = ConvertKeys(xpubBytes)
+	if err != nil {
+		return nil, errors.WithDetail(errors.New("bad xpub in databse"), errors.Detail(err))
+	}
+	return &old, nil
+}
+
+// checkKeys validates xpubs and quorum, sorting xpubs in place,
+// and returns the keys as byte slices for storage.
+func checkKeys(xpubs []chainkd.XPub, quorum int) ([][]byte, error) {
+	if len(xpubs) == 0 {
+		return nil, errors.Wrap(ErrNoXPubs)
+	}
+
+	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
+	for i := 1; i < len(xpubs); i++ {
+		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
+			return nil, errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
+		}
+	}
+
+	if quorum == 0 || quorum > len(xpubs) {
+		return nil, errors.Wrap(ErrBadQuorum)
+	}
+
+	var xpubBytes [][]byte
+	for _, key := range xpubs {
+		key := key
+		xpubBytes = append(xpubBytes, key[:])
+	}
+	return xpubBytes, nil
+}
+
 func New(id, typ string, xpubs [][]byte, quorum int, keyIndex uint64) (*Signer, error) {
 	keys, err := ConvertKeys(xpubs)
 	if err != nil {
 		return nil, errors.WithDetail(errors.New("bad xpub in databse"), errors.Detail(err))
 	}
 	return &Signer{
-		ID:       id,
-		Type:     typ,
-		XPubs:    keys,
-		Quorum:   quorum,
-		KeyIndex: keyIndex,
+		ID:         id,
+		Type:       typ,
+		XPubs:      keys,
+		Quorum:     quorum,
+		KeyIndex:   keyIndex,
+		KeyVersion: 1,
 	}, nil
 }
 
 func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Signer, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, key_version
 		FROM signers WHERE client_token=$1
 	`
 
@@ -156,7 +250,7 @@ func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Sign
 		xpubBytes [][]byte
 	)
 	err := db.QueryRowContext(ctx, q, clientToken).
-		Scan(&s.ID, &s.Type, (*pq.ByteaArray)(&xpubBytes), &s.Quorum, &s.KeyIndex)
+		Scan(&s.ID, &s.Type, (*pq.ByteaArray)(&xpubBytes), &s.Quorum, &s.KeyIndex, &s.KeyVersion)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -175,7 +269,7 @@ func findByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Sign
 // using the type and id.
 func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, key_version
 		FROM signers WHERE id=$1
 	`
 
@@ -189,6 +283,7 @@ func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 		(*pq.ByteaArray)(&xpubBytes),
 		&s.Quorum,
 		&s.KeyIndex,
+		&s.KeyVersion,
 	)
 	if err == sql.ErrNoRows {
 		return nil, errors.Wrap(pg.ErrUserInputNotFound)
@@ -215,25 +310,26 @@ func Find(ctx context.Context, db pg.DB, typ, id string) (*Signer, error) {
 // the provided type.
 func List(ctx context.Context, db pg.DB, typ, prev string, limit int) ([]*Signer, string, error) {
 	const q = `
-		SELECT id, type, xpubs, quorum, key_index
+		SELECT id, type, xpubs, quorum, key_index, key_version
 		FROM signers WHERE type=$1 AND ($2='' OR $2<id)
 		ORDER BY id ASC LIMIT $3
 	`
 
 	var signers []*Signer
 	err := pg.ForQueryRows(ctx, db, q, typ, prev, limit,
-		func(id, typ string, xpubs pq.ByteaArray, quorum int, keyIndex uint64) error {
+		func(id, typ string, xpubs pq.ByteaArray, quorum int, keyIndex uint64, keyVersion int) error {
 			keys, err := ConvertKeys(xpubs)
 			if err != nil {
 				return errors.WithDetail(errors.New("bad xpub in databse"), errors.Detail(err))
 			}
 
 			signers = append(signers, &Signer{
-				ID:       id,
-				Type:     typ,
-				XPubs:    keys,
-				Quorum:   quorum,
-				KeyIndex: keyIndex,
+				ID:         id,
+				Type:       typ,
+				XPubs:      keys,
+				Quorum:     quorum,
+				KeyIndex:   keyIndex,
+				KeyVersion: keyVersion,
 			})
 			return nil
 		},
@@ -237,6 +237,59 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestUpdateKeys(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+
+	s1 := createFixture(ctx, db, t)
+
+	_, err := UpdateKeys(ctx, db, "account", s1.ID, []chainkd.XPub{dummyXPub}, 2)
+	if errors.Root(err) != ErrBadQuorum {
+		t.Errorf("UpdateKeys with bad quorum = %v want %v", err, ErrBadQuorum)
+	}
+	_, err = UpdateKeys(ctx, db, "asset", s1.ID, []chainkd.XPub{dummyXPub}, 1)
+	if errors.Root(err) != ErrBadType {
+		t.Errorf("UpdateKeys with wrong type = %v want %v", err, ErrBadType)
+	}
+	_, err = UpdateKeys(ctx, db, "account", "nonexistent", []chainkd.XPub{dummyXPub}, 1)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("UpdateKeys with unknown ID = %v want %v", err, pg.ErrUserInputNotFound)
+	}
+
+	s2, err := UpdateKeys(ctx, db, "account", s1.ID, []chainkd.XPub{testutil.TestXPub, dummyXPub}, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if s2.KeyVersion != 2 || s2.KeyIndex != s1.KeyIndex {
+		t.Errorf("updated signer has key version %d, key index %d; want 2, %d", s2.KeyVersion, s2.KeyIndex, s1.KeyIndex)
+	}
+
+	got, err := Find(ctx, db, "account", s1.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !testutil.DeepEqual(got, s2) {
+		t.Errorf("Find after UpdateKeys\n\tgot:  %+v\n\twant: %+v", got, s2)
+	}
+
+	// Updating to the same keys doesn't create a new version.
+	same, err := UpdateKeys(ctx, db, "account", s1.ID, []chainkd.XPub{dummyXPub, testutil.TestXPub}, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if same.KeyVersion != 2 {
+		t.Errorf("repeated UpdateKeys: key version = %d want 2", same.KeyVersion)
+	}
+
+	old, err := FindVersion(ctx, db, got, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !testutil.DeepEqual(old, s1) {
+		t.Errorf("FindVersion(1)\n\tgot:  %+v\n\twant: %+v", old, s1)
+	}
+}
+
 var clientTokenCounter = createCounter()
 
 func createFixture(ctx context.Context, db pg.DB, t testing.TB) *Signer {
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"chain/core/config"
+	"chain/core/signpolicy"
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// signingPolicyChecker returns a function that checks templates
+// against the signing policy in the configuration options
+// signing_max_amount, signing_allowed_account, signing_allowed_program
+// and signing_window. It returns nil if the Core has no configuration
+// options or no accounts, in which case nothing is checked.
+func (a *API) signingPolicyChecker() func(context.Context, *txbuilder.Template) error {
+	if a.options == nil || a.accounts == nil {
+		return nil
+	}
+	maxAmounts := a.options.ListFunc("signing_max_amount")
+	allowedAccounts := a.options.ListFunc("signing_allowed_account")
+	allowedPrograms := a.options.ListFunc("signing_allowed_program")
+	windows := a.options.ListFunc("signing_window")
+
+	return func(ctx context.Context, tpl *txbuilder.Template) error {
+		// All of the values were validated when they were set.
+		var p signpolicy.Policy
+		for _, tup := range maxAmounts() {
+			if p.MaxAmounts == nil {
+				p.MaxAmounts = make(map[bc.AssetID]uint64)
+			}
+			assetID, amount, _ := parseMaxAmount(tup)
+			p.MaxAmounts[assetID] = amount
+		}
+		for _, tup := range allowedAccounts() {
+			p.AllowedAccounts = append(p.AllowedAccounts, tup[0])
+		}
+		for _, tup := range allowedPrograms() {
+			prog, _ := hex.DecodeString(tup[0])
+			p.AllowedPrograms = append(p.AllowedPrograms, prog)
+		}
+		for _, tup := range windows() {
+			w, _ := parseWindow(tup)
+			p.Windows = append(p.Windows, w)
+		}
+		return p.Check(ctx, tpl, a.accounts.ProgramAccounts, time.Now())
+	}
+}
+
+// parseMaxAmount parses a signing_max_amount tuple
+// of an asset ID and an amount.
+func parseMaxAmount(tup []string) (bc.AssetID, uint64, error) {
+	assetID, amount, err := signpolicy.ParseMaxAmount(tup[0], tup[1])
+	return assetID, amount, errors.Sub(config.ErrConfigOp, err)
+}
+
+// parseWindow parses a signing_window tuple of a start
+// and end time of day, in UTC, of the form HH:MM.
+func parseWindow(tup []string) (signpolicy.Window, error) {
+	w, err := signpolicy.ParseWindow(tup[0], tup[1])
+	return w, errors.Sub(config.ErrConfigOp, err)
+}
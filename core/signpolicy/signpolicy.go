@@ -0,0 +1,252 @@
+// Package signpolicy checks transaction templates against
+// a set of rules before an HSM signs them.
+package signpolicy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrViolation is returned when a template breaks a rule of a Policy.
+// The error's data names the rule under the key "rule".
+var ErrViolation = errors.New("signing policy violation")
+
+// ErrBadRule is returned by ParseMaxAmount and ParseWindow
+// when a rule is malformed. The error's detail says why.
+var ErrBadRule = errors.New("invalid signing policy rule")
+
+// Names of the rules reported in the data of ErrViolation.
+const (
+	RuleMaxAmount   = "max_amount"
+	RuleDestination = "allowed_destination"
+	RuleTimeWindow  = "time_window"
+)
+
+// Policy is a set of rules a template must satisfy to be signed.
+// The zero Policy allows everything.
+//
+// A transaction's local accounts are the accounts that own its
+// inputs. Its local value is the value of those inputs and of its
+// issuances. Value from other parties' inputs, such as the other
+// side of a trade, isn't subject to the rules.
+type Policy struct {
+	// MaxAmounts limits, per asset, the net amount a single
+	// transaction may send out of its local accounts: the local
+	// value of the asset minus its outputs to local accounts.
+	MaxAmounts map[bc.AssetID]uint64
+
+	// AllowedAccounts and AllowedPrograms list the destinations
+	// local value may be sent to, other than back to the local
+	// accounts. If both are empty, any destination is allowed.
+	// Retirements are sent to an OP_FAIL program, which must be
+	// listed in AllowedPrograms to permit them.
+	AllowedAccounts []string
+	AllowedPrograms [][]byte
+
+	// Windows lists the times of day, in UTC, at which signing
+	// is allowed. If empty, signing is allowed at any time.
+	Windows []Window
+}
+
+// Window is a range of the day, in UTC, from Start up to but not
+// including End, each measured from midnight. If Start is after End,
+// the window wraps around midnight.
+type Window struct {
+	Start, End time.Duration
+}
+
+// Contains reports whether the time of day of t falls in w.
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC()
+	h, m, s := t.Clock()
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+	if w.Start <= w.End {
+		return w.Start <= d && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// ParseMaxAmount parses a per-asset limit for Policy.MaxAmounts
+// from an asset ID, as hex, and an amount, in decimal.
+func ParseMaxAmount(assetID, amount string) (bc.AssetID, uint64, error) {
+	var id bc.AssetID
+	err := id.UnmarshalText([]byte(assetID))
+	if err != nil {
+		return id, 0, errors.WithDetailf(ErrBadRule, "Invalid asset ID %q.", assetID)
+	}
+	n, err := strconv.ParseUint(amount, 10, 64)
+	if err != nil {
+		return id, 0, errors.WithDetailf(ErrBadRule, "Invalid amount %q.", amount)
+	}
+	return id, n, nil
+}
+
+// ParseWindow parses a Window from start and end times of
+// day, in UTC, of the form HH:MM.
+func ParseWindow(start, end string) (Window, error) {
+	var w Window
+	for _, x := range []struct {
+		s string
+		d *time.Duration
+	}{{start, &w.Start}, {end, &w.End}} {
+		t, err := time.Parse("15:04", x.s)
+		if err != nil {
+			return w, errors.WithDetailf(ErrBadRule, "Invalid time of day %q; use HH:MM in UTC.", x.s)
+		}
+		*x.d = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if w.Start == w.End {
+		return w, errors.WithDetail(ErrBadRule, "Signing window must not be empty.")
+	}
+	return w, nil
+}
+
+// AccountLookup returns the IDs of the local accounts that own
+// the given control programs, keyed by string(program).
+// Programs that belong to no local account are absent.
+type AccountLookup func(ctx context.Context, progs [][]byte) (map[string]string, error)
+
+// Check returns an error wrapping ErrViolation if signing tpl at
+// time now would break a rule of p. It uses lookup to find the
+// local accounts of tpl and the accounts it sends to.
+func (p *Policy) Check(ctx context.Context, tpl *txbuilder.Template, lookup AccountLookup, now time.Time) error {
+	if len(p.Windows) > 0 && !p.inWindow(now) {
+		return errors.WithData(
+			errors.WithDetailf(ErrViolation, "signing is not allowed at %s UTC", now.UTC().Format("15:04")),
+			"rule", RuleTimeWindow,
+		)
+	}
+	if len(p.MaxAmounts) == 0 && len(p.AllowedAccounts) == 0 && len(p.AllowedPrograms) == 0 {
+		return nil
+	}
+
+	tx := tpl.Transaction
+	if tx == nil {
+		return errors.Wrap(txbuilder.ErrMissingRawTx)
+	}
+	var progs [][]byte
+	for _, in := range tx.Inputs {
+		if prog := in.ControlProgram(); prog != nil {
+			progs = append(progs, prog)
+		}
+	}
+	for _, out := range tx.Outputs {
+		progs = append(progs, out.ControlProgram)
+	}
+	accounts, err := lookup(ctx, progs)
+	if err != nil {
+		return errors.Wrap(err, "looking up accounts")
+	}
+
+	var (
+		local     = make(map[string]bool)       // local account IDs
+		localIn   = make(map[bc.AssetID]uint64) // local value
+		foreignIn = make(map[bc.AssetID]uint64) // value of other parties' inputs
+		localOut  = make(map[bc.AssetID]uint64) // outputs to local accounts
+		deniedOut = make(map[bc.AssetID]uint64) // outputs to disallowed destinations
+	)
+	for _, in := range tx.Inputs {
+		acctID, isAccount := accounts[string(in.ControlProgram())]
+		if isAccount {
+			local[acctID] = true
+		}
+		if _, isIssuance := in.TypedInput.(*legacy.IssuanceInput); isAccount || isIssuance {
+			localIn[in.AssetID()] = addSaturating(localIn[in.AssetID()], in.Amount())
+		} else {
+			foreignIn[in.AssetID()] = addSaturating(foreignIn[in.AssetID()], in.Amount())
+		}
+	}
+	for _, out := range tx.Outputs {
+		acctID, isAccount := accounts[string(out.ControlProgram)]
+		if isAccount && local[acctID] {
+			localOut[*out.AssetId] = addSaturating(localOut[*out.AssetId], out.Amount)
+		} else if !p.allowedDestination(acctID, isAccount, out.ControlProgram) {
+			deniedOut[*out.AssetId] = addSaturating(deniedOut[*out.AssetId], out.Amount)
+		}
+	}
+
+	// Outputs can't be matched to the inputs that fund them, but
+	// the value of an asset sent to disallowed destinations can
+	// only be covered by other parties' inputs up to their value.
+	// Any more must be local value.
+	for i, out := range tx.Outputs {
+		assetID := *out.AssetId
+		if deniedOut[assetID] <= foreignIn[assetID] {
+			continue
+		}
+		acctID, isAccount := accounts[string(out.ControlProgram)]
+		if isAccount && local[acctID] || p.allowedDestination(acctID, isAccount, out.ControlProgram) {
+			continue
+		}
+		dest := fmt.Sprintf("control program %x", out.ControlProgram)
+		if isAccount {
+			dest = "account " + acctID
+		}
+		return errors.WithData(
+			errors.WithDetailf(ErrViolation, "output %d sends to %s, which is not an allowed destination", i, dest),
+			"rule", RuleDestination,
+			"output_index", i,
+		)
+	}
+
+	for assetID, max := range p.MaxAmounts {
+		var amount uint64
+		if localIn[assetID] > localOut[assetID] {
+			amount = localIn[assetID] - localOut[assetID]
+		}
+		if amount > max {
+			return errors.WithData(
+				errors.WithDetailf(ErrViolation, "transaction sends %d of asset %x, more than the limit of %d", amount, assetID.Bytes(), max),
+				"rule", RuleMaxAmount,
+				"asset_id", assetID,
+				"amount", amount,
+				"limit", max,
+			)
+		}
+	}
+	return nil
+}
+
+func addSaturating(a, b uint64) uint64 {
+	if math.MaxUint64-a < b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func (p *Policy) inWindow(now time.Time) bool {
+	for _, w := range p.Windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowedDestination(acctID string, isAccount bool, prog []byte) bool {
+	if len(p.AllowedAccounts) == 0 && len(p.AllowedPrograms) == 0 {
+		return true
+	}
+	if isAccount {
+		for _, id := range p.AllowedAccounts {
+			if id == acctID {
+				return true
+			}
+		}
+	}
+	for _, allowed := range p.AllowedPrograms {
+		if bytes.Equal(allowed, prog) {
+			return true
+		}
+	}
+	return false
+}
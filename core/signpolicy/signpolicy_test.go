@@ -0,0 +1,241 @@
+package signpolicy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	assetA = bc.NewAssetID([32]byte{1})
+	assetB = bc.NewAssetID([32]byte{2})
+
+	aliceProg  = []byte("alice")
+	alice2Prog = []byte("alice change")
+	bobProg    = []byte("bob")
+	extProg    = []byte("external")
+	ext2Prog   = []byte("external change")
+)
+
+func lookup(ctx context.Context, progs [][]byte) (map[string]string, error) {
+	owners := map[string]string{
+		string(aliceProg):  "acc-alice",
+		string(alice2Prog): "acc-alice",
+		string(bobProg):    "acc-bob",
+	}
+	res := make(map[string]string)
+	for _, p := range progs {
+		if id, ok := owners[string(p)]; ok {
+			res[string(p)] = id
+		}
+	}
+	return res, nil
+}
+
+// template spends 100 units of assetA from alice and
+// sends 30 to dest and the rest back to alice as change.
+func template(dest []byte) *txbuilder.Template {
+	return &txbuilder.Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.NewHash([32]byte{9}), assetA, 100, 0, aliceProg, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetA, 30, dest, nil),
+				legacy.NewTxOutput(assetA, 70, alice2Prog, nil),
+			},
+		}),
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	noon := time.Date(2017, 7, 18, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		policy Policy
+		dest   []byte
+		rule   string // empty if the template should be allowed
+	}{
+		{Policy{}, extProg, ""},
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetA: 30}}, extProg, ""},
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetA: 29}}, extProg, RuleMaxAmount},
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetB: 1}}, extProg, ""},
+		// Sending to the funding account itself isn't an outflow.
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetA: 0}}, aliceProg, ""},
+		{Policy{AllowedAccounts: []string{"acc-bob"}}, bobProg, ""},
+		{Policy{AllowedAccounts: []string{"acc-bob"}}, extProg, RuleDestination},
+		{Policy{AllowedPrograms: [][]byte{extProg}}, extProg, ""},
+		{Policy{AllowedPrograms: [][]byte{extProg}}, bobProg, RuleDestination},
+		{Policy{AllowedAccounts: []string{"acc-carol"}}, aliceProg, ""},
+		{Policy{Windows: []Window{{9 * time.Hour, 17 * time.Hour}}}, extProg, ""},
+		{Policy{Windows: []Window{{13 * time.Hour, 17 * time.Hour}}}, extProg, RuleTimeWindow},
+		{Policy{Windows: []Window{{13 * time.Hour, 17 * time.Hour}, {22 * time.Hour, 13 * time.Hour}}}, extProg, ""},
+	}
+	for i, c := range cases {
+		err := c.policy.Check(ctx, template(c.dest), lookup, noon)
+		if c.rule == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if errors.Root(err) != ErrViolation {
+			t.Errorf("case %d: got error %v, want %v", i, err, ErrViolation)
+			continue
+		}
+		if got := errors.Data(err)["rule"]; got != c.rule {
+			t.Errorf("case %d: got rule %v, want %s", i, got, c.rule)
+		}
+	}
+}
+
+// tradeTemplate trades 100 units of assetA from alice for 50 units
+// of assetB from an external party, who spends 60 and takes 10 back
+// as change.
+func tradeTemplate() *txbuilder.Template {
+	return &txbuilder.Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.NewHash([32]byte{9}), assetA, 100, 0, aliceProg, bc.Hash{}, nil),
+				legacy.NewSpendInput(nil, bc.NewHash([32]byte{10}), assetB, 60, 0, extProg, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetA, 100, extProg, nil),
+				legacy.NewTxOutput(assetB, 50, alice2Prog, nil),
+				legacy.NewTxOutput(assetB, 10, ext2Prog, nil),
+			},
+		}),
+	}
+}
+
+func TestCheckTrade(t *testing.T) {
+	ctx := context.Background()
+	noon := time.Date(2017, 7, 18, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		policy Policy
+		rule   string // empty if the template should be allowed
+	}{
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetA: 100}}, ""},
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetA: 99}}, RuleMaxAmount},
+		// Alice receives assetB; she doesn't send any.
+		{Policy{MaxAmounts: map[bc.AssetID]uint64{assetB: 0}}, ""},
+		// The external party's change is funded by its own input,
+		// so it isn't subject to the destination rules.
+		{Policy{AllowedPrograms: [][]byte{extProg}}, ""},
+		{Policy{AllowedPrograms: [][]byte{ext2Prog}}, RuleDestination},
+		{Policy{AllowedAccounts: []string{"acc-bob"}}, RuleDestination},
+	}
+	for i, c := range cases {
+		err := c.policy.Check(ctx, tradeTemplate(), lookup, noon)
+		if c.rule == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if errors.Root(err) != ErrViolation {
+			t.Errorf("case %d: got error %v, want %v", i, err, ErrViolation)
+			continue
+		}
+		if got := errors.Data(err)["rule"]; got != c.rule {
+			t.Errorf("case %d: got rule %v, want %s", i, got, c.rule)
+		}
+	}
+}
+
+func TestCheckIssuance(t *testing.T) {
+	ctx := context.Background()
+	iss := legacy.NewIssuanceInput([]byte{1}, 10, nil, bc.Hash{}, []byte("issuer"), nil, nil)
+	assetID := iss.AssetID()
+	tpl := &txbuilder.Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs:  []*legacy.TxInput{iss},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 10, extProg, nil),
+			},
+		}),
+	}
+
+	p := Policy{MaxAmounts: map[bc.AssetID]uint64{assetID: 9}}
+	err := p.Check(ctx, tpl, lookup, time.Now())
+	if errors.Root(err) != ErrViolation {
+		t.Errorf("issuing over the limit: got error %v, want %v", err, ErrViolation)
+	}
+	p = Policy{AllowedAccounts: []string{"acc-bob"}}
+	err = p.Check(ctx, tpl, lookup, time.Now())
+	if errors.Root(err) != ErrViolation {
+		t.Errorf("issuing to a disallowed destination: got error %v, want %v", err, ErrViolation)
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2017, 7, 18, h, m, 0, 0, time.UTC) }
+	cases := []struct {
+		w    Window
+		t    time.Time
+		want bool
+	}{
+		{Window{9 * time.Hour, 17 * time.Hour}, at(9, 0), true},
+		{Window{9 * time.Hour, 17 * time.Hour}, at(16, 59), true},
+		{Window{9 * time.Hour, 17 * time.Hour}, at(17, 0), false},
+		{Window{9 * time.Hour, 17 * time.Hour}, at(8, 59), false},
+		{Window{22 * time.Hour, 2 * time.Hour}, at(23, 0), true},
+		{Window{22 * time.Hour, 2 * time.Hour}, at(1, 0), true},
+		{Window{22 * time.Hour, 2 * time.Hour}, at(12, 0), false},
+		{Window{9 * time.Hour, 17 * time.Hour}, at(12, 0).In(time.FixedZone("", -10*3600)), true},
+	}
+	for _, c := range cases {
+		if got := c.w.Contains(c.t); got != c.want {
+			t.Errorf("%v.Contains(%s) = %v, want %v", c.w, c.t, got, c.want)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	cases := []struct {
+		start, end string
+		want       Window
+		ok         bool
+	}{
+		{"09:00", "17:30", Window{9 * time.Hour, 17*time.Hour + 30*time.Minute}, true},
+		{"22:00", "02:00", Window{22 * time.Hour, 2 * time.Hour}, true},
+		{"9am", "17:00", Window{}, false},
+		{"09:00", "09:00", Window{}, false},
+	}
+	for _, c := range cases {
+		got, err := ParseWindow(c.start, c.end)
+		if !c.ok {
+			if errors.Root(err) != ErrBadRule {
+				t.Errorf("ParseWindow(%q, %q) error = %v, want %v", c.start, c.end, err, ErrBadRule)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("ParseWindow(%q, %q) = %v, %v; want %v, nil", c.start, c.end, got, err, c.want)
+		}
+	}
+}
+
+func TestParseMaxAmount(t *testing.T) {
+	b, _ := assetA.MarshalText()
+	id, amount, err := ParseMaxAmount(string(b), "30")
+	if err != nil || id != assetA || amount != 30 {
+		t.Errorf("ParseMaxAmount = %v, %d, %v; want %v, 30, nil", id, amount, err, assetA)
+	}
+	for _, args := range [][2]string{{"zz", "30"}, {string(b), "-1"}} {
+		_, _, err := ParseMaxAmount(args[0], args[1])
+		if errors.Root(err) != ErrBadRule {
+			t.Errorf("ParseMaxAmount(%q, %q) error = %v, want %v", args[0], args[1], err, ErrBadRule)
+		}
+	}
+}
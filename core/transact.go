@@ -25,10 +25,14 @@ func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, erro
 	switch action {
 	case "control_account":
 		decoder = a.accounts.DecodeControlAction
+	case "control_accounts_batch":
+		decoder = a.accounts.DecodeControlBatchAction
 	case "control_program":
 		decoder = txbuilder.DecodeControlProgramAction
 	case "control_receiver":
 		decoder = txbuilder.DecodeControlReceiverAction
+	case "lock_with_contract":
+		decoder = a.contracts.DecodeLockAction
 	case "issue":
 		decoder = a.assets.DecodeIssueAction
 	case "retire":
@@ -37,6 +41,8 @@ func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, erro
 		decoder = a.accounts.DecodeSpendAction
 	case "spend_account_unspent_output":
 		decoder = a.accounts.DecodeSpendUTXOAction
+	case "unlock_contract_clause":
+		decoder = a.contracts.DecodeUnlockAction
 	case "set_transaction_reference_data":
 		decoder = txbuilder.DecodeSetTxRefDataAction
 	default:
@@ -134,12 +140,37 @@ func (a *API) build(ctx context.Context, buildReqs []*buildRequest) (interface{}
 	return responses, nil
 }
 
+type mergeRequest struct {
+	Templates []*txbuilder.Template `json:"templates"`
+}
+
+// POST /merge-transaction-templates
+func (a *API) mergeTemplates(ctx context.Context, x mergeRequest) (*txbuilder.Template, error) {
+	tpl, err := txbuilder.Merge(x.Templates...)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure null is never returned for signing instructions
+	if tpl.SigningInstructions == nil {
+		tpl.SigningInstructions = []*txbuilder.SigningInstruction{}
+	}
+	return tpl, nil
+}
+
 func (a *API) submitSingle(ctx context.Context, tpl *txbuilder.Template, waitUntil string) (interface{}, error) {
 	if tpl.Transaction == nil {
 		return nil, errors.Wrap(txbuilder.ErrMissingRawTx)
 	}
 
-	err := a.finalizeTxWait(ctx, tpl, waitUntil)
+	// Templates built on a canceled reservation are rejected, even
+	// though the outputs they spend may still be unspent.
+	err := a.accounts.CheckCanceledReservations(ctx, tpl.Transaction)
+	if err != nil {
+		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+	}
+
+	err = a.finalizeTxWait(ctx, tpl, waitUntil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
 	}
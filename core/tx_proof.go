@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// txProof proves that a transaction is in a block. To check it,
+// verify the block header's signature against the consensus
+// program (see validation.ValidateBlockSig), then verify the
+// proof against the header's TransactionsMerkleRoot with
+// bc.VerifyMerkleProof.
+type txProof struct {
+	ID          bc.Hash             `json:"id"`
+	BlockHeight uint64              `json:"block_height"`
+	BlockHeader *legacy.BlockHeader `json:"block_header"`
+	Proof       *bc.MerkleProof     `json:"proof"`
+}
+
+// getTxProof is an http handler for getting a Merkle proof that
+// a transaction is in a block, along with the signed header of
+// the block. If no block height is given, the block is found
+// using the transaction index.
+//
+// POST /get-transaction-proof
+func (a *API) getTxProof(ctx context.Context, in struct {
+	ID          bc.Hash `json:"id"`
+	BlockHeight uint64  `json:"block_height"`
+}) (*txProof, error) {
+	height := in.BlockHeight
+	if height == 0 {
+		if !a.indexTxs {
+			return nil, errors.WithDetail(httpjson.ErrBadRequest, "block_height is required when transaction indexing is disabled")
+		}
+		var found bool
+		var err error
+		height, found, err = a.indexer.LookupTxHeight(ctx, in.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "looking up indexed tx")
+		}
+		if !found {
+			return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s is not in a block", in.ID.String())
+		}
+	}
+	if height > a.chain.Height() {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no block at height %d", height)
+	}
+
+	b, err := a.chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", height)
+	}
+	index := -1
+	txs := make([]*bc.Tx, 0, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if tx.ID == in.ID {
+			index = i
+		}
+		txs = append(txs, tx.Tx)
+	}
+	if index < 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "transaction %s is not in block %d", in.ID.String(), height)
+	}
+
+	proof, err := bc.NewMerkleProof(txs, index)
+	if err != nil {
+		return nil, errors.Wrap(err, "building merkle proof")
+	}
+	return &txProof{
+		ID:          in.ID,
+		BlockHeight: height,
+		BlockHeader: &b.BlockHeader,
+		Proof:       proof,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/validation"
+	"chain/testutil"
+)
+
+func TestGetTxProof(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c)
+	a := &API{chain: c}
+
+	var txs []*legacy.Tx
+	for i := 0; i < 3; i++ {
+		txs = append(txs, bctest.NewIssuanceTx(t, initial.Hash()))
+	}
+	prev, _ := c.State()
+	b := prottest.MakeBlock(t, c, txs)
+
+	type req struct {
+		ID          bc.Hash `json:"id"`
+		BlockHeight uint64  `json:"block_height"`
+	}
+	resp, err := a.getTxProof(ctx, req{txs[1].ID, b.Height})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Verify the proof as an auditor would, from the JSON
+	// response and the consensus program alone.
+	j, err := json.Marshal(resp)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var got struct {
+		ID          bc.Hash            `json:"id"`
+		BlockHeader legacy.BlockHeader `json:"block_header"`
+		Proof       bc.MerkleProof     `json:"proof"`
+	}
+	err = json.Unmarshal(j, &got)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	header := legacy.MapBlock(&legacy.Block{BlockHeader: got.BlockHeader})
+	err = validation.ValidateBlockSig(header, prev.ConsensusProgram)
+	if err != nil {
+		t.Errorf("header signature did not verify: %v", err)
+	}
+	if !bc.VerifyMerkleProof(got.BlockHeader.TransactionsMerkleRoot, got.ID, &got.Proof) {
+		t.Error("proof did not verify")
+	}
+
+	other := bctest.NewIssuanceTx(t, initial.Hash())
+	_, err = a.getTxProof(ctx, req{other.ID, b.Height})
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("proof of tx not in block: got error %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+}
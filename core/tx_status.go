@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/net/http/httperror"
+	"chain/protocol/bc"
+)
+
+// Statuses of a submitted transaction.
+const (
+	// txStatusUnknown means neither this core nor the
+	// generator knows of the tx. It may never have been
+	// submitted, or its record may have expired.
+	txStatusUnknown = "unknown"
+
+	// txStatusPending means the tx is in the generator's
+	// pending tx pool and is valid for the next block.
+	txStatusPending = "pending"
+
+	// txStatusConfirmed means the tx is in a block.
+	txStatusConfirmed = "confirmed"
+
+	// txStatusRejected means the tx failed validation
+	// and won't be included in a block.
+	txStatusRejected = "rejected"
+)
+
+type txStatus struct {
+	ID          bc.Hash             `json:"id"`
+	Status      string              `json:"status"`
+	BlockHeight uint64              `json:"block_height,omitempty"`
+	Error       *httperror.Response `json:"error,omitempty"`
+}
+
+// getTxStatus is an http handler for looking up where a
+// transaction is in its lifecycle. Cores that aren't the
+// generator ask the generator about txs they don't know of.
+//
+// POST /get-transaction-status
+func (a *API) getTxStatus(ctx context.Context, in struct {
+	ID bc.Hash `json:"id"`
+}) (*txStatus, error) {
+	status, err := a.localTxStatus(ctx, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status != txStatusUnknown || a.remoteGenerator == nil {
+		return status, nil
+	}
+
+	var remote txStatus
+	err = a.remoteGenerator.Call(ctx, "/rpc/get-transaction-status", in.ID, &remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting tx status from generator")
+	}
+	return &remote, nil
+}
+
+// getTxStatusRPC looks up the status of a tx for
+// a core that isn't the generator.
+//
+// POST /rpc/get-transaction-status
+func (a *API) getTxStatusRPC(ctx context.Context, id bc.Hash) (*txStatus, error) {
+	if a.generator == nil {
+		return nil, errNoGenerator
+	}
+	return a.localTxStatus(ctx, id)
+}
+
+// localTxStatus looks up the status of a tx using this
+// core's transaction index, its cache of validation
+// results, and the generator's pending tx pool and record
+// of rejected txs if this core is the generator.
+func (a *API) localTxStatus(ctx context.Context, id bc.Hash) (*txStatus, error) {
+	status := &txStatus{ID: id, Status: txStatusUnknown}
+
+	if a.indexTxs {
+		height, found, err := a.indexer.LookupTxHeight(ctx, id)
+		if err != nil {
+			return nil, errors.Wrap(err, "looking up indexed tx")
+		}
+		if found {
+			status.Status = txStatusConfirmed
+			status.BlockHeight = height
+			return status, nil
+		}
+	}
+
+	if err, ok := a.chain.ValidationResult(id); ok && err != nil {
+		status.Status = txStatusRejected
+		status.Error = formatRejection(err)
+		return status, nil
+	}
+
+	if a.generator == nil {
+		return status, nil
+	}
+	p, err := a.generator.GetPending(ctx, id)
+	if err == nil {
+		status.Status = txStatusPending
+		if p.Err != nil {
+			status.Status = txStatusRejected
+			status.Error = formatRejection(p.Err)
+		}
+		return status, nil
+	} else if errors.Root(err) != pg.ErrUserInputNotFound {
+		return nil, errors.Wrap(err, "looking up pending tx")
+	}
+
+	// The generator may have dropped the tx from its pool.
+	r, err := a.generator.GetRejection(ctx, id)
+	if errors.Root(err) == pg.ErrUserInputNotFound {
+		return status, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "looking up rejected tx")
+	}
+	status.Status = txStatusRejected
+	status.Error = formatRejection(errors.New(r.Reason))
+	return status, nil
+}
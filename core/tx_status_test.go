@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/generator"
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestTxStatus(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	g := generator.New(c, nil, db)
+	indexer := query.NewIndexer(db, c, pin.NewStore(db))
+	a := &API{chain: c, generator: g, indexer: indexer, indexTxs: true}
+
+	check := func(id bc.Hash, want string, wantHeight uint64) {
+		got, err := a.localTxStatus(ctx, id)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Status != want || got.BlockHeight != wantHeight {
+			t.Errorf("status of %x = %s at %d, want %s at %d", id.Bytes(), got.Status, got.BlockHeight, want, wantHeight)
+		}
+		if (want == txStatusRejected) != (got.Error != nil) {
+			t.Errorf("status of %x has error %v", id.Bytes(), got.Error)
+		}
+	}
+
+	tx := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	check(tx.ID, txStatusUnknown, 0)
+
+	err := g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	check(tx.ID, txStatusPending, 0)
+
+	// An issuance committing to the wrong initial block fails validation.
+	bad := bctest.NewIssuanceTx(t, bc.Hash{})
+	c.ValidateTx(bad.Tx)
+	check(bad.ID, txStatusRejected, 0)
+
+	b := prottest.MakeBlock(t, c, g.PendingTxs())
+	err = indexer.IndexTransactions(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	check(tx.ID, txStatusConfirmed, b.Height)
+}
@@ -144,8 +144,9 @@ func TestConflictingTxsInPool(t *testing.T) {
 		SigningInstructions: firstTemplate.SigningInstructions,
 		Local:               true,
 	}
-	secondTemplate.SigningInstructions[0].SignatureWitnesses[0].Program = nil
-	secondTemplate.SigningInstructions[0].SignatureWitnesses[0].Sigs = nil
+	sw := secondTemplate.SigningInstructions[0].WitnessComponents[0].(*SignatureWitness)
+	sw.Program = nil
+	sw.Sigs = nil
 	coretest.SignTxTemplate(t, ctx, secondTemplate, nil)
 	err = FinalizeTx(ctx, info.Chain, g, secondTemplate.Transaction)
 	if err != nil {
@@ -0,0 +1,58 @@
+package txbuilder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"chain/core/rpc"
+	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
+	"chain/net/http/httperror"
+)
+
+// ErrRemoteSign is returned when a remote signer
+// refuses or fails to sign a template.
+var ErrRemoteSign = errors.New("remote signer error")
+
+// RemoteSigner signs templates by calling the /sign-transaction
+// endpoint of a remote HSM, such as signerd or a Core's MockHSM.
+type RemoteSigner struct {
+	Peer *rpc.Client
+}
+
+// Sign sends tpl to the remote HSM to be signed with the keys of
+// xpubs that it holds, and replaces tpl with the signed template.
+// It satisfies TemplateSigner.
+func (rs *RemoteSigner) Sign(ctx context.Context, tpl *Template, xpubs []chainkd.XPub) error {
+	req := struct {
+		Txs   []*Template    `json:"transactions"`
+		XPubs []chainkd.XPub `json:"xpubs"`
+	}{[]*Template{tpl}, xpubs}
+
+	// Each item in the response is either a signed
+	// template or an error.
+	var resp []json.RawMessage
+	err := rs.Peer.Call(ctx, "/sign-transaction", req, &resp)
+	if err != nil {
+		return errors.Wrap(err, "calling remote signer")
+	}
+	if len(resp) != 1 {
+		return errors.WithDetailf(ErrRemoteSign, "got %d results for 1 transaction", len(resp))
+	}
+	if errResp, ok := httperror.Parse(bytes.NewReader(resp[0])); ok {
+		detail := errResp.ChainCode + ": " + errResp.Message
+		if errResp.Detail != "" {
+			detail += ": " + errResp.Detail
+		}
+		return errors.WithDetail(ErrRemoteSign, detail)
+	}
+
+	var signed Template
+	err = json.Unmarshal(resp[0], &signed)
+	if err != nil {
+		return errors.Wrap(err, "decoding signed template")
+	}
+	*tpl = signed
+	return nil
+}
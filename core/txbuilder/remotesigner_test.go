@@ -0,0 +1,58 @@
+package txbuilder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/core/rpc"
+	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	_, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reply string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/sign-transaction" {
+			t.Errorf("got path %s, want /sign-transaction", req.URL.Path)
+		}
+		var x struct {
+			Txs   []*Template    `json:"transactions"`
+			XPubs []chainkd.XPub `json:"xpubs"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(x.Txs) != 1 || len(x.XPubs) != 1 || x.XPubs[0] != xpub {
+			t.Errorf("got %d templates and xpubs %v, want 1 template and [%v]", len(x.Txs), x.XPubs, xpub)
+		}
+		rw.Write([]byte(reply))
+	}))
+	defer server.Close()
+	rs := &RemoteSigner{Peer: &rpc.Client{BaseURL: server.URL}}
+	ctx := context.Background()
+
+	reply = `[{"raw_transaction": null, "signing_instructions": [], "local": true, "allow_additional_actions": true}]`
+	tpl := &Template{}
+	err = rs.Sign(ctx, tpl, []chainkd.XPub{xpub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tpl.Local || !tpl.AllowAdditional {
+		t.Errorf("template wasn't replaced with the signed one: %+v", tpl)
+	}
+
+	reply = `[{"code": "CH803", "message": "MockHSM is locked"}]`
+	err = rs.Sign(ctx, &Template{}, []chainkd.XPub{xpub})
+	if errors.Root(err) != ErrRemoteSign {
+		t.Errorf("got error %v, want %v", err, ErrRemoteSign)
+	}
+}
@@ -3,14 +3,18 @@
 package txbuilder
 
 import (
+	"bytes"
 	"context"
 	"time"
 
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/math/checked"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
 )
 
 var (
@@ -21,6 +25,10 @@ var (
 	ErrBlankCheck          = errors.New("unsafe transaction: leaves assets free to control")
 	ErrAction              = errors.New("errors occurred in one or more actions")
 	ErrMissingFields       = errors.New("required field is missing")
+	ErrNoTemplates         = errors.New("no templates to merge")
+	ErrBadTxVersion        = errors.New("transaction versions do not match")
+	ErrDuplicateInput      = errors.New("input appears in more than one template")
+	ErrBrokenCommitment    = errors.New("merged transaction violates a signed commitment")
 )
 
 // Build builds or adds on to a transaction.
@@ -66,9 +74,25 @@ func Build(ctx context.Context, tx *legacy.TxData, actions []Action, maxTime tim
 	return tpl, nil
 }
 
+// TemplateSigner signs the parts of tpl that can be signed
+// with the private keys of xpubs.
+type TemplateSigner func(ctx context.Context, tpl *Template, xpubs []chainkd.XPub) error
+
+// SignWith returns a TemplateSigner that signs templates
+// with Sign, calling signFn for each signature.
+func SignWith(signFn SignFunc) TemplateSigner {
+	return func(ctx context.Context, tpl *Template, xpubs []chainkd.XPub) error {
+		return Sign(ctx, tpl, xpubs, signFn)
+	}
+}
+
 func Sign(ctx context.Context, tpl *Template, xpubs []chainkd.XPub, signFn SignFunc) error {
 	for i, sigInst := range tpl.SigningInstructions {
-		for j, sw := range sigInst.SignatureWitnesses {
+		for j, wc := range sigInst.WitnessComponents {
+			sw, ok := wc.(signer)
+			if !ok {
+				continue
+			}
 			err := sw.sign(ctx, tpl, uint32(i), xpubs, signFn)
 			if err != nil {
 				return errors.WithDetailf(err, "adding signature(s) to witness component %d of input %d", j, i)
@@ -78,6 +102,143 @@ func Sign(ctx context.Context, tpl *Template, xpubs []chainkd.XPub, signFn SignF
 	return materializeWitnesses(tpl)
 }
 
+// Merge combines the inputs, outputs, and signing instructions of
+// tpls into a single template. The inputs and outputs of each
+// template are appended in order, so the first template's output
+// indexes are unchanged. The merged transaction's time range is the
+// intersection of the time ranges of tpls.
+//
+// Every signature already present in tpls must still be valid for
+// the merged transaction: each signed predicate is evaluated against
+// the merged transaction, and ErrBrokenCommitment is returned if any
+// of them (e.g., a constraint requiring a payment at a particular
+// output index) no longer holds.
+func Merge(tpls ...*Template) (*Template, error) {
+	if len(tpls) == 0 {
+		return nil, errors.Wrap(ErrNoTemplates)
+	}
+
+	merged := &Template{
+		Local:           true,
+		AllowAdditional: true,
+	}
+	var (
+		tx       legacy.TxData
+		spentIDs = make(map[bc.Hash]bool)
+	)
+	for i, tpl := range tpls {
+		if tpl.Transaction == nil {
+			return nil, errors.WithDetailf(ErrMissingRawTx, "template %d", i)
+		}
+		data := tpl.Transaction.TxData
+		if i == 0 {
+			tx.Version = data.Version
+		} else if data.Version != tx.Version {
+			return nil, errors.WithDetailf(ErrBadTxVersion, "template %d has version %d, want %d", i, data.Version, tx.Version)
+		}
+
+		if data.MinTime > tx.MinTime {
+			tx.MinTime = data.MinTime
+		}
+		if data.MaxTime > 0 && (tx.MaxTime == 0 || data.MaxTime < tx.MaxTime) {
+			tx.MaxTime = data.MaxTime
+		}
+		if len(data.ReferenceData) > 0 {
+			if len(tx.ReferenceData) > 0 && !bytes.Equal(tx.ReferenceData, data.ReferenceData) {
+				return nil, errors.WithDetailf(ErrBadRefData, "template %d", i)
+			}
+			tx.ReferenceData = data.ReferenceData
+		}
+
+		for _, in := range data.Inputs {
+			if in.IsIssuance() {
+				continue
+			}
+			id, err := in.SpentOutputID()
+			if err != nil {
+				return nil, errors.Wrapf(err, "template %d", i)
+			}
+			if spentIDs[id] {
+				return nil, errors.WithDetailf(ErrDuplicateInput, "template %d spends output %x", i, id.Bytes())
+			}
+			spentIDs[id] = true
+		}
+
+		offset := uint32(len(tx.Inputs))
+		for _, sigInst := range tpl.SigningInstructions {
+			if sigInst.Position >= uint32(len(data.Inputs)) {
+				return nil, errors.WithDetailf(ErrBadTxInputIdx, "template %d signing instruction references missing tx input %d", i, sigInst.Position)
+			}
+			merged.SigningInstructions = append(merged.SigningInstructions, &SigningInstruction{
+				Position:          offset + sigInst.Position,
+				WitnessComponents: sigInst.WitnessComponents,
+			})
+		}
+		tx.Inputs = append(tx.Inputs, data.Inputs...)
+		tx.Outputs = append(tx.Outputs, data.Outputs...)
+
+		merged.Local = merged.Local && tpl.Local
+		merged.AllowAdditional = merged.AllowAdditional && tpl.AllowAdditional
+	}
+	merged.Transaction = legacy.NewTx(tx)
+
+	err := checkSignedCommitments(merged)
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// checkSignedCommitments evaluates the predicate of every signature
+// witness in tpl that has at least one signature, ensuring that
+// whatever the signers committed to still holds in tpl's transaction.
+// Raw tx signatures commit to the tx sighash itself, so each one is
+// checked against the sighash of tpl's transaction.
+func checkSignedCommitments(tpl *Template) error {
+	tx := tpl.Transaction
+	for _, sigInst := range tpl.SigningInstructions {
+		for j, wc := range sigInst.WitnessComponents {
+			switch sw := wc.(type) {
+			case *SignatureWitness:
+				if len(sw.Program) == 0 || !hasSigs(sw.Sigs) {
+					continue
+				}
+				entry := tx.Entries[tx.InputIDs[sigInst.Position]]
+				prog := &bc.Program{VmVersion: 1, Code: sw.Program}
+				err := vm.Verify(validation.NewTxVMContext(tx.Tx, entry, prog, nil))
+				if err != nil {
+					return errors.WithDetailf(ErrBrokenCommitment, "witness component %d of input %d: %s", j, sigInst.Position, err)
+				}
+			case *RawTxSigWitness:
+				h := tpl.Hash(sigInst.Position)
+				for k, sig := range sw.Sigs {
+					if len(sig) == 0 || k >= len(sw.Keys) {
+						continue
+					}
+					key := sw.Keys[k]
+					path := make([][]byte, len(key.DerivationPath))
+					for i, p := range key.DerivationPath {
+						path[i] = p
+					}
+					if !key.XPub.Derive(path).Verify(h.Bytes(), sig) {
+						return errors.WithDetailf(ErrBrokenCommitment, "raw signature %d of witness component %d of input %d no longer matches the tx sighash", k, j, sigInst.Position)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasSigs(sigs []chainjson.HexBytes) bool {
+	for _, sig := range sigs {
+		if len(sig) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func checkBlankCheck(tx *legacy.TxData) error {
 	assetMap := make(map[bc.AssetID]int64)
 	var ok bool
@@ -1,6 +1,7 @@
 package txbuilder
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
@@ -74,7 +75,7 @@ func TestBuild(t *testing.T) {
 			ReferenceData: []byte("xyz"),
 		}),
 		SigningInstructions: []*SigningInstruction{{
-			SignatureWitnesses: []*signatureWitness{},
+			WitnessComponents: []witnessComponent{},
 		}},
 	}
 
@@ -131,8 +132,8 @@ func TestMaterializeWitnesses(t *testing.T) {
 	tpl := &Template{
 		Transaction: unsigned,
 		SigningInstructions: []*SigningInstruction{{
-			SignatureWitnesses: []*signatureWitness{
-				&signatureWitness{
+			WitnessComponents: []witnessComponent{
+				&SignatureWitness{
 					Quorum: 1,
 					Keys: []keyID{{
 						XPub:           pubkey,
@@ -210,8 +211,8 @@ func TestSignatureWitnessMaterialize(t *testing.T) {
 
 	// Test with more signatures than required, in correct order
 	tpl.SigningInstructions = []*SigningInstruction{{
-		SignatureWitnesses: []*signatureWitness{
-			&signatureWitness{
+		WitnessComponents: []witnessComponent{
+			&SignatureWitness{
 				Quorum: 2,
 				Keys: []keyID{
 					{
@@ -242,7 +243,7 @@ func TestSignatureWitnessMaterialize(t *testing.T) {
 	}
 
 	// Test with exact amount of signatures required, in correct order
-	component := tpl.SigningInstructions[0].SignatureWitnesses[0]
+	component := tpl.SigningInstructions[0].WitnessComponents[0].(*SignatureWitness)
 	component.Sigs = []json.HexBytes{sig1, sig2}
 	err = materializeWitnesses(tpl)
 	if err != nil {
@@ -254,6 +255,62 @@ func TestSignatureWitnessMaterialize(t *testing.T) {
 	}
 }
 
+func TestRawTxSigWitness(t *testing.T) {
+	privkey, pubkey, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 5, 0, nil, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(bc.AssetID{}, 5, []byte{1}, nil),
+			},
+		}),
+	}
+	path := [][]byte{{0, 0, 0, 1}}
+	tpl.SigningInstructions = []*SigningInstruction{{
+		WitnessComponents: []witnessComponent{
+			DataWitness{7},
+			NewRawTxSigWitness([]chainkd.XPub{pubkey}, path, 1),
+		},
+	}}
+
+	signFn := func(_ context.Context, xpub chainkd.XPub, path [][]byte, h [32]byte) ([]byte, error) {
+		if xpub != pubkey {
+			t.Fatalf("signing with unexpected xpub %x", xpub.Bytes())
+		}
+		return privkey.Derive(path).Sign(h[:]), nil
+	}
+	err = Sign(context.Background(), tpl, []chainkd.XPub{pubkey}, signFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := tpl.Transaction.Inputs[0].Arguments()
+	if len(args) != 2 {
+		t.Fatalf("got %d witness arguments, want 2", len(args))
+	}
+	if !bytes.Equal(args[0], []byte{7}) {
+		t.Errorf("data argument = %x want 07", args[0])
+	}
+	h := tpl.Hash(0)
+	if !pubkey.Derive(path).Verify(h.Bytes(), args[1]) {
+		t.Error("raw signature does not verify against the tx sighash")
+	}
+
+	// A raw signature no longer matches once the transaction changes.
+	tpl.Transaction.Outputs[0].Amount = 4
+	tpl.Transaction = legacy.NewTx(tpl.Transaction.TxData)
+	err = checkSignedCommitments(tpl)
+	if errors.Root(err) != ErrBrokenCommitment {
+		t.Errorf("got error %v, want %v", err, ErrBrokenCommitment)
+	}
+}
+
 func mustDecodeHex(str string) []byte {
 	data, err := hex.DecodeString(str)
 	if err != nil {
@@ -493,3 +550,77 @@ func TestCheckBlankCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	_, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signFn := func(context.Context, chainkd.XPub, [][]byte, [32]byte) ([]byte, error) {
+		return []byte{1}, nil
+	}
+
+	assetID1 := bc.NewAssetID([32]byte{1})
+	assetID2 := bc.NewAssetID([32]byte{2})
+	newTemplate := func(src byte, spend, pay bc.AssetID, maxTime uint64) *Template {
+		tpl := &Template{
+			Transaction: legacy.NewTx(legacy.TxData{
+				Version: 1,
+				MaxTime: maxTime,
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, bc.NewHash([32]byte{src}), spend, 5, 0, nil, bc.Hash{}, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					legacy.NewTxOutput(pay, 5, []byte{src}, nil),
+				},
+			}),
+			SigningInstructions: []*SigningInstruction{{}},
+			AllowAdditional:     true,
+		}
+		tpl.SigningInstructions[0].AddWitnessKeys([]chainkd.XPub{xpub}, nil, 1)
+		return tpl
+	}
+
+	// Alice offers assetID1 for assetID2 and signs first. Bob's
+	// counter-offer is unsigned, so it may be appended to Alice's.
+	alice := newTemplate(0xa, assetID1, assetID2, 2000)
+	err = Sign(ctx, alice, []chainkd.XPub{xpub}, signFn)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	bob := newTemplate(0xb, assetID2, assetID1, 1000)
+
+	got, err := Merge(alice, bob)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(got.Transaction.Inputs) != 2 || len(got.Transaction.Outputs) != 2 {
+		t.Fatalf("got %d inputs and %d outputs, want 2 and 2", len(got.Transaction.Inputs), len(got.Transaction.Outputs))
+	}
+	if got.Transaction.MaxTime != 1000 {
+		t.Errorf("got max time %d, want 1000", got.Transaction.MaxTime)
+	}
+	for i, sigInst := range got.SigningInstructions {
+		if sigInst.Position != uint32(i) {
+			t.Errorf("signing instruction %d has position %d, want %d", i, sigInst.Position, i)
+		}
+	}
+
+	// Putting Bob's output first moves the payment Alice's signature
+	// commits to.
+	_, err = Merge(bob, alice)
+	if errors.Root(err) != ErrBrokenCommitment {
+		t.Errorf("Merge(bob, alice) err = %v, want ErrBrokenCommitment", errors.Root(err))
+	}
+
+	_, err = Merge(alice, alice)
+	if errors.Root(err) != ErrDuplicateInput {
+		t.Errorf("Merge(alice, alice) err = %v, want ErrDuplicateInput", errors.Root(err))
+	}
+
+	_, err = Merge()
+	if errors.Root(err) != ErrNoTemplates {
+		t.Errorf("Merge() err = %v, want ErrNoTemplates", errors.Root(err))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"chain/crypto/ed25519/chainkd"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/protocol/bc"
@@ -36,17 +37,29 @@ func (t *Template) Hash(idx uint32) bc.Hash {
 
 // SigningInstruction gives directions for signing inputs in a TxTemplate.
 type SigningInstruction struct {
-	Position           uint32              `json:"position"`
-	SignatureWitnesses []*signatureWitness `json:"witness_components,omitempty"`
+	Position          uint32             `json:"position"`
+	WitnessComponents []witnessComponent `json:"witness_components,omitempty"`
+}
+
+// witnessComponent is the abstract type for the parts of a
+// SigningInstruction. Each witnessComponent produces one or more
+// arguments for a VM program via its materialize method. Concrete
+// witnessComponent types include SignatureWitness, RawTxSigWitness
+// and DataWitness.
+type witnessComponent interface {
+	materialize(*Template, uint32, *[][]byte) error
+}
+
+// signer is implemented by witness components that hold signatures
+// to be added during Sign.
+type signer interface {
+	sign(context.Context, *Template, uint32, []chainkd.XPub, SignFunc) error
 }
 
 func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 	var pre struct {
-		Position           uint32 `json:"position"`
-		SignatureWitnesses []struct {
-			Type string
-			signatureWitness
-		} `json:"witness_components"`
+		Position          uint32            `json:"position"`
+		WitnessComponents []json.RawMessage `json:"witness_components"`
 	}
 	err := json.Unmarshal(b, &pre)
 	if err != nil {
@@ -54,12 +67,36 @@ func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 	}
 
 	si.Position = pre.Position
-	si.SignatureWitnesses = make([]*signatureWitness, 0, len(pre.SignatureWitnesses))
-	for i, w := range pre.SignatureWitnesses {
-		if w.Type != "signature" {
-			return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d has unknown type '%s'", i, w.Type)
+	si.WitnessComponents = make([]witnessComponent, 0, len(pre.WitnessComponents))
+	for i, wc := range pre.WitnessComponents {
+		var t struct{ Type string }
+		err = json.Unmarshal(wc, &t)
+		if err != nil {
+			return errors.Wrapf(err, "unmarshaling witness component %d type", i)
+		}
+		var component witnessComponent
+		switch t.Type {
+		case "data":
+			var d struct {
+				Value chainjson.HexBytes `json:"value"`
+			}
+			err = json.Unmarshal(wc, &d)
+			component = DataWitness(d.Value)
+		case "raw_tx_signature":
+			w := new(RawTxSigWitness)
+			err = json.Unmarshal(wc, w)
+			component = w
+		case "signature":
+			w := new(SignatureWitness)
+			err = json.Unmarshal(wc, w)
+			component = w
+		default:
+			return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d has unknown type '%s'", i, t.Type)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unmarshaling witness component %d of type %s", i, t.Type)
 		}
-		si.SignatureWitnesses = append(si.SignatureWitnesses, &w.signatureWitness)
+		si.WitnessComponents = append(si.WitnessComponents, component)
 	}
 	return nil
 }
@@ -38,7 +38,7 @@ func materializeWitnesses(txTemplate *Template) error {
 		}
 
 		var witness [][]byte
-		for j, sw := range sigInst.SignatureWitnesses {
+		for j, sw := range sigInst.WitnessComponents {
 			err := sw.materialize(txTemplate, sigInst.Position, &witness)
 			if err != nil {
 				return errors.WithDetailf(err, "error in witness component %d of input %d", j, i)
@@ -52,7 +52,11 @@ func materializeWitnesses(txTemplate *Template) error {
 }
 
 type (
-	signatureWitness struct {
+	// SignatureWitness is a witness component that signs a predicate
+	// program, either the tx sighash or a set of constraints on the
+	// transaction, and materializes into the arguments for a
+	// CHECKPREDICATE of that program.
+	SignatureWitness struct {
 		// Quorum is the number of signatures required.
 		Quorum int `json:"quorum"`
 
@@ -87,7 +91,7 @@ var ErrEmptyProgram = errors.New("empty signature program")
 //  - the mintime and maxtime of the transaction (if non-zero)
 //  - the outputID and (if non-empty) reference data of the current input
 //  - the assetID, amount, control program, and (if non-empty) reference data of each output.
-func (sw *signatureWitness) sign(ctx context.Context, tpl *Template, index uint32, xpubs []chainkd.XPub, signFn SignFunc) error {
+func (sw *SignatureWitness) sign(ctx context.Context, tpl *Template, index uint32, xpubs []chainkd.XPub, signFn SignFunc) error {
 	// Compute the predicate to sign. This is either a
 	// txsighash program if tpl.AllowAdditional is false (i.e., the tx is complete
 	// and no further changes are allowed) or a program enforcing
@@ -191,7 +195,7 @@ func buildSigProgram(tpl *Template, index uint32) []byte {
 	return program
 }
 
-func (sw signatureWitness) materialize(tpl *Template, index uint32, args *[][]byte) error {
+func (sw SignatureWitness) materialize(tpl *Template, index uint32, args *[][]byte) error {
 	// This is the value of N for the CHECKPREDICATE call. The code
 	// assumes that everything already in the arg list before this call
 	// to Materialize is input to the signature program, so N is
@@ -209,7 +213,7 @@ func (sw signatureWitness) materialize(tpl *Template, index uint32, args *[][]by
 	return nil
 }
 
-func (sw signatureWitness) MarshalJSON() ([]byte, error) {
+func (sw SignatureWitness) MarshalJSON() ([]byte, error) {
 	obj := struct {
 		Type   string               `json:"type"`
 		Quorum int                  `json:"quorum"`
@@ -224,23 +228,122 @@ func (sw signatureWitness) MarshalJSON() ([]byte, error) {
 	return json.Marshal(obj)
 }
 
-// AddWitnessKeys adds a signatureWitness with the given quorum and
+// RawTxSigWitness is a witness component that signs the tx sighash
+// of its input directly, rather than a predicate program. It is used
+// for programs, such as compiled Ivy contracts, that check signatures
+// against the tx sighash themselves. It materializes into the
+// signatures alone.
+type RawTxSigWitness struct {
+	Quorum int                  `json:"quorum"`
+	Keys   []keyID              `json:"keys"`
+	Sigs   []chainjson.HexBytes `json:"signatures"`
+}
+
+// NewRawTxSigWitness returns a RawTxSigWitness requiring quorum
+// signatures from the keys derived by applying the derivation path
+// to each of the xpubs.
+func NewRawTxSigWitness(xpubs []chainkd.XPub, path [][]byte, quorum int) *RawTxSigWitness {
+	return &RawTxSigWitness{
+		Quorum: quorum,
+		Keys:   keyIDs(xpubs, path),
+	}
+}
+
+func (sw *RawTxSigWitness) sign(ctx context.Context, tpl *Template, index uint32, xpubs []chainkd.XPub, signFn SignFunc) error {
+	if len(sw.Sigs) < len(sw.Keys) {
+		// See the comment in SignatureWitness.sign.
+		newSigs := make([]chainjson.HexBytes, len(sw.Keys))
+		copy(newSigs, sw.Sigs)
+		sw.Sigs = newSigs
+	}
+	h := tpl.Hash(tpl.SigningInstructions[index].Position).Byte32()
+	for i, keyID := range sw.Keys {
+		if len(sw.Sigs[i]) > 0 {
+			// Already have a signature for this key
+			continue
+		}
+		if !contains(xpubs, keyID.XPub) {
+			continue
+		}
+		path := make([][]byte, len(keyID.DerivationPath))
+		for i, p := range keyID.DerivationPath {
+			path[i] = p
+		}
+		sigBytes, err := signFn(ctx, keyID.XPub, path, h)
+		if err != nil {
+			return errors.WithDetailf(err, "computing raw signature %d", i)
+		}
+		sw.Sigs[i] = sigBytes
+	}
+	return nil
+}
+
+func (sw RawTxSigWitness) materialize(tpl *Template, index uint32, args *[][]byte) error {
+	var nsigs int
+	for i := 0; i < len(sw.Sigs) && nsigs < sw.Quorum; i++ {
+		if len(sw.Sigs[i]) > 0 {
+			*args = append(*args, sw.Sigs[i])
+			nsigs++
+		}
+	}
+	return nil
+}
+
+func (sw RawTxSigWitness) MarshalJSON() ([]byte, error) {
+	obj := struct {
+		Type   string               `json:"type"`
+		Quorum int                  `json:"quorum"`
+		Keys   []keyID              `json:"keys"`
+		Sigs   []chainjson.HexBytes `json:"signatures"`
+	}{
+		Type:   "raw_tx_signature",
+		Quorum: sw.Quorum,
+		Keys:   sw.Keys,
+		Sigs:   sw.Sigs,
+	}
+	return json.Marshal(obj)
+}
+
+// DataWitness is a witness component that materializes into a
+// single, literal argument.
+type DataWitness chainjson.HexBytes
+
+func (d DataWitness) materialize(_ *Template, _ uint32, args *[][]byte) error {
+	*args = append(*args, d)
+	return nil
+}
+
+func (d DataWitness) MarshalJSON() ([]byte, error) {
+	obj := struct {
+		Type  string             `json:"type"`
+		Value chainjson.HexBytes `json:"value"`
+	}{
+		Type:  "data",
+		Value: chainjson.HexBytes(d),
+	}
+	return json.Marshal(obj)
+}
+
+// AddWitnessKeys adds a SignatureWitness with the given quorum and
 // list of keys derived by applying the derivation path to each of the
 // xpubs.
 func (si *SigningInstruction) AddWitnessKeys(xpubs []chainkd.XPub, path [][]byte, quorum int) {
+	sw := &SignatureWitness{
+		Quorum: quorum,
+		Keys:   keyIDs(xpubs, path),
+	}
+	si.WitnessComponents = append(si.WitnessComponents, sw)
+}
+
+func keyIDs(xpubs []chainkd.XPub, path [][]byte) []keyID {
 	hexPath := make([]chainjson.HexBytes, 0, len(path))
 	for _, p := range path {
 		hexPath = append(hexPath, p)
 	}
 
-	keyIDs := make([]keyID, 0, len(xpubs))
+	ids := make([]keyID, 0, len(xpubs))
 	for _, xpub := range xpubs {
-		keyIDs = append(keyIDs, keyID{xpub, hexPath})
-	}
-
-	sw := &signatureWitness{
-		Quorum: quorum,
-		Keys:   keyIDs,
+		ids = append(ids, keyID{xpub, hexPath})
 	}
-	si.SignatureWitnesses = append(si.SignatureWitnesses, sw)
+	return ids
 }
@@ -49,8 +49,8 @@ func TestInferConstraints(t *testing.T) {
 func TestWitnessJSON(t *testing.T) {
 	si := &SigningInstruction{
 		Position: 17,
-		SignatureWitnesses: []*signatureWitness{
-			&signatureWitness{
+		WitnessComponents: []witnessComponent{
+			&SignatureWitness{
 				Quorum: 4,
 				Keys: []keyID{{
 					XPub:           testutil.TestXPub,
@@ -58,6 +58,15 @@ func TestWitnessJSON(t *testing.T) {
 				}},
 				Sigs: []chainjson.HexBytes{{8, 9, 10}},
 			},
+			&RawTxSigWitness{
+				Quorum: 1,
+				Keys: []keyID{{
+					XPub:           testutil.TestXPub,
+					DerivationPath: []chainjson.HexBytes{{11}},
+				}},
+				Sigs: []chainjson.HexBytes{{12, 13}},
+			},
+			DataWitness{14, 15},
 		},
 	}
 
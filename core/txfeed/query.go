@@ -11,7 +11,7 @@ import (
 // Query queries the Chain Core for txfeeds matching the query.
 func (t *Tracker) Query(ctx context.Context, after string, limit int) ([]*TxFeed, string, error) {
 	const baseQ = `
-		SELECT id, alias, filter, after FROM txfeeds
+		SELECT id, alias, filter, after, webhook_url, webhook_secret FROM txfeeds
 		WHERE ($1='' OR id < $1) ORDER BY id DESC LIMIT %d
 	`
 	rows, err := t.DB.QueryContext(ctx, fmt.Sprintf(baseQ, limit), after)
@@ -23,10 +23,11 @@ func (t *Tracker) Query(ctx context.Context, after string, limit int) ([]*TxFeed
 	txfeeds := make([]*TxFeed, 0, limit)
 	for rows.Next() {
 		var (
-			feed  TxFeed
-			alias sql.NullString
+			feed                TxFeed
+			alias               sql.NullString
+			hookURL, hookSecret sql.NullString
 		)
-		err := rows.Scan(&feed.ID, &alias, &feed.Filter, &feed.After)
+		err := rows.Scan(&feed.ID, &alias, &feed.Filter, &feed.After, &hookURL, &hookSecret)
 		if err != nil {
 			return nil, "", errors.Wrap(err, "scanning txfeed row")
 		}
@@ -34,6 +35,7 @@ func (t *Tracker) Query(ctx context.Context, after string, limit int) ([]*TxFeed
 		if alias.Valid {
 			feed.Alias = &alias.String
 		}
+		feed.Webhook = nullWebhook(hookURL, hookSecret)
 		after = feed.ID
 		txfeeds = append(txfeeds, &feed)
 	}
@@ -5,31 +5,49 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"net/url"
 
 	"chain/core/query"
 	"chain/database/pg"
 	"chain/errors"
 )
 
-var ErrDuplicateAlias = errors.New("duplicate feed alias")
+var (
+	ErrDuplicateAlias = errors.New("duplicate feed alias")
+	ErrBadWebhook     = errors.New("invalid webhook")
+)
 
 type Tracker struct {
 	DB pg.DB
 }
 
 type TxFeed struct {
-	ID     string  `json:"id,omitempty"`
-	Alias  *string `json:"alias"`
-	Filter string  `json:"filter,omitempty"`
-	After  string  `json:"after,omitempty"`
+	ID      string   `json:"id,omitempty"`
+	Alias   *string  `json:"alias"`
+	Filter  string   `json:"filter,omitempty"`
+	After   string   `json:"after,omitempty"`
+	Webhook *Webhook `json:"webhook,omitempty"`
+}
+
+// Webhook configures a feed to push each of its transactions to a
+// URL. Each request body is signed with Secret; see Deliverer.
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"-"`
 }
 
-func (t *Tracker) Create(ctx context.Context, alias, fil, after string, clientToken string) (*TxFeed, error) {
+func (t *Tracker) Create(ctx context.Context, alias, fil, after string, clientToken string, hook *Webhook) (*TxFeed, error) {
 	// Validate the filter.
 	err := query.ValidateTransactionFilter(fil)
 	if err != nil {
 		return nil, err
 	}
+	if hook != nil {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.WithDetailf(ErrBadWebhook, "invalid url %q", hook.URL)
+		}
+	}
 
 	var ptrAlias *string
 	if alias != "" {
@@ -37,9 +55,10 @@ func (t *Tracker) Create(ctx context.Context, alias, fil, after string, clientTo
 	}
 
 	feed := &TxFeed{
-		Alias:  ptrAlias,
-		Filter: fil,
-		After:  after,
+		Alias:   ptrAlias,
+		Filter:  fil,
+		After:   after,
+		Webhook: hook,
 	}
 	return insertTxFeed(ctx, t.DB, feed, clientToken)
 }
@@ -49,8 +68,8 @@ func (t *Tracker) Create(ctx context.Context, alias, fil, after string, clientTo
 // lookup and return the existing txfeed instead.
 func insertTxFeed(ctx context.Context, db pg.DB, feed *TxFeed, clientToken string) (*TxFeed, error) {
 	const q = `
-		INSERT INTO txfeeds (alias, filter, after, client_token)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO txfeeds (alias, filter, after, client_token, webhook_url, webhook_secret)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (client_token) DO NOTHING
 		RETURNING id
 	`
@@ -65,9 +84,15 @@ func insertTxFeed(ctx context.Context, db pg.DB, feed *TxFeed, clientToken strin
 		Valid:  clientToken != "",
 	}
 
+	var hookURL, hookSecret sql.NullString
+	if feed.Webhook != nil {
+		hookURL = sql.NullString{Valid: true, String: feed.Webhook.URL}
+		hookSecret = sql.NullString{Valid: true, String: feed.Webhook.Secret}
+	}
+
 	err := db.QueryRowContext(
 		ctx, q, alias, feed.Filter, feed.After,
-		nullToken).Scan(&feed.ID)
+		nullToken, hookURL, hookSecret).Scan(&feed.ID)
 
 	if pg.IsUniqueViolation(err) {
 		return nil, errors.WithDetail(ErrDuplicateAlias, "a transaction feed with the provided alias already exists")
@@ -87,16 +112,17 @@ func insertTxFeed(ctx context.Context, db pg.DB, feed *TxFeed, clientToken strin
 
 func txfeedByClientToken(ctx context.Context, db pg.DB, clientToken string) (*TxFeed, error) {
 	const q = `
-		SELECT id, alias, filter, after
+		SELECT id, alias, filter, after, webhook_url, webhook_secret
 		FROM txfeeds
 		WHERE client_token=$1
 	`
 
 	var (
-		feed  TxFeed
-		alias sql.NullString
+		feed                TxFeed
+		alias               sql.NullString
+		hookURL, hookSecret sql.NullString
 	)
-	err := db.QueryRowContext(ctx, q, clientToken).Scan(&feed.ID, &alias, &feed.Filter, &feed.After)
+	err := db.QueryRowContext(ctx, q, clientToken).Scan(&feed.ID, &alias, &feed.Filter, &feed.After, &hookURL, &hookSecret)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +130,7 @@ func txfeedByClientToken(ctx context.Context, db pg.DB, clientToken string) (*Tx
 	if alias.Valid {
 		feed.Alias = &alias.String
 	}
+	feed.Webhook = nullWebhook(hookURL, hookSecret)
 
 	return &feed, nil
 }
@@ -112,7 +139,7 @@ func (t *Tracker) Find(ctx context.Context, id, alias string) (*TxFeed, error) {
 	var q bytes.Buffer
 
 	q.WriteString(`
-		SELECT id, alias, filter, after
+		SELECT id, alias, filter, after, webhook_url, webhook_secret
 		FROM txfeeds
 		WHERE
 	`)
@@ -125,11 +152,12 @@ func (t *Tracker) Find(ctx context.Context, id, alias string) (*TxFeed, error) {
 	}
 
 	var (
-		feed     TxFeed
-		sqlAlias sql.NullString
+		feed                TxFeed
+		sqlAlias            sql.NullString
+		hookURL, hookSecret sql.NullString
 	)
 
-	err := t.DB.QueryRowContext(ctx, q.String(), id).Scan(&feed.ID, &sqlAlias, &feed.Filter, &feed.After)
+	err := t.DB.QueryRowContext(ctx, q.String(), id).Scan(&feed.ID, &sqlAlias, &feed.Filter, &feed.After, &hookURL, &hookSecret)
 	if err == sql.ErrNoRows {
 		err = errors.Sub(pg.ErrUserInputNotFound, err)
 		err = errors.WithDetailf(err, "alias: %s", alias)
@@ -142,6 +170,7 @@ func (t *Tracker) Find(ctx context.Context, id, alias string) (*TxFeed, error) {
 	if sqlAlias.Valid {
 		feed.Alias = &sqlAlias.String
 	}
+	feed.Webhook = nullWebhook(hookURL, hookSecret)
 
 	return &feed, nil
 }
@@ -209,3 +238,11 @@ func (t *Tracker) Update(ctx context.Context, id, alias, after, prev string) (*T
 		After: after,
 	}, nil
 }
+
+// nullWebhook returns the webhook stored in a txfeeds row, if any.
+func nullWebhook(u, secret sql.NullString) *Webhook {
+	if !u.Valid {
+		return nil
+	}
+	return &Webhook{URL: u.String, Secret: secret.String}
+}
@@ -0,0 +1,363 @@
+package txfeed
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"chain/core/query"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+// Headers set on each webhook request.
+const (
+	// SignatureHeader holds the hex-encoded HMAC-SHA256 of the
+	// request body, keyed with the webhook's secret and prefixed
+	// with "sha256=".
+	SignatureHeader = "Chain-Webhook-Signature"
+
+	// CursorHeader holds the feed's after cursor immediately
+	// following the delivered transaction.
+	CursorHeader = "Chain-Webhook-Cursor"
+)
+
+// Delivery statuses.
+const (
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultBackoff     = time.Second
+	maxBackoff         = 5 * time.Minute
+	pollTimeout        = 30 * time.Second
+	deliveryPageSize   = 100
+)
+
+// Delivery records the outcome of posting one transaction to a
+// feed's webhook. A delivery with status "failed" exhausted its
+// retries; it is kept as a dead letter and the feed moves on.
+type Delivery struct {
+	ID            string    `json:"id"`
+	FeedID        string    `json:"transaction_feed_id"`
+	TransactionID bc.Hash   `json:"transaction_id"`
+	Cursor        string    `json:"cursor"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Deliveries returns deliveries for the feed with the given id,
+// most recent first. If status is not empty, only deliveries with
+// that status are returned.
+func (t *Tracker) Deliveries(ctx context.Context, feedID, status, after string, limit int) ([]*Delivery, string, error) {
+	const baseQ = `
+		SELECT id, feed_id, tx_id, cursor, status, attempts, last_error, created_at
+		FROM txfeed_deliveries
+		WHERE feed_id=$1 AND ($2='' OR status=$2) AND ($3='' OR id < $3)
+		ORDER BY id DESC LIMIT %d
+	`
+	rows, err := t.DB.QueryContext(ctx, fmt.Sprintf(baseQ, limit), feedID, status, after)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "executing deliveries query")
+	}
+	defer rows.Close()
+
+	deliveries := make([]*Delivery, 0, limit)
+	for rows.Next() {
+		d := new(Delivery)
+		err := rows.Scan(&d.ID, &d.FeedID, &d.TransactionID, &d.Cursor,
+			&d.Status, &d.Attempts, &d.LastError, &d.CreatedAt)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "scanning delivery row")
+		}
+		deliveries = append(deliveries, d)
+		after = d.ID
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, "", errors.Wrap(err)
+	}
+	return deliveries, after, nil
+}
+
+func (t *Tracker) insertDelivery(ctx context.Context, d *Delivery) error {
+	const q = `
+		INSERT INTO txfeed_deliveries (feed_id, tx_id, cursor, status, attempts, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := t.DB.QueryRowContext(ctx, q, d.FeedID, d.TransactionID, d.Cursor,
+		d.Status, d.Attempts, d.LastError).Scan(&d.ID, &d.CreatedAt)
+	return errors.Wrap(err, "inserting delivery")
+}
+
+// Replay moves the after cursor of the feed with the given id or
+// alias to after, regardless of its current value. Transactions
+// following the new cursor are delivered again.
+func (t *Tracker) Replay(ctx context.Context, id, alias, after string) (*TxFeed, error) {
+	q, key := `UPDATE txfeeds SET after=$1 WHERE id=$2`, id
+	if id == "" {
+		q, key = `UPDATE txfeeds SET after=$1 WHERE alias=$2`, alias
+	}
+	res, err := t.DB.ExecContext(ctx, q, after, key)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "could not find txfeed with id/alias=%s", key)
+	}
+	return t.Find(ctx, id, alias)
+}
+
+// Deliverer posts the transactions of every feed with a webhook to
+// the webhook's URL, in blockchain order. A feed's cursor advances
+// once its transaction has been delivered or has exhausted its
+// retries, so each transaction is delivered at least once.
+type Deliverer struct {
+	Tracker *Tracker
+	Indexer *query.Indexer
+	Client  *http.Client
+
+	// MaxAttempts is the number of times to try posting a
+	// transaction before recording it as failed.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles
+	// after each further attempt.
+	Backoff time.Duration
+
+	mu      sync.Mutex
+	running map[string]bool // feed id -> has a delivery goroutine
+}
+
+// Run looks for webhook feeds every period and starts delivering
+// each one it finds. It returns when ctx is canceled.
+func (d *Deliverer) Run(ctx context.Context, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		err := d.startFeeds(ctx)
+		if err != nil {
+			log.Error(ctx, err, "starting webhook deliveries")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+		}
+	}
+}
+
+func (d *Deliverer) startFeeds(ctx context.Context) error {
+	var after string
+	for {
+		feeds, next, err := d.Tracker.Query(ctx, after, deliveryPageSize)
+		if err != nil {
+			return err
+		}
+		for _, feed := range feeds {
+			if feed.Webhook == nil {
+				continue
+			}
+			d.mu.Lock()
+			if d.running == nil {
+				d.running = make(map[string]bool)
+			}
+			if !d.running[feed.ID] {
+				d.running[feed.ID] = true
+				go d.deliverFeed(ctx, feed.ID)
+			}
+			d.mu.Unlock()
+		}
+		if len(feeds) < deliveryPageSize {
+			return nil
+		}
+		after = next
+	}
+}
+
+// deliverFeed delivers the feed's transactions until the feed is
+// deleted, loses its webhook, or ctx is canceled.
+func (d *Deliverer) deliverFeed(ctx context.Context, id string) {
+	defer func() {
+		d.mu.Lock()
+		delete(d.running, id)
+		d.mu.Unlock()
+	}()
+
+	for ctx.Err() == nil {
+		// Reload the feed each time around, in case its cursor was
+		// replayed or it was deleted.
+		feed, err := d.Tracker.Find(ctx, id, "")
+		if errors.Root(err) == pg.ErrUserInputNotFound {
+			return
+		}
+		if err != nil {
+			log.Error(ctx, err, "loading txfeed", id)
+			sleep(ctx, d.backoff())
+			continue
+		}
+		if feed.Webhook == nil {
+			return
+		}
+		after, err := query.DecodeTxAfter(feed.After)
+		if err != nil {
+			log.Error(ctx, errors.Wrap(err, "decoding feed `after`"), "txfeed", id)
+			return
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+		txs, _, err := d.Indexer.Transactions(waitCtx, feed.Filter, nil, after, deliveryPageSize, true)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Root(err) == context.DeadlineExceeded {
+			continue
+		}
+		if err != nil {
+			log.Error(ctx, err, "querying txfeed", id)
+			sleep(ctx, d.backoff())
+			continue
+		}
+
+		for _, tx := range txs {
+			cur := query.TxAfter{
+				FromBlockHeight: tx.BlockHeight,
+				FromPosition:    tx.Position,
+				StopBlockHeight: after.StopBlockHeight,
+			}.String()
+			dlv := d.deliver(ctx, feed.Webhook, cur, tx)
+			if ctx.Err() != nil {
+				return
+			}
+			dlv.FeedID = feed.ID
+			err = d.Tracker.insertDelivery(ctx, dlv)
+			if err != nil {
+				log.Error(ctx, err, "txfeed", id)
+			}
+
+			// If the cursor moved underneath us, the feed was replayed;
+			// start again from its new position.
+			_, err = d.Tracker.Update(ctx, feed.ID, "", cur, feed.After)
+			if err != nil {
+				break
+			}
+			feed.After = cur
+		}
+	}
+}
+
+// deliver posts tx to hook, retrying with exponential backoff until
+// the receiver responds with a 2xx status or the attempts run out.
+// The returned delivery has every field set except FeedID, ID and
+// CreatedAt.
+func (d *Deliverer) deliver(ctx context.Context, hook *Webhook, cursor string, tx *query.AnnotatedTx) *Delivery {
+	dlv := &Delivery{
+		TransactionID: tx.ID,
+		Cursor:        cursor,
+		Status:        StatusFailed,
+	}
+	body, err := json.Marshal(tx)
+	if err != nil {
+		dlv.LastError = errors.Wrap(err, "marshaling annotated transaction").Error()
+		return dlv
+	}
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	backoff := d.backoff()
+	for dlv.Attempts < maxAttempts {
+		if dlv.Attempts > 0 {
+			if !sleep(ctx, backoff) {
+				return dlv
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		dlv.Attempts++
+		err = d.post(ctx, hook, cursor, body)
+		if err == nil {
+			dlv.Status = StatusDelivered
+			dlv.LastError = ""
+			return dlv
+		}
+		dlv.LastError = err.Error()
+	}
+	return dlv
+}
+
+func (d *Deliverer) post(ctx context.Context, hook *Webhook, cursor string, body []byte) error {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, body))
+	req.Header.Set(CursorHeader, cursor)
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Deliverer) backoff() time.Duration {
+	if d.Backoff <= 0 {
+		return defaultBackoff
+	}
+	return d.Backoff
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body keyed with
+// secret. Receivers can compare it to the SignatureHeader value to
+// verify that a request came from this core.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sleep waits for d or until ctx is canceled. It reports whether
+// the full duration elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
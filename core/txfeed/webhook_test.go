@@ -0,0 +1,113 @@
+package txfeed
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/core/query"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestDeliver(t *testing.T) {
+	const secret = "s3cret"
+	tx := &query.AnnotatedTx{ID: bc.NewHash([32]byte{1}), BlockHeight: 5, Position: 2}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := req.Header.Get(SignatureHeader), "sha256="+Sign(secret, body); got != want {
+			t.Errorf("signature = %q want %q", got, want)
+		}
+		if got := req.Header.Get(CursorHeader); got != "5:2-10" {
+			t.Errorf("cursor = %q want %q", got, "5:2-10")
+		}
+		var got query.AnnotatedTx
+		err = json.Unmarshal(body, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != tx.ID {
+			t.Errorf("tx id = %x want %x", got.ID.Bytes(), tx.ID.Bytes())
+		}
+	}))
+	defer srv.Close()
+
+	d := &Deliverer{MaxAttempts: 5, Backoff: time.Millisecond}
+	dlv := d.deliver(context.Background(), &Webhook{URL: srv.URL, Secret: secret}, "5:2-10", tx)
+	if dlv.Status != StatusDelivered {
+		t.Errorf("status = %s want %s (last error %q)", dlv.Status, StatusDelivered, dlv.LastError)
+	}
+	if dlv.Attempts != 3 {
+		t.Errorf("attempts = %d want 3", dlv.Attempts)
+	}
+	if dlv.TransactionID != tx.ID || dlv.Cursor != "5:2-10" {
+		t.Errorf("delivery = %+v", dlv)
+	}
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := &Deliverer{MaxAttempts: 4, Backoff: time.Millisecond}
+	dlv := d.deliver(context.Background(), &Webhook{URL: srv.URL}, "1:0-1", &query.AnnotatedTx{})
+	if dlv.Status != StatusFailed {
+		t.Errorf("status = %s want %s", dlv.Status, StatusFailed)
+	}
+	if dlv.Attempts != 4 || calls != 4 {
+		t.Errorf("attempts = %d, calls = %d, want 4", dlv.Attempts, calls)
+	}
+	if dlv.LastError == "" {
+		t.Error("expected last error to be recorded")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	tracker := &Tracker{DB: pgtest.NewTx(t)}
+	alias := "replayed"
+	feed, err := insertTxFeed(ctx, tracker.DB, &TxFeed{Alias: &alias, After: "5:2-10"}, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct{ id, alias, after string }{
+		{feed.ID, "", "3:0-10"},
+		{"", alias, "1:0-10"},
+	}
+	for _, c := range cases {
+		got, err := tracker.Replay(ctx, c.id, c.alias, c.after)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.ID != feed.ID || got.After != c.after {
+			t.Errorf("Replay(%q, %q) = {ID: %s, After: %s}, want {ID: %s, After: %s}", c.id, c.alias, got.ID, got.After, feed.ID, c.after)
+		}
+	}
+
+	_, err = tracker.Replay(ctx, "", "nonexistent", "1:0-10")
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("Replay(nonexistent) error = %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+}
@@ -2,15 +2,24 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
+	"time"
 
 	"chain/core/query"
 	"chain/core/txfeed"
 	"chain/errors"
+	"chain/log"
 	"chain/net/http/httpjson"
 )
 
+// streamKeepAlive is how often an idle transaction feed stream
+// sends a comment line, so that proxies and clients can tell the
+// connection is still alive.
+const streamKeepAlive = 30 * time.Second
+
 // POST /create-txfeed
 func (a *API) createTxFeed(ctx context.Context, in struct {
 	Alias  string
@@ -21,9 +30,21 @@ func (a *API) createTxFeed(ctx context.Context, in struct {
 	// idempotency of create txfeed requests. Duplicate create txfeed requests
 	// with the same client_token will only create one txfeed.
 	ClientToken string `json:"client_token"`
+
+	// Webhook, if set, makes the core post each transaction
+	// matching the filter to Webhook.URL. Each request carries an
+	// HMAC-SHA256 signature of its body keyed with Webhook.Secret.
+	Webhook *struct {
+		URL    string `json:"url"`
+		Secret string `json:"secret"`
+	} `json:"webhook"`
 }) (*txfeed.TxFeed, error) {
+	var hook *txfeed.Webhook
+	if in.Webhook != nil {
+		hook = &txfeed.Webhook{URL: in.Webhook.URL, Secret: in.Webhook.Secret}
+	}
 	after := fmt.Sprintf("%d:%d-%d", a.chain.Height(), math.MaxInt32, uint64(math.MaxInt64))
-	return a.txFeeds.Create(ctx, in.Alias, in.Filter, after, in.ClientToken)
+	return a.txFeeds.Create(ctx, in.Alias, in.Filter, after, in.ClientToken, hook)
 }
 
 // POST /get-transaction-feed
@@ -64,6 +85,120 @@ func (a *API) updateTxFeed(ctx context.Context, in struct {
 	return a.txFeeds.Update(ctx, in.ID, in.Alias, in.After, in.Prev)
 }
 
+// POST /replay-transaction-feed
+//
+// replayTxFeed moves the feed's after cursor to the given value,
+// even if that is before its current value, so that transactions
+// following it are delivered again.
+func (a *API) replayTxFeed(ctx context.Context, in struct {
+	ID    string `json:"id,omitempty"`
+	Alias string `json:"alias,omitempty"`
+	After string `json:"after"`
+}) (*txfeed.TxFeed, error) {
+	_, err := query.DecodeTxAfter(in.After)
+	if err != nil {
+		return nil, err
+	}
+	return a.txFeeds.Replay(ctx, in.ID, in.Alias, in.After)
+}
+
+// POST /stream-transaction-feed
+//
+// streamTxFeed holds the connection open and sends each transaction
+// matching the feed's filter as a Server-Sent Event, in the order the
+// transactions appear in the blockchain. Each event's id is the value
+// of the feed's after cursor immediately following the event's
+// transaction.
+//
+// The stream never advances the stored cursor itself. Clients
+// acknowledge events by calling /update-transaction-feed with an
+// event's id as the new after. Since a new stream begins at the last
+// acknowledged cursor, every matching transaction is delivered at
+// least once.
+func (a *API) streamTxFeed(rw http.ResponseWriter, req *http.Request) {
+	if a.config == nil {
+		alwaysError(errUnconfigured).ServeHTTP(rw, req)
+		return
+	}
+
+	ctx := req.Context()
+	var in struct {
+		ID    string `json:"id,omitempty"`
+		Alias string `json:"alias,omitempty"`
+	}
+	err := httpjson.Read(ctx, req.Body, &in)
+	if err != nil {
+		errorFormatter.Write(ctx, rw, err)
+		return
+	}
+	feed, err := a.txFeeds.Find(ctx, in.ID, in.Alias)
+	if err != nil {
+		errorFormatter.Write(ctx, rw, err)
+		return
+	}
+	after, err := query.DecodeTxAfter(feed.After)
+	if err != nil {
+		errorFormatter.Write(ctx, rw, errors.Wrap(err, "decoding feed `after`"))
+		return
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		errorFormatter.Write(ctx, rw, errors.New("streaming unsupported"))
+		return
+	}
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, streamKeepAlive)
+		txs, next, err := a.indexer.Transactions(waitCtx, feed.Filter, nil, after, defGenericPageSize, true)
+		cancel()
+		if ctx.Err() != nil {
+			return // client went away
+		}
+		if errors.Root(err) == context.DeadlineExceeded {
+			_, err = fmt.Fprint(rw, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		}
+		if err != nil {
+			log.Error(ctx, err, "streaming transaction feed", feed.ID)
+			return
+		}
+
+		for _, tx := range txs {
+			cur := query.TxAfter{
+				FromBlockHeight: tx.BlockHeight,
+				FromPosition:    tx.Position,
+				StopBlockHeight: after.StopBlockHeight,
+			}
+			err = writeTxEvent(rw, cur, tx)
+			if err != nil {
+				log.Error(ctx, err, "streaming transaction feed", feed.ID)
+				return
+			}
+		}
+		flusher.Flush()
+		after = *next
+	}
+}
+
+// writeTxEvent writes tx to w as a Server-Sent Event with the id cur.
+func writeTxEvent(w http.ResponseWriter, cur query.TxAfter, tx *query.AnnotatedTx) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return errors.Wrap(err, "marshaling annotated transaction")
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: transaction\ndata: %s\n\n", cur, data)
+	return err
+}
+
 // txAfterIsBefore returns true if a is before b. It returns an error if either
 // a or b are not valid query.TxAfters.
 func txAfterIsBefore(a, b string) (bool, error) {
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"chain/core/query"
@@ -31,3 +33,29 @@ func TestTxFeedIsBefore(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteTxEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	cur := query.TxAfter{FromBlockHeight: 5, FromPosition: 2, StopBlockHeight: 9}
+	err := writeTxEvent(rec, cur, &query.AnnotatedTx{BlockHeight: 5, Position: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(rec.Body.String(), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5: %q", len(lines), rec.Body.String())
+	}
+	if lines[0] != "id: 5:2-9" {
+		t.Errorf("got id line %q, want %q", lines[0], "id: 5:2-9")
+	}
+	if lines[1] != "event: transaction" {
+		t.Errorf("got event line %q, want %q", lines[1], "event: transaction")
+	}
+	if !strings.HasPrefix(lines[2], `data: {"id":`) || !strings.Contains(lines[2], `"block_height":5`) {
+		t.Errorf("got data line %q, want annotated transaction", lines[2])
+	}
+	if lines[3] != "" || lines[4] != "" {
+		t.Errorf("event is not terminated by a blank line: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,87 @@
+// Package kek encrypts secrets at rest with a key-encryption key
+// derived from a passphrase.
+//
+// The key is derived with PBKDF2-HMAC-SHA256 and used with AES-256-GCM.
+// Sealed values carry their random nonce as a prefix.
+package kek
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"chain/errors"
+)
+
+const (
+	// Iterations is the PBKDF2 iteration count used to derive
+	// a key-encryption key from a passphrase.
+	Iterations = 100000
+
+	// SaltSize is the size of the salts returned by NewSalt.
+	SaltSize = 16
+)
+
+// NewSalt returns a new random salt.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating salt")
+	}
+	return salt, nil
+}
+
+// New returns the cipher for the key-encryption key
+// derived from passphrase and salt.
+func New(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(derive([]byte(passphrase), salt, Iterations))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts plaintext, binding it to ad, and prepends the nonce.
+func Seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating nonce")
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// Open decrypts a value returned by Seal with the same ad.
+func Open(aead cipher.AEAD, sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, ad)
+}
+
+// derive returns a 32-byte key derived from passphrase and salt
+// with PBKDF2-HMAC-SHA256. Since the key is exactly one SHA-256 output
+// long, only the first PBKDF2 block is needed.
+func derive(passphrase, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, passphrase)
+	mac.Write(salt)
+	var blockIndex [4]byte
+	binary.BigEndian.PutUint32(blockIndex[:], 1)
+	mac.Write(blockIndex[:])
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
@@ -0,0 +1,52 @@
+package kek
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	// From RFC 7914, section 11: the first 32 bytes
+	// of PBKDF2-HMAC-SHA256("passwd", "salt", 1).
+	want, _ := hex.DecodeString("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc")
+	got := derive([]byte("passwd"), []byte("salt"), 1)
+	if !bytes.Equal(got, want) {
+		t.Errorf("derive = %x want %x", got, want)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := New("passphrase", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal(aead, []byte("secret"), []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(aead, sealed, []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "secret" {
+		t.Errorf("Open = %q want %q", got, "secret")
+	}
+
+	_, err = Open(aead, sealed, []byte("other ad"))
+	if err == nil {
+		t.Error("Open with different ad: got nil error")
+	}
+	other, err := New("other passphrase", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(other, sealed, []byte("ad"))
+	if err == nil {
+		t.Error("Open with different passphrase: got nil error")
+	}
+}
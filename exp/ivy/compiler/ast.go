@@ -38,6 +38,34 @@ type Contract struct {
 
 	// Pre-optimized list of instruction steps, with stack snapshots.
 	Steps []Step `json:"-"`
+
+	// Instructions lists the instructions of Body, after
+	// optimization, with the source they were compiled from.
+	Instructions []Instruction `json:"-"`
+
+	line int
+}
+
+// Instruction is one instruction of a contract body, for mapping the
+// progress of the VM back to the contract source.
+type Instruction struct {
+	// PC is the offset of the instruction in the body.
+	PC uint32 `json:"pc"`
+
+	// Opcode is the instruction in assembly form.
+	Opcode string `json:"opcode"`
+
+	// Line is the source line, counting from 1, of the statement the
+	// instruction belongs to, or of its clause or contract for code
+	// that selects a clause.
+	Line int `json:"line"`
+
+	// Stack names the items at the top of the stack, bottom first,
+	// after the instruction. Items below those the clause knows
+	// about (such as clause arguments, before a clause is selected)
+	// are not named. It is empty for an instruction in the middle of
+	// an operation that takes several, where the stack is in flux.
+	Stack []string `json:"stack,omitempty"`
 }
 
 // Param is a contract or clause parameter.
@@ -84,6 +112,8 @@ type Clause struct {
 
 	// Contracts is the list of contracts called by this clause.
 	Contracts []string `json:"contracts,omitempty"`
+
+	line int
 }
 
 // HashCall describes a call to a hash function.
@@ -112,12 +142,36 @@ type ClauseReq struct {
 	Amount string `json:"amount"`
 }
 
+// constant is a top-level constant declaration. Each reference to
+// it compiles to its value.
+type constant struct {
+	name string
+
+	// typ is the declared type, or the type of expr if none was
+	// declared.
+	typ  typeDesc
+	expr expression
+}
+
+// function is a top-level helper function. Its body is inlined at
+// each call site; there are no jumps and no recursion.
+type function struct {
+	name   string
+	params []*Param
+	result typeDesc
+	body   expression
+
+	// env binds the function's parameters for compiling body.
+	env *environ
+}
+
 type statement interface {
 	countVarRefs(map[string]int)
 }
 
 type verifyStatement struct {
 	expr expression
+	line int
 }
 
 func (s verifyStatement) countVarRefs(counts map[string]int) {
@@ -130,6 +184,8 @@ type lockStatement struct {
 
 	// Added as a decoration, used by CHECKOUTPUT
 	index int64
+
+	line int
 }
 
 func (s lockStatement) countVarRefs(counts map[string]int) {
@@ -139,6 +195,7 @@ func (s lockStatement) countVarRefs(counts map[string]int) {
 
 type unlockStatement struct {
 	expr expression
+	line int
 }
 
 func (s unlockStatement) countVarRefs(counts map[string]int) {
@@ -225,6 +282,9 @@ func (e callExpr) typ(env *environ) typeDesc {
 
 		return b.result
 	}
+	if f := referencedFunction(e.fn, env); f != nil {
+		return f.result
+	}
 	if e.fn.typ(env) == predType {
 		return boolType
 	}
@@ -9,11 +9,15 @@ import (
 type builder struct {
 	items         []*builderItem
 	pendingVerify *builderItem
+
+	// line is the source line of the code being compiled.
+	line int
 }
 
 type builderItem struct {
 	opcodes string
 	stk     stack
+	line    int
 }
 
 func (b *builder) add(opcodes string, newstack stack) stack {
@@ -21,7 +25,7 @@ func (b *builder) add(opcodes string, newstack stack) stack {
 		b.items = append(b.items, b.pendingVerify)
 		b.pendingVerify = nil
 	}
-	item := &builderItem{opcodes: opcodes, stk: newstack}
+	item := &builderItem{opcodes: opcodes, stk: newstack, line: b.line}
 	if opcodes == "VERIFY" {
 		b.pendingVerify = item
 	} else {
@@ -146,12 +150,34 @@ func (b *builder) addCat(stk stack, desc string) stack {
 	return b.add("CAT", stk.dropN(2).add(desc))
 }
 
-func (b *builder) opcodes() string {
-	var ops []string
+// maxStackDepth is the number of stack items a program can hold
+// before exhausting the ChainVM's initial run limit of 10,000, at a
+// cost of at least 8 per item.
+const maxStackDepth = 10000 / 8
+
+// maxStackDepth returns the largest number of items on the stack
+// after any instruction.
+func (b *builder) maxStackDepth() int {
+	var max int
+	for _, item := range b.items {
+		if n := item.stk.size(); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// ops splits the builder's items into single opcodes, each
+// remembering the item it came from.
+func (b *builder) ops() []op {
+	var ops []op
 	for _, item := range b.items {
-		ops = append(ops, item.opcodes)
+		opcodes := strings.Fields(item.opcodes)
+		for i, opcode := range opcodes {
+			ops = append(ops, op{opcode: opcode, item: item, last: i == len(opcodes)-1})
+		}
 	}
-	return strings.Join(ops, " ")
+	return ops
 }
 
 // This is for producing listings like:
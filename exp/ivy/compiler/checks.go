@@ -1,6 +1,10 @@
 package compiler
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 func checkRecursive(contract *Contract) bool {
 	for _, clause := range contract.Clauses {
@@ -168,6 +172,15 @@ func referencedBuiltin(expr expression) *builtin {
 	return nil
 }
 
+func referencedFunction(expr expression, env *environ) *function {
+	if v, ok := expr.(varRef); ok {
+		if entry := env.lookup(string(v)); entry != nil && entry.r == roleFunction {
+			return entry.f
+		}
+	}
+	return nil
+}
+
 func assignIndexes(clause *Clause) {
 	var nextIndex int64
 	for _, s := range clause.statements {
@@ -182,6 +195,70 @@ func assignIndexes(clause *Clause) {
 	}
 }
 
+// prohibitNegatedSigChecks rejects clauses in which a signature check
+// appears under "!". Anyone can supply an invalid signature, so a
+// negated signature check is always satisfiable. Since functions are
+// inlined, a signature check passed as an argument is checked where
+// the function body uses the parameter.
+func prohibitNegatedSigChecks(clause *Clause, env *environ) error {
+	for _, s := range clause.statements {
+		if stmt, ok := s.(*verifyStatement); ok && negatesSigCheck(stmt.expr, env, nil, false) {
+			return fmt.Errorf("signature check negated in clause \"%s\"", clause.Name)
+		}
+	}
+	return nil
+}
+
+// sigCheckArg is an argument bound to a function parameter while
+// looking for negated signature checks in the function body. The
+// argument is checked in the environment and bindings of the call.
+type sigCheckArg struct {
+	expr expression
+	env  *environ
+	args map[string]sigCheckArg
+}
+
+func negatesSigCheck(expr expression, env *environ, args map[string]sigCheckArg, negated bool) bool {
+	switch e := expr.(type) {
+	case *binaryExpr:
+		return negatesSigCheck(e.left, env, args, negated) || negatesSigCheck(e.right, env, args, negated)
+	case *unaryExpr:
+		return negatesSigCheck(e.expr, env, args, negated != (e.op.op == "!"))
+	case listExpr:
+		for _, elt := range e {
+			if negatesSigCheck(elt, env, args, negated) {
+				return true
+			}
+		}
+	case varRef:
+		if a, ok := args[string(e)]; ok {
+			return negatesSigCheck(a.expr, a.env, a.args, negated)
+		}
+		if entry := env.lookup(string(e)); entry != nil && entry.r == roleConstant {
+			return negatesSigCheck(entry.k.expr, env, nil, negated)
+		}
+	case *callExpr:
+		if b := referencedBuiltin(e.fn); negated && b != nil && (b.name == "checkTxSig" || b.name == "checkTxMultiSig") {
+			return true
+		}
+		if f := referencedFunction(e.fn, env); f != nil {
+			bound := make(map[string]sigCheckArg)
+			for i, p := range f.params {
+				if i < len(e.args) {
+					bound[p.Name] = sigCheckArg{e.args[i], env, args}
+				}
+			}
+			return negatesSigCheck(f.body, f.env, bound, negated)
+		}
+		for _, a := range e.args {
+			if negatesSigCheck(a, env, args, negated) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func typeCheckClause(contract *Contract, clause *Clause, env *environ) error {
 	for _, s := range clause.statements {
 		switch stmt := s.(type) {
@@ -209,3 +286,248 @@ func typeCheckClause(contract *Contract, clause *Clause, env *environ) error {
 	}
 	return nil
 }
+
+// assignable tells whether an expression of type from may be used
+// where type to is declared: as a constant's value, a function's
+// result, or an argument to a function. Besides identical types, an
+// Integer may stand for an Amount or a Time, and a hash of a
+// specific kind for a Hash.
+func assignable(to, from typeDesc) bool {
+	switch {
+	case to == from:
+		return true
+	case from == intType:
+		return to == amountType || to == timeType
+	case to == hashType:
+		return isHashSubtype(from)
+	}
+	return false
+}
+
+// prohibitRecursiveDecls rejects constants and functions that refer,
+// directly or indirectly, to themselves. Since functions are
+// inlined, a recursive one would never finish compiling. It returns
+// the declarations in dependency order, each after everything it
+// refers to.
+func prohibitRecursiveDecls(consts []*constant, funcs []*function) ([]interface{}, error) {
+	decls := make(map[string]interface{})
+	for _, k := range consts {
+		decls[k.name] = k
+	}
+	for _, f := range funcs {
+		decls[f.name] = f
+	}
+
+	var (
+		ordered []interface{}
+		done    = make(map[string]bool)
+		visit   func(name string, path []string) error
+	)
+	visit = func(name string, path []string) error {
+		for i, n := range path {
+			if n == name {
+				return fmt.Errorf("%s \"%s\" is recursive (%s)", declKind(decls[name]), name, strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if done[name] {
+			return nil
+		}
+		var body expression
+		switch d := decls[name].(type) {
+		case *constant:
+			body = d.expr
+		case *function:
+			body = d.body
+		}
+		refs := make(map[string]int)
+		body.countVarRefs(refs)
+		var names []string
+		for n := range refs {
+			if _, ok := decls[n]; ok {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			err := visit(n, append(path, name))
+			if err != nil {
+				return err
+			}
+		}
+		done[name] = true
+		ordered = append(ordered, decls[name])
+		return nil
+	}
+
+	for _, k := range consts {
+		err := visit(k.name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range funcs {
+		err := visit(f.name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func declKind(decl interface{}) string {
+	if _, ok := decl.(*function); ok {
+		return roleDesc[roleFunction]
+	}
+	return roleDesc[roleConstant]
+}
+
+// typeCheckFunction checks the body of f against its declared
+// result type. It also rejects parameters that are unused, which
+// would be left on the stack by an inlined call, and parameter and
+// result types that no expression can produce.
+func typeCheckFunction(f *function) error {
+	for _, p := range f.params {
+		switch p.Type {
+		case valueType, listType, contractType, predType:
+			return fmt.Errorf("parameter \"%s\" of function \"%s\" has type %s, which function parameters cannot have", p.Name, f.name, p.Type)
+		}
+		if !references(f.body, p.Name) {
+			return fmt.Errorf("parameter \"%s\" is unused in function \"%s\"", p.Name, f.name)
+		}
+	}
+	switch f.result {
+	case valueType, listType, contractType, predType, nilType:
+		return fmt.Errorf("function \"%s\" has result type %s, which functions cannot return", f.name, f.result)
+	}
+	t, err := typeCheckExpr(f.body, f.env)
+	if err != nil {
+		return fmt.Errorf("in function \"%s\": %s", f.name, err)
+	}
+	if !assignable(f.result, t) {
+		return fmt.Errorf("function \"%s\" returns type \"%s\", must be \"%s\"", f.name, t, f.result)
+	}
+	if negatesSigCheck(f.body, f.env, nil, false) {
+		return fmt.Errorf("signature check negated in function \"%s\"", f.name)
+	}
+	return nil
+}
+
+// typeCheckConstant checks the value of k against its declared type,
+// or, if it has none, sets its type to that of the value.
+func typeCheckConstant(k *constant, env *environ) error {
+	t, err := typeCheckExpr(k.expr, env)
+	if err != nil {
+		return fmt.Errorf("in constant \"%s\": %s", k.name, err)
+	}
+	switch {
+	case k.typ == nilType:
+		k.typ = t
+	case !assignable(k.typ, t):
+		return fmt.Errorf("constant \"%s\" has value of type \"%s\", must be \"%s\"", k.name, t, k.typ)
+	}
+	env.lookup(k.name).t = k.typ
+	return nil
+}
+
+// typeCheckExpr checks the operand and argument types throughout
+// expr, which is the value of a constant or the body of a function,
+// and returns its type. Clause statements are checked as they are
+// compiled instead; here there is nothing to compile against, since
+// these expressions are only compiled where they are used.
+func typeCheckExpr(expr expression, env *environ) (typeDesc, error) {
+	switch e := expr.(type) {
+	case *binaryExpr:
+		lType, err := typeCheckExpr(e.left, env)
+		if err != nil {
+			return nilType, err
+		}
+		rType, err := typeCheckExpr(e.right, env)
+		if err != nil {
+			return nilType, err
+		}
+		if e.op.left != "" && lType != e.op.left {
+			return nilType, fmt.Errorf("in \"%s\", left operand has type \"%s\", must be \"%s\"", e, lType, e.op.left)
+		}
+		if e.op.right != "" && rType != e.op.right {
+			return nilType, fmt.Errorf("in \"%s\", right operand has type \"%s\", must be \"%s\"", e, rType, e.op.right)
+		}
+		switch e.op.op {
+		case "==", "!=":
+			if lType != rType && !(lType == hashType && isHashSubtype(rType)) && !(rType == hashType && isHashSubtype(lType)) {
+				return nilType, fmt.Errorf("type mismatch in \"%s\": left operand has type \"%s\", right operand has type \"%s\"", e, lType, rType)
+			}
+			if lType == boolType {
+				return nilType, fmt.Errorf("in \"%s\": using \"%s\" on Boolean values not allowed", e, e.op.op)
+			}
+		}
+		return e.op.result, nil
+
+	case *unaryExpr:
+		t, err := typeCheckExpr(e.expr, env)
+		if err != nil {
+			return nilType, err
+		}
+		if e.op.operand != "" && t != e.op.operand {
+			return nilType, fmt.Errorf("in \"%s\", operand has type \"%s\", must be \"%s\"", e, t, e.op.operand)
+		}
+		return e.op.result, nil
+
+	case *callExpr:
+		var (
+			want   []typeDesc
+			isFunc bool
+		)
+		if bi := referencedBuiltin(e.fn); bi != nil {
+			want = bi.args
+		} else if f := referencedFunction(e.fn, env); f != nil {
+			for _, p := range f.params {
+				want = append(want, p.Type)
+			}
+			isFunc = true
+		} else {
+			return nilType, fmt.Errorf("\"%s\" is not a function or built-in function", e.fn)
+		}
+		if len(e.args) != len(want) {
+			return nilType, fmt.Errorf("wrong number of args for \"%s\": have %d, want %d", e.fn, len(e.args), len(want))
+		}
+		for i, a := range e.args {
+			var (
+				t   typeDesc
+				err error
+			)
+			if list, ok := a.(listExpr); ok && want[i] == listType {
+				for _, elt := range list {
+					_, err = typeCheckExpr(elt, env)
+					if err != nil {
+						return nilType, err
+					}
+				}
+				t = listType
+			} else {
+				t, err = typeCheckExpr(a, env)
+				if err != nil {
+					return nilType, err
+				}
+			}
+			if want[i] != "" && t != want[i] && !(isFunc && assignable(want[i], t)) {
+				return nilType, fmt.Errorf("argument %d to \"%s\" has type \"%s\", must be \"%s\"", i, e.fn, t, want[i])
+			}
+		}
+		return e.typ(env), nil
+
+	case varRef:
+		entry := env.lookup(string(e))
+		if entry == nil {
+			return nilType, fmt.Errorf("undefined reference: \"%s\"", e)
+		}
+		switch entry.r {
+		case roleConstant, roleFunctionParam:
+			return entry.t, nil
+		}
+		return nilType, fmt.Errorf("%s \"%s\" cannot be used here", roleDesc[entry.r], e)
+
+	case listExpr:
+		return nilType, fmt.Errorf("encountered list outside of function-call context")
+	}
+	return expr.typ(env), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+// debugSpend runs the VM on input n of the hex-encoded transaction
+// txHex, which must spend an instance of one of contracts, and
+// writes a trace of the contract body to w. Each instruction is shown
+// under the source line it was compiled from, with the stack items
+// named after the parameters and expressions they hold. If the spend
+// fails, the trace ends with the statement that failed.
+func debugSpend(w io.Writer, src []byte, contracts []*compiler.Contract, txHex string, n int) error {
+	var tx legacy.Tx
+	err := tx.UnmarshalText([]byte(txHex))
+	if err != nil {
+		return fmt.Errorf("parsing transaction: %s", err)
+	}
+	if n < 0 || n >= len(tx.Inputs) {
+		return fmt.Errorf("transaction has no input %d", n)
+	}
+	spend, ok := tx.Inputs[n].TypedInput.(*legacy.SpendInput)
+	if !ok {
+		return fmt.Errorf("input %d is not a spend", n)
+	}
+	contract, err := findContract(spend.ControlProgram, contracts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "input %d spends contract %s\n\n", n, contract.Name)
+
+	lines := strings.Split(string(src), "\n")
+	sourceLine := func(line int) string {
+		if line < 1 || line > len(lines) {
+			return ""
+		}
+		return strings.TrimSpace(lines[line-1])
+	}
+
+	var lastLine, failedLine int
+	prog := &bc.Program{VmVersion: spend.VMVersion, Code: spend.ControlProgram}
+	vmContext := validation.NewTxVMContext(tx.Tx, tx.Entries[tx.InputIDs[n]], prog, spend.Arguments)
+	vmContext.StepHook = func(step vm.Step) {
+		// The contract body runs as the predicate checked by the
+		// instantiated program.
+		if step.Depth != 1 {
+			return
+		}
+		inst := instructionAt(contract, step.PC)
+		if inst == nil {
+			return
+		}
+		if inst.Line != lastLine {
+			fmt.Fprintf(w, "%4d  %s\n", inst.Line, sourceLine(inst.Line))
+			lastLine = inst.Line
+		}
+		fmt.Fprintf(w, "      %5d  %-20s %s\n", inst.PC, inst.Opcode, stackString(inst.Stack, step.Stack))
+		if step.Err != nil && failedLine == 0 {
+			fmt.Fprintf(w, "             error: %s\n", step.Err)
+			failedLine = inst.Line
+		}
+	}
+
+	err = vm.Verify(vmContext)
+	if err == nil {
+		fmt.Fprintf(w, "\ninput %d is valid\n", n)
+		return nil
+	}
+	if vmErr, ok := err.(vm.Error); ok {
+		err = vmErr.Err
+	}
+	if failedLine == 0 {
+		// No instruction failed, so the clause left false on
+		// the stack; its last statement is the culprit.
+		failedLine = lastLine
+	}
+	fmt.Fprintf(w, "\ninput %d is invalid: %s\n", n, err)
+	if failedLine > 0 {
+		fmt.Fprintf(w, "failed at line %d: %s\n", failedLine, sourceLine(failedLine))
+	}
+	return nil
+}
+
+// findContract returns the contract whose body appears as pushed
+// data in prog.
+func findContract(prog []byte, contracts []*compiler.Contract) (*compiler.Contract, error) {
+	insts, err := vm.ParseProgram(prog)
+	if err != nil {
+		return nil, fmt.Errorf("parsing control program: %s", err)
+	}
+	for _, inst := range insts {
+		for _, c := range contracts {
+			if bytes.Equal(inst.Data, c.Body) {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("control program %x is not an instance of any contract in the source", prog)
+}
+
+func instructionAt(contract *compiler.Contract, pc uint32) *compiler.Instruction {
+	for i := range contract.Instructions {
+		if contract.Instructions[i].PC == pc {
+			return &contract.Instructions[i]
+		}
+	}
+	return nil
+}
+
+// stackString formats the VM stack, top first, naming items from
+// the top down after the entries of names (which are bottom first).
+func stackString(names []string, stack [][]byte) string {
+	var items []string
+	for i := 0; i < len(stack); i++ {
+		item := stack[len(stack)-1-i]
+		if i < len(names) {
+			items = append(items, fmt.Sprintf("%s=%x", names[len(names)-1-i], item))
+		} else {
+			items = append(items, fmt.Sprintf("%x", item))
+		}
+	}
+	return "[" + strings.Join(items, " ") + "]"
+}
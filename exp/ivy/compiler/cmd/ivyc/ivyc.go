@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -13,12 +14,27 @@ import (
 
 func main() {
 	packageName := flag.String("package", "main", "Go package name for generated file")
+	debug := flag.Bool("debug", false, "instead of generating Go, trace the spend of a contract by the transaction in -tx")
+	txHex := flag.String("tx", "", "with -debug, the hex-encoded spending transaction")
+	input := flag.Int("input", 0, "with -debug, the index of the input spending the contract")
 	flag.Parse()
 
-	contracts, err := compiler.Compile(os.Stdin)
+	src, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
 		log.Fatal(err)
 	}
+	contracts, err := compiler.Compile(bytes.NewReader(src))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *debug {
+		err = debugSpend(os.Stdout, src, contracts, *txHex, *input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	fmt.Printf("package %s\n\n", *packageName)
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	chainjson "chain/encoding/json"
 	"chain/errors"
@@ -53,7 +54,7 @@ func Compile(r io.Reader) ([]*Contract, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "reading input")
 	}
-	contracts, err := parse(inp)
+	contracts, consts, funcs, err := parse(inp)
 	if err != nil {
 		return nil, errors.Wrap(err, "parse error")
 	}
@@ -66,6 +67,11 @@ func Compile(r io.Reader) ([]*Contract, error) {
 		globalEnv.add(b.name, nilType, roleBuiltin)
 	}
 
+	err = compileDecls(globalEnv, consts, funcs)
+	if err != nil {
+		return nil, err
+	}
+
 	// All contracts must be checked for recursiveness before any are
 	// compiled.
 	for _, contract := range contracts {
@@ -169,6 +175,48 @@ func Instantiate(body []byte, params []*Param, recursive bool, args []ContractAr
 	return b.Build()
 }
 
+// compileDecls adds the constants and functions to env and checks
+// them. Nothing is emitted for them here; they are compiled where
+// they are used.
+func compileDecls(env *environ, consts []*constant, funcs []*function) error {
+	for _, k := range consts {
+		err := env.addConstant(k)
+		if err != nil {
+			return err
+		}
+	}
+	for _, f := range funcs {
+		err := env.addFunction(f)
+		if err != nil {
+			return err
+		}
+		f.env = newEnviron(env)
+		for _, p := range f.params {
+			err = f.env.add(p.Name, p.Type, roleFunctionParam)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	decls, err := prohibitRecursiveDecls(consts, funcs)
+	if err != nil {
+		return err
+	}
+	for _, d := range decls {
+		switch d := d.(type) {
+		case *constant:
+			err = typeCheckConstant(d, env)
+		case *function:
+			err = typeCheckFunction(d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func compileContract(contract *Contract, globalEnv *environ) error {
 	var err error
 
@@ -221,7 +269,7 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 		stk = stk.add(contract.Name)
 	}
 
-	b := &builder{}
+	b := &builder{line: contract.line}
 
 	if len(contract.Clauses) == 1 {
 		err = compileClause(b, stk, contract, env, contract.Clauses[0])
@@ -262,6 +310,7 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 				stk = stk2
 			}
 
+			b.line = clause.line
 			b.addJumpTarget(stk, clause.Name)
 
 			if i > 1 {
@@ -280,7 +329,12 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 		b.addJumpTarget(stk, "_end")
 	}
 
-	opcodes := optimize(b.opcodes())
+	if depth := b.maxStackDepth(); depth > maxStackDepth {
+		return fmt.Errorf("contract needs %d stack items, more than the %d the VM can hold", depth, maxStackDepth)
+	}
+
+	ops := optimize(b.ops())
+	opcodes := joinOps(ops)
 	prog, err := vm.Assemble(opcodes)
 	if err != nil {
 		return err
@@ -291,12 +345,56 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 
 	contract.Steps = b.steps()
 
+	contract.Instructions, err = instructions(ops)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// instructions locates each of ops in the assembled body.
+func instructions(ops []op) ([]Instruction, error) {
+	var (
+		res []Instruction
+		pc  uint32
+	)
+	for _, o := range ops {
+		var n int
+		switch {
+		case strings.HasPrefix(o.opcode, "$"):
+			// A jump target assembles to nothing.
+			continue
+		case strings.HasPrefix(o.opcode, "JUMP:"), strings.HasPrefix(o.opcode, "JUMPIF:"):
+			// The opcode and a 4-byte address, which can't be
+			// assembled on its own.
+			n = 5
+		default:
+			code, err := vm.Assemble(o.opcode)
+			if err != nil {
+				return nil, err
+			}
+			n = len(code)
+		}
+		inst := Instruction{
+			PC:     pc,
+			Opcode: o.opcode,
+			Line:   o.item.line,
+		}
+		if o.last {
+			inst.Stack = o.item.stk.names()
+		}
+		res = append(res, inst)
+		pc += uint32(n)
+	}
+	return res, nil
+}
+
 func compileClause(b *builder, contractStk stack, contract *Contract, env *environ, clause *Clause) error {
 	var err error
 
+	b.line = clause.line
+
 	// copy env to leave outerEnv unchanged
 	env = newEnviron(env)
 	for _, p := range clause.Params {
@@ -338,6 +436,7 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 	for _, s := range clause.statements {
 		switch stmt := s.(type) {
 		case *verifyStatement:
+			b.line = stmt.line
 			stk, err = compileExpr(b, stk, contract, clause, env, counts, stmt.expr)
 			if err != nil {
 				return errors.Wrapf(err, "in verify statement in clause \"%s\"", clause.Name)
@@ -357,6 +456,8 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 			}
 
 		case *lockStatement:
+			b.line = stmt.line
+
 			// index
 			stk = b.addInt64(stk, stmt.index)
 
@@ -407,6 +508,7 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 			stk = b.addVerify(stk)
 
 		case *unlockStatement:
+			b.line = stmt.line
 			if len(clause.statements) == 1 {
 				// This is the only statement in the clause, make sure TRUE is
 				// on the stack.
@@ -423,6 +525,10 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 	if err != nil {
 		return err
 	}
+	err = prohibitNegatedSigChecks(clause, env)
+	if err != nil {
+		return err
+	}
 	err = requireAllParamsUsedInClause(clause.Params, clause)
 	if err != nil {
 		return err
@@ -499,6 +605,9 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 		bi := referencedBuiltin(e.fn)
 		if bi == nil {
 			if v, ok := e.fn.(varRef); ok {
+				if f := referencedFunction(v, env); f != nil {
+					return compileFunctionCall(b, stk, contract, clause, env, counts, e, f)
+				}
 				if entry := env.lookup(string(v)); entry != nil && entry.t == contractType {
 					clause.Contracts = append(clause.Contracts, entry.c.Name)
 
@@ -641,6 +750,13 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 		}
 
 	case varRef:
+		if entry := env.lookup(string(e)); entry != nil && entry.r == roleConstant {
+			stk, err = compileExpr(b, stk, contract, clause, env, counts, entry.k.expr)
+			if err != nil {
+				return stk, errors.Wrapf(err, "in constant \"%s\"", e)
+			}
+			return stk.drop().add(string(e)), nil
+		}
 		return compileRef(b, stk, counts, e)
 
 	case integerLiteral:
@@ -663,6 +779,40 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 	return stk, nil
 }
 
+// compileFunctionCall inlines a call to f. Each argument is evaluated
+// once, in order, and then renamed on the stack after its parameter
+// (not before, since a later argument may refer to a variable of the
+// caller with the same name). The body is compiled with its own
+// reference counts, so the last reference to each parameter consumes
+// it and only the result remains.
+func compileFunctionCall(b *builder, stk stack, contract *Contract, clause *Clause, env *environ, counts map[string]int, call *callExpr, f *function) (stack, error) {
+	if len(call.args) != len(f.params) {
+		return stk, fmt.Errorf("function \"%s\" expects %d argument(s), got %d", f.name, len(f.params), len(call.args))
+	}
+	for i, arg := range call.args {
+		var err error
+		stk, err = compileExpr(b, stk, contract, clause, env, counts, arg)
+		if err != nil {
+			return stk, errors.Wrapf(err, "compiling argument %d in call expression", i)
+		}
+		if t := arg.typ(env); !assignable(f.params[i].Type, t) {
+			return stk, fmt.Errorf("argument %d to function \"%s\" has type \"%s\", must be \"%s\"", i, f.name, t, f.params[i].Type)
+		}
+	}
+	stk = stk.dropN(len(f.params))
+	for _, p := range f.params {
+		stk = stk.add(p.Name)
+	}
+
+	bodyCounts := make(map[string]int)
+	f.body.countVarRefs(bodyCounts)
+	stk, err := compileExpr(b, stk, contract, clause, f.env, bodyCounts, f.body)
+	if err != nil {
+		return stk, errors.Wrapf(err, "in function \"%s\"", f.name)
+	}
+	return stk.drop().add(call.String()), nil
+}
+
 func compileArg(b *builder, stk stack, contract *Contract, clause *Clause, env *environ, counts map[string]int, expr expression) (stack, int, error) {
 	var n int
 	if list, ok := expr.(listExpr); ok {
@@ -3,10 +3,17 @@ package compiler
 import (
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
 
+	"chain/crypto/ed25519"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/vm"
 )
 
 func TestCompile(t *testing.T) {
@@ -75,6 +82,21 @@ func TestCompile(t *testing.T) {
 			ivytest.OneTwo,
 			`[{"name":"Two","params":[{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","maxtimes":["expirationTime"],"values":[{"name":"value","program":"b"}]},{"name":"default","mintimes":["expirationTime"],"values":[{"name":"value","program":"c"}]}],"value":"value","body_bytecode":"537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac1","body_opcodes":"3 ROLL JUMPIF:$default $redeem ROT MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $default ROT MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT $_end","recursive":false},{"name":"One","params":[{"name":"a","declared_type":"Program"},{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"switchTime","declared_type":"Time"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","maxtimes":["switchTime"],"values":[{"name":"value","program":"a"}]},{"name":"switch","mintimes":["switchTime"],"values":[{"name":"value","program":"Two(b, c, expirationTime)"}],"contracts":["Two"]}],"value":"value","body_bytecode":"557a6419000000537ac6a0690000c3c251557ac1635c000000537ac59f690000c3c25100597a89587a89577a8901747e24537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac189008901c07ec1","body_opcodes":"5 ROLL JUMPIF:$switch $redeem 3 ROLL MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $switch 3 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 0 9 ROLL CATPUSHDATA 8 ROLL CATPUSHDATA 7 ROLL CATPUSHDATA 116 CAT 0x537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac1 CATPUSHDATA 0 CATPUSHDATA 192 CAT CHECKOUTPUT $_end","recursive":false}]`,
 		},
+		{
+			"EscrowWithTimeout",
+			ivytest.EscrowWithTimeout,
+			`[{"name":"EscrowWithTimeout","params":[{"name":"agent","declared_type":"PublicKey"},{"name":"sender","declared_type":"PublicKey"},{"name":"deadline","declared_type":"Time"}],"clauses":[{"name":"release","params":[{"name":"sig","declared_type":"Signature"}],"values":[{"name":"value"}]}],"value":"value","body_bytecode":"53797cae7cac7bc59f72ae7cac9a9b","body_opcodes":"3 PICK SWAP TXSIGHASH SWAP CHECKSIG ROT MINTIME LESSTHAN 2SWAP TXSIGHASH SWAP CHECKSIG BOOLAND BOOLOR","recursive":false}]`,
+		},
+		{
+			"RangeLock",
+			ivytest.RangeLock,
+			`[{"name":"RangeLock","params":[{"name":"low","declared_type":"Integer"},{"name":"high","declared_type":"Integer"}],"clauses":[{"name":"spend","params":[{"name":"x","declared_type":"Integer"}],"values":[{"name":"value"}]}],"value":"value","body_bytecode":"52797c9f7b7ba09b91","body_opcodes":"2 PICK SWAP LESSTHAN ROT ROT GREATERTHAN BOOLOR NOT","recursive":false}]`,
+		},
+		{
+			"FixedRangeLock",
+			ivytest.FixedRangeLock,
+			`[{"name":"FixedRangeLock","params":[{"name":"offset","declared_type":"Integer"}],"clauses":[{"name":"spend","params":[{"name":"x","declared_type":"Integer"}],"values":[{"name":"value"}]}],"value":"value","body_bytecode":"945a5a5a937c5279a17b7ba19a","body_opcodes":"SUB 10 10 10 ADD SWAP 2 PICK LESSTHANOREQUAL ROT ROT LESSTHANOREQUAL BOOLAND","recursive":false}]`,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -95,6 +117,285 @@ func TestCompile(t *testing.T) {
 	}
 }
 
+func TestInstructions(t *testing.T) {
+	sources := []string{
+		ivytest.TradeOffer,
+		ivytest.CallOptionWithSettlement,
+		ivytest.PriceChanger,
+		ivytest.OneTwo,
+		ivytest.LockWith2of3Keys,
+		ivytest.FixedRangeLock,
+	}
+	for _, src := range sources {
+		contracts, err := Compile(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range contracts {
+			var pc uint32
+			for _, inst := range c.Instructions {
+				if inst.PC != pc {
+					t.Fatalf("%s: instruction %s at pc %d, want %d", c.Name, inst.Opcode, inst.PC, pc)
+				}
+				parsed, err := vm.ParseOp(c.Body, pc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pc += parsed.Len
+			}
+			if int(pc) != len(c.Body) {
+				t.Errorf("%s: instructions cover %d bytes of body, want %d", c.Name, pc, len(c.Body))
+			}
+		}
+	}
+
+	contracts, err := Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(ivytest.TradeOffer, "\n")
+	for _, inst := range contracts[0].Instructions {
+		if inst.Opcode != "CHECKSIG" {
+			continue
+		}
+		if got := strings.TrimSpace(lines[inst.Line-1]); got != "verify checkTxSig(sellerKey, sellerSig)" {
+			t.Errorf("CHECKSIG line %d is %q", inst.Line, got)
+		}
+		want := []string{"sellerProgram", "requestedAmount", "requestedAsset", "checkTxSig(sellerKey, sellerSig)"}
+		if !reflect.DeepEqual(inst.Stack, want) {
+			t.Errorf("CHECKSIG stack = %v want %v", inst.Stack, want)
+		}
+	}
+}
+
+func TestBooleanOperators(t *testing.T) {
+	agentPub, agentPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderPub, senderPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigHash := make([]byte, 32)
+	agentSig := ed25519.Sign(agentPriv, sigHash)
+	senderSig := ed25519.Sign(senderPriv, sigHash)
+
+	run := func(src string, args []ContractArg, clauseArgs [][]byte, minTimeMS uint64) error {
+		contracts, err := Compile(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := contracts[0]
+		prog, err := Instantiate(c.Body, c.Params, c.Recursive, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxTimeMS := uint64(math.MaxInt64)
+		return vm.Verify(&vm.Context{
+			VMVersion: 1,
+			Code:      prog,
+			Arguments: clauseArgs,
+			MinTimeMS: &minTimeMS,
+			MaxTimeMS: &maxTimeMS,
+			TxSigHash: func() []byte { return sigHash },
+		})
+	}
+
+	deadline := int64(1000)
+	escrowArgs := []ContractArg{
+		{S: (*chainjson.HexBytes)(&agentPub)},
+		{S: (*chainjson.HexBytes)(&senderPub)},
+		{I: &deadline},
+	}
+	escrowCases := []struct {
+		name      string
+		sig       []byte
+		minTimeMS uint64
+		ok        bool
+	}{
+		{"agent before deadline", agentSig, 500, true},
+		{"agent after deadline", agentSig, 1500, true},
+		{"sender before deadline", senderSig, 500, false},
+		{"sender after deadline", senderSig, 1500, true},
+		{"no signature after deadline", nil, 1500, false},
+	}
+	for _, c := range escrowCases {
+		err := run(ivytest.EscrowWithTimeout, escrowArgs, [][]byte{c.sig}, c.minTimeMS)
+		if c.ok && err != nil {
+			t.Errorf("EscrowWithTimeout, %s: unexpected error %s", c.name, err)
+		} else if !c.ok && !isFalseResult(err) {
+			t.Errorf("EscrowWithTimeout, %s: got error %v, want %s", c.name, err, vm.ErrFalseVMResult)
+		}
+	}
+
+	low, high := int64(10), int64(20)
+	rangeArgs := []ContractArg{{I: &low}, {I: &high}}
+	for x, ok := range map[int64]bool{9: false, 10: true, 15: true, 20: true, 21: false, -15: false} {
+		err := run(ivytest.RangeLock, rangeArgs, [][]byte{vm.Int64Bytes(x)}, 0)
+		if ok && err != nil {
+			t.Errorf("RangeLock, x=%d: unexpected error %s", x, err)
+		} else if !ok && !isFalseResult(err) {
+			t.Errorf("RangeLock, x=%d: got error %v, want %s", x, err, vm.ErrFalseVMResult)
+		}
+	}
+}
+
+func TestDecls(t *testing.T) {
+	contracts, err := Compile(strings.NewReader(ivytest.FixedRangeLock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := contracts[0]
+	offset := int64(5)
+	prog, err := Instantiate(c.Body, c.Params, c.Recursive, []ContractArg{{I: &offset}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x, ok := range map[int64]bool{14: false, 15: true, 20: true, 25: true, 26: false} {
+		err := vm.Verify(&vm.Context{VMVersion: 1, Code: prog, Arguments: [][]byte{vm.Int64Bytes(x)}})
+		if ok && err != nil {
+			t.Errorf("FixedRangeLock, x=%d: unexpected error %s", x, err)
+		} else if !ok && !isFalseResult(err) {
+			t.Errorf("FixedRangeLock, x=%d: got error %v, want %s", x, err, vm.ErrFalseVMResult)
+		}
+	}
+}
+
+func TestDeclErrors(t *testing.T) {
+	const contract = `
+contract C(k: PublicKey, n: Integer) locks value {
+  clause spend(sig: Signature) {
+    verify %s
+    unlock value
+  }
+}
+`
+	cases := []struct {
+		name  string
+		decls string
+		expr  string
+		want  string
+	}{
+		{
+			"recursive function",
+			"function f(x: Integer): Boolean { return f(x - 1) }",
+			"f(n) && checkTxSig(k, sig)",
+			`function "f" is recursive (f -> f)`,
+		},
+		{
+			"mutually recursive functions",
+			"function f(x: Integer): Integer { return g(x) }\nfunction g(x: Integer): Integer { return f(x) }",
+			"f(n) > 0 && checkTxSig(k, sig)",
+			`function "f" is recursive (f -> g -> f)`,
+		},
+		{
+			"recursive constant",
+			"const a = b\nconst b = a + 1",
+			"n > a && checkTxSig(k, sig)",
+			`constant "a" is recursive (a -> b -> a)`,
+		},
+		{
+			"wrong result type",
+			"function f(x: Integer): Boolean { return x + 1 }",
+			"f(n) && checkTxSig(k, sig)",
+			`function "f" returns type "Integer", must be "Boolean"`,
+		},
+		{
+			"wrong constant type",
+			"const a: PublicKey = 7",
+			"n > 0 && checkTxSig(a, sig)",
+			`constant "a" has value of type "Integer", must be "PublicKey"`,
+		},
+		{
+			"unused function parameter",
+			"function f(x, y: Integer): Integer { return x }",
+			"f(n, n) > 0 && checkTxSig(k, sig)",
+			`parameter "y" is unused in function "f"`,
+		},
+		{
+			"wrong argument type",
+			"function f(x: Integer): Integer { return x }",
+			"f(k) > n && checkTxSig(k, sig)",
+			`argument 0 to function "f" has type "PublicKey", must be "Integer"`,
+		},
+		{
+			"negated signature check",
+			"function signed(p: PublicKey, s: Signature): Boolean { return checkTxSig(p, s) }",
+			"n > 0 && !signed(k, sig)",
+			`signature check negated in clause "spend"`,
+		},
+		{
+			"signature check negated through a function parameter",
+			"function neg(b: Boolean): Boolean { return !b }",
+			"n > 0 && neg(checkTxSig(k, sig))",
+			`signature check negated in clause "spend"`,
+		},
+		{
+			"signature check negated through nested function parameters",
+			"function neg(b: Boolean): Boolean { return !b }\nfunction both(a, b: Boolean): Boolean { return a && neg(b) }",
+			"both(n > 0, checkTxSig(k, sig))",
+			`signature check negated in clause "spend"`,
+		},
+		{
+			"name conflict",
+			"const n = 3",
+			"n > 0 && checkTxSig(k, sig)",
+			`contract parameter "n" conflicts with constant`,
+		},
+		{
+			"stack too deep",
+			"",
+			strings.Repeat("(1 + ", maxStackDepth) + "n" + strings.Repeat(")", maxStackDepth) + " > 0 && checkTxSig(k, sig)",
+			fmt.Sprintf("more than the %d the VM can hold", maxStackDepth),
+		},
+	}
+	for _, c := range cases {
+		_, err := Compile(strings.NewReader(c.decls + fmt.Sprintf(contract, c.expr)))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got error %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestDeclOrder(t *testing.T) {
+	const src = `
+contract C(k: PublicKey, n: Integer) locks value {
+  clause spend(sig: Signature) {
+    verify big(n) && checkTxSig(k, sig)
+    unlock value
+  }
+}
+function big(x: Integer): Boolean { return x > limit }
+const limit = base * 2
+const base = 5
+`
+	_, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Errorf("declarations after their uses: unexpected error %s", err)
+	}
+}
+
+func isFalseResult(err error) bool {
+	vmErr, ok := errors.Root(err).(vm.Error)
+	return ok && vmErr.Err == vm.ErrFalseVMResult
+}
+
+func TestNegatedSigCheck(t *testing.T) {
+	const src = `
+contract NotAgent(agent: PublicKey) locks value {
+  clause spend(sig: Signature) {
+    verify !checkTxSig(agent, sig)
+    unlock value
+  }
+}
+`
+	_, err := Compile(strings.NewReader(src))
+	if err == nil {
+		t.Error("expected error compiling negated signature check")
+	}
+}
+
 func mustDecodeHex(h string) []byte {
 	bits, err := hex.DecodeString(h)
 	if err != nil {
@@ -12,7 +12,26 @@ valid.
 The language definition is in flux, but here's what's implemented as
 of late May 2017.
 
-  program = contract*
+  program = (contract | const | function)*
+
+  const = "const" identifier [":" identifier] "=" expr
+
+    Declares a constant, which may be used in place of expr in
+    any contract, function, or other constant in the program.
+    Constants and functions may be declared in any order, before
+    or after their uses, but may not refer to themselves, directly
+    or indirectly. The optional identifier after the colon is its
+    type; without it, the constant has the type of expr. An Integer
+    expr may be declared Amount or Time.
+
+  function = "function" identifier "(" [params] ")" ":" identifier "{" "return" expr "}"
+
+    Declares a helper function returning expr, which must have the
+    type named after the colon. Every parameter must be used.
+    Calls are inlined: the arguments are evaluated once each and
+    expr is compiled in place of the call. A function may call
+    built-in functions and other functions, but not itself
+    (directly or indirectly) and not contracts.
 
   contract = "contract" identifier "(" [params] ")" "locks" identifier "{" clause+ "}"
 
@@ -77,6 +96,9 @@ of late May 2017.
     the appropriate arguments) produces a program suitable for use
     in "lock" statements.
 
+    If expr is the name of a function declared with "function",
+    calling it produces the function's result.
+
     Otherwise, expr should be one of these builtin functions:
 
       sha3(x)
@@ -113,10 +135,15 @@ of late May 2017.
         be supplied in the same order as the sigs. The square
         brackets here are literal and must appear as shown.
 
-  unary_op = "-" | "~"
+  unary_op = "-" | "~" | "!"
+
+  binary_op = "||" | "&&" | ">" | "<" | ">=" | "<=" | "==" | "!=" |
+        "^" | "|" | "+" | "-" | "&" | "<<" | ">>" | "%" | "*" | "/"
 
-  binary_op = ">" | "<" | ">=" | "<=" | "==" | "!=" | "^" | "|" |
-        "+" | "-" | "&" | "<<" | ">>" | "%" | "*" | "/"
+    The operands of "!", "&&" and "||" must be Boolean. Both
+    operands of "&&" and "||" are always evaluated. A signature
+    check may not appear under "!", since an invalid signature is
+    easy to supply.
 
   args = expr | args "," expr
 
@@ -12,6 +12,8 @@ type envEntry struct {
 	t typeDesc
 	r role
 	c *Contract // if t == contractType
+	k *constant // if r == roleConstant
+	f *function // if r == roleFunction
 }
 
 type role int
@@ -25,6 +27,9 @@ const (
 	roleClause
 	roleClauseParam
 	roleClauseValue
+	roleConstant
+	roleFunction
+	roleFunctionParam
 )
 
 var roleDesc = map[role]string{
@@ -36,6 +41,9 @@ var roleDesc = map[role]string{
 	roleClause:        "clause",
 	roleClauseParam:   "clause parameter",
 	roleClauseValue:   "clause value",
+	roleConstant:      "constant",
+	roleFunction:      "function",
+	roleFunctionParam: "function parameter",
 }
 
 func newEnviron(parent *environ) *environ {
@@ -61,6 +69,22 @@ func (e *environ) addContract(contract *Contract) error {
 	return nil
 }
 
+func (e *environ) addConstant(k *constant) error {
+	if entry := e.lookup(k.name); entry != nil {
+		return fmt.Errorf("%s \"%s\" conflicts with %s", roleDesc[roleConstant], k.name, roleDesc[entry.r])
+	}
+	e.entries[k.name] = &envEntry{t: k.typ, r: roleConstant, k: k}
+	return nil
+}
+
+func (e *environ) addFunction(f *function) error {
+	if entry := e.lookup(f.name); entry != nil {
+		return fmt.Errorf("%s \"%s\" conflicts with %s", roleDesc[roleFunction], f.name, roleDesc[entry.r])
+	}
+	e.entries[f.name] = &envEntry{t: nilType, r: roleFunction, f: f}
+	return nil
+}
+
 func (e environ) lookup(name string) *envEntry {
 	if res, ok := e.entries[name]; ok {
 		return res
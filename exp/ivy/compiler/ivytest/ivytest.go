@@ -153,3 +153,37 @@ contract One(a, b, c: Program, switchTime, expirationTime: Time) locks value {
   }
 }
 `
+
+const EscrowWithTimeout = `
+contract EscrowWithTimeout(agent: PublicKey, sender: PublicKey, deadline: Time) locks value {
+  clause release(sig: Signature) {
+    verify checkTxSig(agent, sig) || (after(deadline) && checkTxSig(sender, sig))
+    unlock value
+  }
+}
+`
+
+const RangeLock = `
+contract RangeLock(low: Integer, high: Integer) locks value {
+  clause spend(x: Integer) {
+    verify !(x < low || x > high)
+    unlock value
+  }
+}
+`
+
+const FixedRangeLock = `
+const lowest = 10
+const width = 10
+
+function between(x, lo, hi: Integer): Boolean {
+  return lo <= x && x <= hi
+}
+
+contract FixedRangeLock(offset: Integer) locks value {
+  clause spend(x: Integer) {
+    verify between(x - offset, lowest, lowest + width)
+    unlock value
+  }
+}
+`
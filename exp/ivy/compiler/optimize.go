@@ -38,27 +38,83 @@ var optimizations = []struct {
 	{"DUP 2 PICK BOOLOR", "2DUP BOOLOR"},
 	{"DUP 2 PICK MIN", "2DUP MIN"},
 	{"DUP 2 PICK MAX", "2DUP MAX"},
+	{"NOT NOT VERIFY", "VERIFY"},
+	{"NOT NOT NOT", "NOT"},
+	{"EQUAL NOT NOT", "EQUAL"},
+	{"LESSTHAN NOT", "GREATERTHANOREQUAL"},
+	{"GREATERTHAN NOT", "LESSTHANOREQUAL"},
+	{"LESSTHANOREQUAL NOT", "GREATERTHAN"},
+	{"GREATERTHANOREQUAL NOT", "LESSTHAN"},
 }
 
-func optimize(opcodes string) string {
-	opcodes = " " + opcodes + " "
+// op is a single opcode together with the builder item that
+// produced it.
+type op struct {
+	opcode string
+	item   *builderItem
+
+	// last tells whether this opcode completes item, so that the
+	// stack is as item.stk describes.
+	last bool
+}
+
+// optimize applies the peephole optimizations to ops until none
+// applies. Opcodes introduced by an optimization take the item of the
+// last opcode they replace, and the last of them completes it if
+// that one did.
+func optimize(ops []op) []op {
 	looping := true
 	for looping {
 		looping = false
 		for _, o := range optimizations {
-			before := " " + o.before + " "
-			var after string
-			if o.after == "" {
-				after = " "
-			} else {
-				after = " " + o.after + " "
-			}
-			newOpcodes := strings.Replace(opcodes, before, after, -1)
-			if newOpcodes != opcodes {
+			before := strings.Fields(o.before)
+			after := strings.Fields(o.after)
+			var res []op
+			for i := 0; i < len(ops); {
+				if !hasPrefix(ops[i:], before) {
+					res = append(res, ops[i])
+					i++
+					continue
+				}
+				replaced := ops[i+len(before)-1]
+				for j, a := range after {
+					res = append(res, op{opcode: a, item: replaced.item, last: replaced.last && j == len(after)-1})
+				}
+				i += len(before)
 				looping = true
-				opcodes = newOpcodes
+
+				// A match cannot begin with the opcode just after
+				// another. (Optimizations were once done by string
+				// replacement, with each pattern surrounded by
+				// spaces, so the separating space belonged to the
+				// first match; this keeps the output the same.)
+				if i < len(ops) {
+					res = append(res, ops[i])
+					i++
+				}
 			}
+			ops = res
 		}
 	}
-	return strings.TrimSpace(opcodes)
+	return ops
+}
+
+func hasPrefix(ops []op, opcodes []string) bool {
+	if len(ops) < len(opcodes) {
+		return false
+	}
+	for i, opcode := range opcodes {
+		if ops[i].opcode != opcode {
+			return false
+		}
+	}
+	return true
+}
+
+func joinOps(ops []op) string {
+	var opcodes []string
+	for _, o := range ops {
+		opcodes = append(opcodes, o.opcode)
+	}
+	return strings.Join(opcodes, " ")
 }
@@ -28,8 +28,13 @@ func (p *parser) errorf(format string, args ...interface{}) {
 	panic(parserErr{buf: p.buf, offset: p.pos, format: format, args: args})
 }
 
+// line returns the line number, counting from 1, of the next token.
+func (p *parser) line() int {
+	return 1 + bytes.Count(p.buf[:skipWsAndComments(p.buf, p.pos)], []byte{'\n'})
+}
+
 // parse is the main entry point to the parser
-func parse(buf []byte) (contracts []*Contract, err error) {
+func parse(buf []byte) (contracts []*Contract, consts []*constant, funcs []*function, err error) {
 	defer func() {
 		if val := recover(); val != nil {
 			if e, ok := val.(parserErr); ok {
@@ -40,23 +45,59 @@ func parse(buf []byte) (contracts []*Contract, err error) {
 		}
 	}()
 	p := &parser{buf: buf}
-	contracts = parseContracts(p)
+	contracts, consts, funcs = parseDecls(p)
 	return
 }
 
 // parse functions
 
-func parseContracts(p *parser) []*Contract {
-	var result []*Contract
-	for peekKeyword(p) == "contract" {
-		contract := parseContract(p)
-		result = append(result, contract)
+// parseDecls parses the top-level declarations, which may appear in
+// any order.
+func parseDecls(p *parser) (contracts []*Contract, consts []*constant, funcs []*function) {
+	for {
+		switch peekKeyword(p) {
+		case "contract":
+			contracts = append(contracts, parseContract(p))
+		case "const":
+			consts = append(consts, parseConst(p))
+		case "function":
+			funcs = append(funcs, parseFunction(p))
+		default:
+			return
+		}
 	}
-	return result
+}
+
+// const name: t = expr
+func parseConst(p *parser) *constant {
+	consumeKeyword(p, "const")
+	k := &constant{name: consumeIdentifier(p)}
+	if peekTok(p, ":") {
+		consumeTok(p, ":")
+		k.typ = parseType(p)
+	}
+	consumeTok(p, "=")
+	k.expr = parseExpr(p)
+	return k
+}
+
+// function name(p1, p2: t1, p3: t2): t3 { return expr }
+func parseFunction(p *parser) *function {
+	consumeKeyword(p, "function")
+	f := &function{name: consumeIdentifier(p)}
+	f.params = parseParams(p)
+	consumeTok(p, ":")
+	f.result = parseType(p)
+	consumeTok(p, "{")
+	consumeKeyword(p, "return")
+	f.body = parseExpr(p)
+	consumeTok(p, "}")
+	return f
 }
 
 // contract name(p1, p2: t1, p3: t2) locks value { ... }
 func parseContract(p *parser) *Contract {
+	line := p.line()
 	consumeKeyword(p, "contract")
 	name := consumeIdentifier(p)
 	params := parseParams(p)
@@ -65,7 +106,7 @@ func parseContract(p *parser) *Contract {
 	consumeTok(p, "{")
 	clauses := parseClauses(p)
 	consumeTok(p, "}")
-	return &Contract{Name: name, Params: params, Clauses: clauses, Value: value}
+	return &Contract{Name: name, Params: params, Clauses: clauses, Value: value, line: line}
 }
 
 // (p1, p2: t1, p3: t2)
@@ -104,19 +145,24 @@ func parseParamsType(p *parser) []*Param {
 		params = append(params, &Param{Name: name})
 	}
 	consumeTok(p, ":")
-	typ := consumeIdentifier(p)
+	typ := parseType(p)
 	for _, parm := range params {
-		if tdesc, ok := types[typ]; ok {
-			parm.Type = tdesc
-		} else {
-			p.errorf("unknown type %s", typ)
-		}
+		parm.Type = typ
 	}
 	return params
 }
 
+func parseType(p *parser) typeDesc {
+	typ := consumeIdentifier(p)
+	tdesc, ok := types[typ]
+	if !ok {
+		p.errorf("unknown type %s", typ)
+	}
+	return tdesc
+}
+
 func parseClause(p *parser) *Clause {
-	var c Clause
+	c := Clause{line: p.line()}
 	consumeKeyword(p, "clause")
 	c.Name = consumeIdentifier(p)
 	c.Params = parseParams(p)
@@ -174,23 +220,26 @@ func parseStatement(p *parser) statement {
 }
 
 func parseVerifyStmt(p *parser) *verifyStatement {
+	line := p.line()
 	consumeKeyword(p, "verify")
 	expr := parseExpr(p)
-	return &verifyStatement{expr: expr}
+	return &verifyStatement{expr: expr, line: line}
 }
 
 func parseLockStmt(p *parser) *lockStatement {
+	line := p.line()
 	consumeKeyword(p, "lock")
 	locked := parseExpr(p)
 	consumeKeyword(p, "with")
 	program := parseExpr(p)
-	return &lockStatement{locked: locked, program: program}
+	return &lockStatement{locked: locked, program: program, line: line}
 }
 
 func parseUnlockStmt(p *parser) *unlockStatement {
+	line := p.line()
 	consumeKeyword(p, "unlock")
 	expr := parseExpr(p)
-	return &unlockStatement{expr}
+	return &unlockStatement{expr: expr, line: line}
 }
 
 func parseExpr(p *parser) expression {
@@ -318,6 +367,7 @@ func peekTok(p *parser, token string) bool {
 var keywords = []string{
 	"contract", "clause", "verify", "output", "return",
 	"locks", "requires", "of", "lock", "with", "unlock",
+	"const", "function",
 }
 
 func consumeKeyword(p *parser, keyword string) {
@@ -52,6 +52,23 @@ func (stk stack) dropN(n int) stack {
 	return stk
 }
 
+func (stk stack) size() int {
+	var n int
+	for e := stk.stackEntry; e != nil; e = e.prev {
+		n++
+	}
+	return n
+}
+
+// names returns the entries of stk, bottom first.
+func (stk stack) names() []string {
+	var res []string
+	for e := stk.stackEntry; e != nil; e = e.prev {
+		res = append([]string{e.str}, res...)
+	}
+	return res
+}
+
 func (stk stack) find(str string) int {
 	if stk.isEmpty() {
 		return -1
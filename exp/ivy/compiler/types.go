@@ -61,6 +61,11 @@ func propagateType(contract *Contract, clause *Clause, env *environ, t typeDesc,
 		return
 	}
 	if entry := env.lookup(string(v)); entry != nil {
+		if entry.r == roleConstant || entry.r == roleFunctionParam {
+			// Declarations are shared by every use; don't narrow
+			// their types from one of them.
+			return
+		}
 		entry.t = t
 		for _, p := range contract.Params {
 			if p.Name == string(v) {
@@ -49,9 +49,21 @@ type responseWriter struct {
 
 var _ http.ResponseWriter = (*responseWriter)(nil)
 var _ http.Hijacker = (*responseWriter)(nil)
+var _ http.Flusher = (*responseWriter)(nil)
 
 func (w *responseWriter) Write(p []byte) (int, error) { return w.w.Write(p) }
 
+// Flush writes any buffered compressed data to the underlying
+// ResponseWriter and then flushes it, if possible.
+func (w *responseWriter) Flush() {
+	if gz, ok := w.w.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	h, ok := w.ResponseWriter.(http.Hijacker)
 	if !ok {
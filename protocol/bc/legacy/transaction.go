@@ -2,6 +2,7 @@ package legacy
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -31,6 +32,36 @@ func (tx *Tx) UnmarshalText(p []byte) error {
 	return nil
 }
 
+// Scan fulfills the sql.Scanner interface.
+func (tx *Tx) Scan(val interface{}) error {
+	driverBuf, ok := val.([]byte)
+	if !ok {
+		return errors.New("Scan must receive a byte slice")
+	}
+	buf := make([]byte, len(driverBuf))
+	copy(buf[:], driverBuf)
+	r := blockchain.NewReader(buf)
+	err := tx.TxData.readFrom(r)
+	if err != nil {
+		return err
+	}
+	if trailing := r.Len(); trailing > 0 {
+		return fmt.Errorf("trailing garbage (%d bytes)", trailing)
+	}
+	tx.Tx = MapTx(&tx.TxData)
+	return nil
+}
+
+// Value fulfills the sql.driver.Valuer interface.
+func (tx *Tx) Value() (driver.Value, error) {
+	buf := new(bytes.Buffer)
+	_, err := tx.TxData.WriteTo(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // SetInputArguments sets the Arguments field in input n.
 func (tx *Tx) SetInputArguments(n uint32, args [][]byte) {
 	tx.Inputs[n].SetArguments(args)
@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"chain/crypto/sha3pool"
+	"chain/errors"
 )
 
 var (
@@ -11,6 +12,8 @@ var (
 	interiorPrefix = []byte{0x01}
 )
 
+var errBadProofIndex = errors.New("transaction index out of range")
+
 // MerkleRoot creates a merkle tree from a slice of transactions
 // and returns the root hash of the tree.
 func MerkleRoot(transactions []*Tx) (root Hash, err error) {
@@ -19,13 +22,7 @@ func MerkleRoot(transactions []*Tx) (root Hash, err error) {
 		return EmptyStringHash, nil
 
 	case len(transactions) == 1:
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-
-		h.Write(leafPrefix)
-		transactions[0].ID.WriteTo(h)
-		root.ReadFrom(h)
-		return root, nil
+		return leafHash(transactions[0].ID), nil
 
 	default:
 		k := prevPowerOfTwo(len(transactions))
@@ -39,14 +36,118 @@ func MerkleRoot(transactions []*Tx) (root Hash, err error) {
 			return root, err
 		}
 
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-		h.Write(interiorPrefix)
-		left.WriteTo(h)
-		right.WriteTo(h)
-		root.ReadFrom(h)
-		return root, nil
+		return interiorHash(left, right), nil
+	}
+}
+
+// MerkleProof is an audit path proving that a transaction
+// is in the transactions Merkle tree of a block.
+type MerkleProof struct {
+	// Index is the position of the transaction in the block,
+	// and Count is the number of transactions in the block.
+	// Together they determine the shape of the tree and the
+	// side of the path each of Hashes is on.
+	Index uint64 `json:"index"`
+	Count uint64 `json:"count"`
+
+	// Hashes are the roots of the subtrees adjacent to the
+	// path from the transaction to the root of the tree,
+	// nearest the transaction first.
+	Hashes []Hash `json:"hashes"`
+}
+
+// NewMerkleProof returns a proof that transactions[index]
+// is in the Merkle tree of transactions computed by MerkleRoot.
+func NewMerkleProof(transactions []*Tx, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(transactions) {
+		return nil, errors.WithDetailf(errBadProofIndex, "index %d of %d transactions", index, len(transactions))
+	}
+	p := &MerkleProof{Index: uint64(index), Count: uint64(len(transactions))}
+	for len(transactions) > 1 {
+		// Descend toward the transaction, collecting the root
+		// of the subtree on the other side at each level.
+		k := prevPowerOfTwo(len(transactions))
+		var sibling []*Tx
+		if index < k {
+			transactions, sibling = transactions[:k], transactions[k:]
+		} else {
+			transactions, sibling = transactions[k:], transactions[:k]
+			index -= k
+		}
+		h, err := MerkleRoot(sibling)
+		if err != nil {
+			return nil, err
+		}
+		p.Hashes = append(p.Hashes, h)
+	}
+
+	// The hashes were collected from the root down.
+	for i, j := 0, len(p.Hashes)-1; i < j; i, j = i+1, j-1 {
+		p.Hashes[i], p.Hashes[j] = p.Hashes[j], p.Hashes[i]
 	}
+	return p, nil
+}
+
+// VerifyMerkleProof reports whether p proves that the
+// transaction with the given ID is in the transactions
+// Merkle tree with the given root.
+//
+// To prove that the transaction is in a block, root must be
+// the TransactionsMerkleRoot of a block header whose signature
+// has been checked against the consensus program.
+func VerifyMerkleProof(root, txID Hash, p *MerkleProof) bool {
+	if p == nil || p.Index >= p.Count || p.Count > math.MaxInt32 {
+		return false
+	}
+
+	// Find the side of the path each hash is on, from the root down.
+	var onLeft []bool
+	index, count := p.Index, p.Count
+	for count > 1 {
+		k := uint64(prevPowerOfTwo(int(count)))
+		if index < k {
+			onLeft = append(onLeft, false)
+			count = k
+		} else {
+			onLeft = append(onLeft, true)
+			index -= k
+			count -= k
+		}
+	}
+	if len(onLeft) != len(p.Hashes) {
+		return false
+	}
+
+	h := leafHash(txID)
+	for i, sibling := range p.Hashes {
+		if onLeft[len(onLeft)-1-i] {
+			h = interiorHash(sibling, h)
+		} else {
+			h = interiorHash(h, sibling)
+		}
+	}
+	return h == root
+}
+
+func leafHash(id Hash) (h Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write(leafPrefix)
+	id.WriteTo(hasher)
+	h.ReadFrom(hasher)
+	return h
+}
+
+func interiorHash(left, right Hash) (h Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write(interiorPrefix)
+	left.WriteTo(hasher)
+	right.WriteTo(hasher)
+	h.ReadFrom(hasher)
+	return h
 }
 
 // prevPowerOfTwo returns the largest power of two that is smaller than a given number.
@@ -146,3 +146,49 @@ func mustDecodeHash(s string) (h Hash) {
 	}
 	return h
 }
+
+func TestMerkleProof(t *testing.T) {
+	var initialBlockHash Hash
+	trueProg := []byte{byte(vm.OP_TRUE)}
+	assetID := ComputeAssetID(trueProg, &initialBlockHash, 1, &EmptyStringHash)
+
+	var txs []*Tx
+	for n := uint64(1); n <= 9; n++ {
+		now := []byte(time.Now().String())
+		txs = append(txs, legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs:  []*legacy.TxInput{legacy.NewIssuanceInput(now, n, nil, initialBlockHash, trueProg, nil, nil)},
+			Outputs: []*legacy.TxOutput{legacy.NewTxOutput(assetID, n, trueProg, nil)},
+		}).Tx)
+
+		root, err := MerkleRoot(txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, tx := range txs {
+			p, err := NewMerkleProof(txs, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyMerkleProof(root, tx.ID, p) {
+				t.Errorf("proof of tx %d of %d did not verify", i, len(txs))
+			}
+
+			// The proof must not verify for another tx or position.
+			other := txs[(i+1)%len(txs)]
+			if len(txs) > 1 && VerifyMerkleProof(root, other.ID, p) {
+				t.Errorf("proof of tx %d of %d verified for another tx", i, len(txs))
+			}
+			moved := *p
+			moved.Index = (moved.Index + 1) % moved.Count
+			if len(txs) > 1 && VerifyMerkleProof(root, tx.ID, &moved) {
+				t.Errorf("proof of tx %d of %d verified at index %d", i, len(txs), moved.Index)
+			}
+		}
+	}
+
+	_, err := NewMerkleProof(txs, len(txs))
+	if err == nil {
+		t.Error("expected error for out of range index")
+	}
+}
@@ -73,14 +73,7 @@ func (t *Tree) Contains(item []byte) bool {
 
 	key := bitKey(item)
 	n := lookup(t.root, key)
-
-	var hash bc.Hash
-	h := sha3pool.Get256()
-	h.Write(leafPrefix)
-	h.Write(item)
-	hash.ReadFrom(h)
-	sha3pool.Put256(h)
-	return n != nil && n.Hash() == hash
+	return n != nil && n.Hash() == leafHash(item)
 }
 
 func lookup(n *node, key []uint8) *node {
@@ -106,13 +99,7 @@ func lookup(n *node, key []uint8) *node {
 // (and this is not an error).
 func (t *Tree) Insert(item []byte) error {
 	key := bitKey(item)
-
-	var hash bc.Hash
-	h := sha3pool.Get256()
-	h.Write(leafPrefix)
-	h.Write(item)
-	hash.ReadFrom(h)
-	sha3pool.Put256(h)
+	hash := leafHash(item)
 
 	if t.root == nil {
 		t.root = &node{key: key, hash: &hash, isLeaf: true}
@@ -0,0 +1,199 @@
+package patricia
+
+import (
+	"bytes"
+
+	"chain/crypto/sha3pool"
+	"chain/protocol/bc"
+)
+
+// A Path is the audit path from the root of a tree to a leaf.
+type Path struct {
+	// Item is the item at the leaf.
+	Item []byte
+
+	// Right holds, for each interior node from the root down to
+	// the leaf, whether the path goes to the node's right child.
+	// Siblings holds the hash of the node's other child.
+	Right    []bool
+	Siblings []bc.Hash
+}
+
+// A Proof proves that an item is or is not in a tree.
+//
+// A proof of membership holds the path to the item. A proof of
+// non-membership holds the paths to the items immediately before
+// and after it in the tree. Items are ordered as byte strings.
+// Since the tree is ordered the same way, the item would have to
+// be between them if it were in the tree.
+type Proof struct {
+	// Leaf is the path to the item, in a proof of membership.
+	Leaf *Path
+
+	// Prev and Next are the paths to the adjacent items, in a
+	// proof of non-membership. Either is nil if no item in the
+	// tree comes before or after the item. Both are nil if the
+	// tree is empty.
+	Prev, Next *Path
+}
+
+// Prove returns a proof that item is or is not in t.
+func (t *Tree) Prove(item []byte) *Proof {
+	if t.root == nil {
+		return &Proof{}
+	}
+
+	key := bitKey(item)
+	var steps []step
+	n := t.root
+	for !n.isLeaf && len(key) > len(n.key) && bytes.HasPrefix(key, n.key) {
+		bit := key[len(n.key)]
+		steps = append(steps, step{n, bit})
+		n = n.children[bit]
+	}
+	if n.isLeaf && bytes.Equal(n.key, key) {
+		return &Proof{Leaf: pathOf(steps, n)}
+	}
+
+	// The item isn't in t. All the leaves under n are on
+	// the same side of it, so it belongs next to one of n's
+	// extreme leaves.
+	if bytes.Compare(key, n.key) <= 0 {
+		s, first := extreme(steps, n, 0)
+		return &Proof{Prev: neighbor(steps, 0), Next: pathOf(s, first)}
+	}
+	s, last := extreme(steps, n, 1)
+	return &Proof{Prev: pathOf(s, last), Next: neighbor(steps, 1)}
+}
+
+// step records the child taken at an interior node
+// while descending the tree.
+type step struct {
+	n   *node
+	bit uint8
+}
+
+func pathOf(steps []step, leaf *node) *Path {
+	p := &Path{Item: leaf.Key()}
+	for _, s := range steps {
+		p.Right = append(p.Right, s.bit == 1)
+		p.Siblings = append(p.Siblings, s.n.children[1-s.bit].Hash())
+	}
+	return p
+}
+
+// extreme descends from n to its leftmost leaf if bit is 0,
+// or its rightmost leaf if bit is 1, appending to steps.
+func extreme(steps []step, n *node, bit uint8) ([]step, *node) {
+	for !n.isLeaf {
+		steps = append(steps, step{n, bit})
+		n = n.children[bit]
+	}
+	return steps, n
+}
+
+// neighbor returns the path to the leaf immediately before
+// (if bit is 0) or after (if bit is 1) the subtree reached by
+// steps, or nil if there is no such leaf.
+func neighbor(steps []step, bit uint8) *Path {
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].bit == bit {
+			continue
+		}
+		s := append(steps[:i:i], step{steps[i].n, bit})
+		s, leaf := extreme(s, steps[i].n.children[bit], 1-bit)
+		return pathOf(s, leaf)
+	}
+	return nil
+}
+
+// VerifyMembership reports whether p proves that
+// item is in the tree with the given root hash.
+func VerifyMembership(root bc.Hash, item []byte, p *Proof) bool {
+	if p == nil || p.Leaf == nil {
+		return false
+	}
+	return bytes.Equal(p.Leaf.Item, item) && p.Leaf.verify(root)
+}
+
+// VerifyNonMembership reports whether p proves that
+// item is not in the tree with the given root hash.
+func VerifyNonMembership(root bc.Hash, item []byte, p *Proof) bool {
+	if p == nil || p.Leaf != nil {
+		return false
+	}
+	if p.Prev == nil && p.Next == nil {
+		return root == (bc.Hash{}) // the empty tree
+	}
+	if p.Prev != nil && (!p.Prev.verify(root) || bytes.Compare(p.Prev.Item, item) >= 0) {
+		return false
+	}
+	if p.Next != nil && (!p.Next.verify(root) || bytes.Compare(item, p.Next.Item) >= 0) {
+		return false
+	}
+
+	// Check that no item in the tree is between Prev and Next.
+	switch {
+	case p.Prev == nil:
+		return allEqual(p.Next.Right, false) // Next is the first item
+	case p.Next == nil:
+		return allEqual(p.Prev.Right, true) // Prev is the last item
+	}
+	// The paths agree down to the node where Prev goes left and
+	// Next goes right. From there, Prev must go right to the last
+	// item of the left subtree and Next must go left to the first
+	// item of the right subtree.
+	a, b := p.Prev.Right, p.Next.Right
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	if i == len(a) || i == len(b) || a[i] || !b[i] {
+		return false
+	}
+	return allEqual(a[i+1:], true) && allEqual(b[i+1:], false)
+}
+
+// verify reports whether p leads from the leaf to the given root.
+func (p *Path) verify(root bc.Hash) bool {
+	if len(p.Right) != len(p.Siblings) {
+		return false
+	}
+	h := leafHash(p.Item)
+	for i := len(p.Right) - 1; i >= 0; i-- {
+		if p.Right[i] {
+			h = interiorHash(p.Siblings[i], h)
+		} else {
+			h = interiorHash(h, p.Siblings[i])
+		}
+	}
+	return h == root
+}
+
+func allEqual(a []bool, v bool) bool {
+	for _, b := range a {
+		if b != v {
+			return false
+		}
+	}
+	return true
+}
+
+func leafHash(item []byte) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(leafPrefix)
+	h.Write(item)
+	hash.ReadFrom(h)
+	return hash
+}
+
+func interiorHash(left, right bc.Hash) (hash bc.Hash) {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(interiorPrefix)
+	left.WriteTo(h)
+	right.WriteTo(h)
+	hash.ReadFrom(h)
+	return hash
+}
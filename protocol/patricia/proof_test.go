@@ -0,0 +1,88 @@
+package patricia
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestProofs(t *testing.T) {
+	r := rand.New(rand.NewSource(12345))
+	randItem := func() []byte {
+		item := make([]byte, 32)
+		r.Read(item)
+		return item
+	}
+
+	for _, n := range []int{0, 1, 2, 3, 10, 100} {
+		tr := new(Tree)
+		var items [][]byte
+		for i := 0; i < n; i++ {
+			item := randItem()
+			items = append(items, item)
+			err := tr.Insert(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		root := tr.RootHash()
+
+		for _, item := range items {
+			p := tr.Prove(item)
+			if !VerifyMembership(root, item, p) {
+				t.Errorf("%d items: membership proof of %x did not verify", n, item)
+			}
+			if VerifyNonMembership(root, item, p) {
+				t.Errorf("%d items: membership proof of %x verified as non-membership", n, item)
+			}
+		}
+
+		absent := [][]byte{randItem(), make([]byte, 32), {0xff}, {}}
+		for _, item := range items {
+			// Items that differ from one in the tree only in the
+			// last bit, to land next to it.
+			near := append([]byte(nil), item...)
+			near[len(near)-1] ^= 1
+			absent = append(absent, near)
+		}
+		for _, item := range absent {
+			if tr.Contains(item) {
+				continue
+			}
+			p := tr.Prove(item)
+			if !VerifyNonMembership(root, item, p) {
+				t.Errorf("%d items: non-membership proof of %x did not verify", n, item)
+			}
+			if VerifyMembership(root, item, p) {
+				t.Errorf("%d items: non-membership proof of %x verified as membership", n, item)
+			}
+		}
+	}
+}
+
+func TestNonMembershipProofNotAdjacent(t *testing.T) {
+	tr := new(Tree)
+	for _, b := range []byte{0x10, 0x20, 0x30} {
+		err := tr.Insert([]byte{b})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := tr.RootHash()
+
+	// Paths to 0x10 and 0x30 both verify and bracket 0x20,
+	// but they aren't adjacent, so they don't prove 0x20 is absent.
+	p := &Proof{
+		Prev: tr.Prove([]byte{0x10}).Leaf,
+		Next: tr.Prove([]byte{0x30}).Leaf,
+	}
+	if VerifyNonMembership(root, []byte{0x20}, p) {
+		t.Error("non-membership proof with non-adjacent items verified")
+	}
+
+	// Leaving out one side of a proof must fail too.
+	p = tr.Prove([]byte{0x18})
+	p.Next = nil
+	if VerifyNonMembership(root, []byte{0x18}, p) {
+		t.Error("non-membership proof without the next item verified")
+	}
+}
@@ -30,6 +30,15 @@ func (c *Chain) ValidateTx(tx *bc.Tx) error {
 	return errors.Sub(ErrBadTx, err)
 }
 
+// ValidationResult returns the cached result of validating the
+// transaction with the given ID, as ValidateTx would return it.
+// The ok result reports whether a result was cached. The cache
+// is bounded, so the result of an old validation may be gone.
+func (c *Chain) ValidationResult(txID bc.Hash) (err error, ok bool) {
+	err, ok = c.prevalidated.lookup(txID)
+	return errors.Sub(ErrBadTx, err), ok
+}
+
 type prevalidatedTxsCache struct {
 	mu  sync.Mutex
 	lru *lru.Cache
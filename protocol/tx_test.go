@@ -9,6 +9,7 @@ import (
 	"golang.org/x/crypto/sha3"
 
 	"chain/crypto/ed25519"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
@@ -33,6 +34,27 @@ func TestBadMaxIssuanceWindow(t *testing.T) {
 	}
 }
 
+func TestValidationResult(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+
+	// The issuance commits to the wrong initial block hash.
+	tx, _, _ := issue(t, nil, nil, 1)
+	if _, ok := c.ValidationResult(tx.ID); ok {
+		t.Fatal("expected no cached result before validating")
+	}
+	err := c.ValidateTx(tx.Tx)
+	if errors.Root(err) != ErrBadTx {
+		t.Fatalf("ValidateTx error = %v, want %v", err, ErrBadTx)
+	}
+	got, ok := c.ValidationResult(tx.ID)
+	if !ok {
+		t.Fatal("expected a cached result after validating")
+	}
+	if errors.Root(got) != ErrBadTx {
+		t.Errorf("ValidationResult error = %v, want %v", got, ErrBadTx)
+	}
+}
+
 type testDest struct {
 	privKey ed25519.PrivateKey
 }
@@ -42,4 +42,9 @@ type Context struct {
 
 	TxSigHash   func() []byte
 	CheckOutput func(index uint64, data []byte, amount uint64, assetID []byte, vmVersion uint64, code []byte, expansion bool) (bool, error)
+
+	// StepHook, if non-nil, is called after each instruction executes
+	// or fails, including those of predicates run by CHECKPREDICATE.
+	// It is for debugging and has no effect on the result.
+	StepHook func(Step)
 }
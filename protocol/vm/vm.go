@@ -40,6 +40,24 @@ var ErrFalseVMResult = errors.New("false VM result")
 // execution.
 var TraceOut io.Writer
 
+// Step describes one instruction executed by the VM. It is passed to
+// Context.StepHook.
+type Step struct {
+	// Depth is 0 for the program being verified, 1 for a predicate
+	// it runs with CHECKPREDICATE, and so on.
+	Depth int
+
+	PC   uint32
+	Op   Op
+	Data []byte
+
+	// Stack is the data stack after the instruction, bottom first.
+	Stack [][]byte
+
+	// Err is the error executing the instruction, if any.
+	Err error
+}
+
 func Verify(context *Context) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -86,7 +104,11 @@ func (vm *virtualMachine) falseResult() bool {
 
 func (vm *virtualMachine) run() error {
 	for vm.pc = 0; vm.pc < uint32(len(vm.program)); { // handle vm.pc updates in step
+		pc := vm.pc
 		err := vm.step()
+		if vm.context != nil && vm.context.StepHook != nil {
+			vm.callStepHook(pc, err)
+		}
 		if err != nil {
 			return err
 		}
@@ -94,6 +116,20 @@ func (vm *virtualMachine) run() error {
 	return nil
 }
 
+func (vm *virtualMachine) callStepHook(pc uint32, err error) {
+	step := Step{
+		Depth: vm.depth,
+		PC:    pc,
+		Stack: append([][]byte(nil), vm.dataStack...),
+		Err:   err,
+	}
+	if inst, parseErr := ParseOp(vm.program, pc); parseErr == nil {
+		step.Op = inst.Op
+		step.Data = inst.Data
+	}
+	vm.context.StepHook(step)
+}
+
 func (vm *virtualMachine) step() error {
 	inst, err := ParseOp(vm.program, vm.pc)
 	if err != nil {
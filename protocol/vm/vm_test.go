@@ -251,6 +251,41 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestStepHook(t *testing.T) {
+	// The predicate is FALSE VERIFY.
+	prog, err := Assemble("0 0x0069 0 CHECKPREDICATE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type step struct {
+		depth int
+		op    Op
+		err   error
+	}
+	var got []step
+	err = Verify(&Context{
+		VMVersion: 1,
+		Code:      prog,
+		StepHook: func(s Step) {
+			got = append(got, step{s.Depth, s.Op, s.Err})
+		},
+	})
+	if vmErr, ok := err.(Error); !ok || vmErr.Err != ErrFalseVMResult {
+		t.Errorf("Verify() error = %v want %v", err, ErrFalseVMResult)
+	}
+	want := []step{
+		{0, OP_0, nil},
+		{0, OP_DATA_2, nil},
+		{0, OP_0, nil},
+		{1, OP_FALSE, nil},
+		{1, OP_VERIFY, ErrVerifyFailed},
+		{0, OP_CHECKPREDICATE, nil},
+	}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("steps = %v want %v", got, want)
+	}
+}
+
 func TestStep(t *testing.T) {
 	txVMContext := &Context{DestPos: new(uint64)}
 	cases := []struct {